@@ -0,0 +1,1381 @@
+package main
+
+// The JSON API (/api/v1) - the whole game workflow for CLI and mobile clients
+// - Same models and rules as the HTML handlers; only the wire format differs
+// - Every response is an envelope (see apiRespond and apiError in helpers.go)
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/forms"
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/matchmaking"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// ----------------------------------------------------------------------------
+// What the API sends back
+
+type apiPlayer struct {
+	ID				int					`json:"id"`
+	ScreenName		string				`json:"screen_name"`
+	LoggedIn		bool				`json:"logged_in"`
+	Computer		bool				`json:"computer"`
+	Rating			int					`json:"rating"`
+	Wins			int					`json:"wins"`
+	Losses			int					`json:"losses"`
+	Streak			int					`json:"streak"`				// wins (positive) or losses (negative) in a row
+}
+
+// apiTicket - your place in the matchmaking queue
+type apiTicket struct {
+	BoardID			int					`json:"board_id"`
+	BoardSize		int					`json:"board_size"`
+	Rules			string				`json:"rules"`
+	SalvoShots		int					`json:"salvo_shots"`
+	TurnTime		int					`json:"turn_time"`				// seconds; 0 is no turn clock
+	Rating			int					`json:"rating"`
+	Band			int					`json:"band"`					// how far from rating an opponent may be right now
+	Joined			time.Time			`json:"joined"`
+	Waiting			int					`json:"waiting"`				// players in the queue, you included
+}
+
+// apiRating - one change to a player's rating
+type apiRating struct {
+	BattleID		int					`json:"battle_id"`
+	OpponentID		int					`json:"opponent_id"`
+	OpponentName	string				`json:"opponent_screen_name"`
+	Rating			int					`json:"rating"`
+	Change			int					`json:"change"`
+	Created			time.Time			`json:"created"`
+}
+
+type apiBoard struct {
+	ID				int					`json:"id"`
+	Name			string				`json:"name"`
+	Size			int					`json:"size"`
+	Created			time.Time			`json:"created"`
+	Ships			map[string][]string	`json:"ships,omitempty"`
+	TemplateID		int					`json:"template_id,omitempty"`
+	BattleID		int					`json:"battle_id,omitempty"`
+	Battles			int					`json:"battles"`
+}
+
+type apiBattleSide struct {
+	PlayerID		int					`json:"player_id"`
+	ScreenName		string				`json:"screen_name"`
+	BoardID			int					`json:"board_id,omitempty"`
+	Accepted		bool				`json:"accepted"`
+}
+
+type apiBattle struct {
+	ID				int					`json:"id"`
+	Challenger		apiBattleSide		`json:"challenger"`
+	Opponent		apiBattleSide		`json:"opponent"`
+	ChallengeDate	time.Time			`json:"challenge_date"`
+	BoardSize		int					`json:"board_size"`
+	Rules			string				`json:"rules"`
+	SalvoShots		int					`json:"salvo_shots"`
+	Status			string				`json:"status"`
+	TurnPlayerID	int					`json:"turn_player_id"`
+	TurnTime		int					`json:"turn_time"`				// seconds; 0 is no turn clock
+	Deadline		*time.Time			`json:"deadline,omitempty"`		// when turn_player_id runs out of time
+	WinnerID		int					`json:"winner_id"`
+	AIStrategy		string				`json:"ai_strategy,omitempty"`
+	Private			bool				`json:"private"`				// a player keeps spectators out
+}
+
+// apiBattleState - a battle as seen by one of its players
+// - YourBoard holds the pins your opponent has dropped on you
+// - TargetBoard holds the pins you have dropped on your opponent
+// - TurnToken is only present on your turn; send it back with your strike
+type apiBattleState struct {
+	apiBattle
+	YourTurn		bool				`json:"your_turn"`
+	TurnToken		string				`json:"turn_token,omitempty"`
+	ShotsPerTurn	int					`json:"shots_per_turn"`
+	YourBoard		[]apiPin			`json:"your_board"`
+	TargetBoard		[]apiPin			`json:"target_board"`
+}
+
+type apiPin struct {
+	CoordX			int					`json:"coord_x"`
+	CoordY			string				`json:"coord_y"`
+	PinColor		string				`json:"pin_color"`
+}
+
+// apiShot - the result of one shot (same shape as the battle page's strike response)
+type apiShot struct {
+	CoordX			int					`json:"coord_x"`
+	CoordY			string				`json:"coord_y"`
+	Result			string				`json:"result"`
+	PinColor		string				`json:"pin_color"`
+	ShipType		string				`json:"sunken_ship"`
+}
+
+// apiMove - one turn in a battle's history (every shot of a salvo is one move)
+type apiMove struct {
+	Move			int					`json:"move"`
+	PlayerID		int					`json:"player_id"`
+	BoardID			int					`json:"board_id"`
+	Fired			time.Time			`json:"fired"`
+	Shots			[]apiShot			`json:"shots"`
+}
+
+// apiReplay - a battle move by move
+// - Fleets ("challenger" and "opponent", ship type => squares) only once the battle is over
+type apiReplay struct {
+	Battle			apiBattle						`json:"battle"`
+	Finished		bool							`json:"finished"`
+	Fleets			map[string]map[string][]string	`json:"fleets,omitempty"`
+	Moves			[]apiMove						`json:"moves"`
+}
+
+// apiNotification - one entry in a player's inbox
+type apiNotification struct {
+	ID				int					`json:"id"`
+	BattleID		int					`json:"battle_id"`
+	Kind			string				`json:"kind"`
+	Message			string				`json:"message"`
+	Read			bool				`json:"read"`
+	Created			time.Time			`json:"created"`
+}
+
+// apiInbox - the unread count and the most recent notifications
+type apiInbox struct {
+	Unread			int					`json:"unread"`
+	Notifications	[]apiNotification	`json:"notifications"`
+}
+
+// apiWebhook - one of your webhooks; events is empty when it gets everything
+type apiWebhook struct {
+	ID				int					`json:"id"`
+	URL				string				`json:"url"`
+	Secret			string				`json:"secret"`
+	Events			[]string			`json:"events"`
+	Created			time.Time			`json:"created"`
+}
+
+// apiDelivery - one event sent to a webhook, and how the last attempt went
+type apiDelivery struct {
+	ID				int					`json:"id"`
+	Event			string				`json:"event"`
+	BattleID		int					`json:"battle_id,omitempty"`
+	Status			string				`json:"status"`
+	Attempts		int					`json:"attempts"`
+	StatusCode		int					`json:"status_code,omitempty"`		// what the receiver answered the last time
+	Error			string				`json:"error,omitempty"`
+	Payload			json.RawMessage		`json:"payload"`
+	Created			time.Time			`json:"created"`
+	Updated			time.Time			`json:"updated"`
+}
+
+// apiMessage - one chat message in a battle
+type apiMessage struct {
+	ID				int					`json:"id"`
+	BattleID		int					`json:"battle_id"`
+	PlayerID		int					`json:"player_id"`
+	ScreenName		string				`json:"screen_name"`
+	Body			string				`json:"body"`
+	Sent			time.Time			`json:"sent"`
+}
+
+func newAPIPlayer(p *models.Player) apiPlayer {
+	return apiPlayer{
+		ID:				p.ID,
+		ScreenName:		p.ScreenName,
+		LoggedIn:		p.LoggedIn.String == "1" || p.LoggedIn.String == "true",
+		Computer:		p.IsComputer,
+		Rating:			p.Rating,
+		Wins:			p.Wins,
+		Losses:			p.Losses,
+		Streak:			p.Streak,
+	}
+}
+
+func (app *application) newAPITicket(t matchmaking.Ticket) apiTicket {
+	return apiTicket{
+		BoardID:		t.BoardID,
+		BoardSize:		t.BoardSize,
+		Rules:			t.Rules.Mode,
+		SalvoShots:		t.Rules.SalvoShots,
+		TurnTime:		int(t.Rules.TurnTime / time.Second),
+		Rating:			t.Rating,
+		Band:			matchmaking.Band(time.Since(t.Joined)),
+		Joined:			t.Joined,
+		Waiting:		app.queue.Len(),
+	}
+}
+
+func newAPIBoard(b *models.Board) apiBoard {
+	return apiBoard{ID: b.ID, Name: b.Title, Size: b.BoardSize, Created: b.Created,
+		TemplateID: b.TemplateID, BattleID: int(b.BattleID.Int64), Battles: b.Battles}
+}
+
+func newAPIMessage(m *models.Message) apiMessage {
+	return apiMessage{
+		ID:				m.ID,
+		BattleID:		m.BattleID,
+		PlayerID:		m.PlayerID,
+		ScreenName:		m.ScreenName,
+		Body:			m.Body,
+		Sent:			m.Created,
+	}
+}
+
+func newAPIBattle(b *models.Battle) apiBattle {
+	battle := apiBattle{
+		ID:				b.ID,
+		Challenger:		apiBattleSide{b.Player1ID, b.Player1ScreenName, b.Player1BoardID, b.Player1Accepted},
+		Opponent:		apiBattleSide{b.Player2ID, b.Player2ScreenName, b.Player2BoardID, b.Player2Accepted},
+		ChallengeDate:	b.ChallengeDate,
+		BoardSize:		b.BoardSize,
+		Rules:			b.Rules,
+		SalvoShots:		b.SalvoShots,
+		Status:			string(b.Status),
+		TurnPlayerID:	int(b.Turn.Int64),
+		TurnTime:		int(b.TurnTime / time.Second),
+		WinnerID:		b.Winner,
+		AIStrategy:		b.AIStrategy,
+		Private:		b.Private(),
+	}
+	if b.Deadline.Valid && !b.Status.Over() {
+		battle.Deadline = &b.Deadline.Time
+	}
+	return battle
+}
+
+// Who is making this API call? (an API token or the session cookie)
+func (app *application) apiPlayerID(r *http.Request) int {
+	if t := apiToken(r); t != nil {
+		return t.PlayerID
+	}
+	return app.session.GetInt(r, "authenticatedPlayerID")
+}
+
+func (app *application) apiScreenName(r *http.Request) string {
+	if t := apiToken(r); t != nil {
+		return t.ScreenName
+	}
+	return app.session.GetString(r, "screenName")
+}
+
+// The :id in the URL; false if it isn't a positive number
+func apiID(r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	return id, err == nil && id > 0
+}
+
+// ----------------------------------------------------------------------------
+// BEGIN AUTH
+
+// Log in - POST {"screen_name": "...", "password": "..."}
+// - Sets the same session cookie the web pages use
+func (app *application) apiLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ScreenName		string		`json:"screen_name"`
+		Password		string		`json:"password"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	rowid, err := app.players.Authenticate(req.ScreenName, req.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.apiError(w, r, http.StatusUnauthorized, "Supplied credentials are incorrect")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+	app.players.UpdateLogin(rowid, true)
+	app.session.Put(r, "authenticatedPlayerID", rowid)
+	app.session.Put(r, "screenName", req.ScreenName)
+	p, err := app.players.Get(rowid)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.apiRespond(w, r, http.StatusOK, newAPIPlayer(p))
+}
+
+// Log out
+func (app *application) apiLogout(w http.ResponseWriter, r *http.Request) {
+	rowid := app.session.PopInt(r, "authenticatedPlayerID")
+	app.players.UpdateLogin(rowid, false)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// END AUTH
+// ----------------------------------------------------------------------------
+
+// ----------------------------------------------------------------------------
+// BEGIN PLAYERS
+
+// List the other players - ?status=loggedIn for just the ones online
+func (app *application) apiListPlayers(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "loggedIn" {
+		app.apiError(w, r, http.StatusBadRequest, "status must be loggedIn or left out")
+		return
+	}
+	p, err := app.players.List(app.apiPlayerID(r), status)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.apiServerError(w, r, err)
+		return
+	}
+	players := []apiPlayer{}
+	for _, player := range p {
+		players = append(players, newAPIPlayer(player))
+	}
+	app.apiRespond(w, r, http.StatusOK, players)
+}
+
+// One player
+func (app *application) apiGetPlayer(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	p, err := app.players.Get(id)
+	if err != nil {
+		app.apiError(w, r, http.StatusNotFound, "No such player")
+		return
+	}
+	app.apiRespond(w, r, http.StatusOK, newAPIPlayer(p))
+}
+
+// A player's rating history, newest first
+func (app *application) apiPlayerRatings(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	if _, err := app.players.Get(id); err != nil {
+		app.apiError(w, r, http.StatusNotFound, "No such player")
+		return
+	}
+	history, err := app.ratings.History(id)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	ratings := []apiRating{}
+	for _, h := range history {
+		ratings = append(ratings, apiRating{h.BattleID, h.OpponentID, h.OpponentScreenName, h.Rating, h.Delta, h.Created})
+	}
+	app.apiRespond(w, r, http.StatusOK, ratings)
+}
+
+// The leaderboard - GET ?limit=10 (at most, and by default, 50)
+// - Just the players who have finished a battle, best rated first
+func (app *application) apiLeaderboard(w http.ResponseWriter, r *http.Request) {
+	limit := leaderboardSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > leaderboardSize {
+			app.apiValidationError(w, r, map[string][]string{
+				"limit": {fmt.Sprintf("The limit has to be between 1 and %d", leaderboardSize)},
+			})
+			return
+		}
+		limit = n
+	}
+	p, err := app.ratings.Leaderboard(limit)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	players := []apiPlayer{}
+	for _, player := range p {
+		players = append(players, newAPIPlayer(player))
+	}
+	app.apiRespond(w, r, http.StatusOK, players)
+}
+
+// END PLAYERS
+// ----------------------------------------------------------------------------
+
+// ----------------------------------------------------------------------------
+// BEGIN BOARDS
+
+// List your boards
+func (app *application) apiListBoards(w http.ResponseWriter, r *http.Request) {
+	b, err := app.boards.List(app.apiPlayerID(r))
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.apiServerError(w, r, err)
+		return
+	}
+	boards := []apiBoard{}
+	for _, board := range b {
+		boards = append(boards, newAPIBoard(board))
+	}
+	app.apiRespond(w, r, http.StatusOK, boards)
+}
+
+// Create a board - POST {"name": "...", "size": 10, "ships": {"carrier": ["1,A", "1,B", ...], ...}}
+// - Same checks as the board form; every ship in the fleet must be placed
+func (app *application) apiCreateBoard(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	var req struct {
+		Name			string					`json:"name"`
+		Size			int						`json:"size"`
+		Ships			map[string][]string		`json:"ships"`
+		Random			bool					`json:"random"`
+		NoTouching		bool					`json:"no_touching"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	// "random" lets the server place the fleet; the result is checked like any other board
+	if req.Random {
+		if len(req.Ships) > 0 {
+			app.apiValidationError(w, r, map[string][]string{"ships": {"Send ships or random, not both"}})
+			return
+		}
+		size := req.Size
+		if !game.ValidSize(size) {
+			size = game.DefaultSize
+		}
+		ships, err := app.randomShips(size, req.NoTouching)
+		if err != nil {
+			app.apiValidationError(w, r, map[string][]string{"no_touching": {placementProblem(err)}})
+			return
+		}
+		req.Ships = ships
+	}
+
+	form := forms.New(url.Values{"name": []string{req.Name}})
+	form.Required("name")
+	form.MaxLength("name", 35)
+	if req.Size == 0 {
+		req.Size = game.DefaultSize
+	}
+	if !game.ValidSize(req.Size) {
+		form.Errors.Add("size", fmt.Sprintf("Boards must be between %d and %d squares wide", game.MinSize, game.MaxSize))
+		req.Size = game.DefaultSize
+	}
+	for shipType := range req.Ships {
+		if _, ok := app.fleet.Lookup(shipType); !ok {
+			form.Errors.Add(shipType, fmt.Sprintf("%s is not part of the fleet", shipType))
+		}
+	}
+	// Coordinates are stored the way the board form sends them ("row,COL")
+	coordinates := map[string][]string{}
+	for shipType, coords := range req.Ships {
+		for _, rc := range coords {
+			s, err := game.ParseCoordinate(rc)
+			if err != nil {
+				form.Errors.Add(shipType, err.Error())
+				continue
+			}
+			coordinates[shipType] = append(coordinates[shipType], fmt.Sprintf("%d,%s", s.CoordX, s.CoordY))
+		}
+	}
+	board := game.NewBoard(req.Size, app.fleet)
+	for _, ship := range app.fleet {
+		form.RequiredNumberOfItems(ship.Type, ship.Length, len(coordinates[ship.Type]))
+		form.ValidNumberOfItems(board, coordinates[ship.Type], ship.Type)
+	}
+	// The form can't see one ship sitting on top of another; the engine can
+	if form.Valid() {
+		for _, ship := range app.fleet {
+			var shots []game.Shot
+			for _, rc := range coordinates[ship.Type] {
+				s, _ := game.ParseCoordinate(rc)
+				shots = append(shots, s)
+			}
+			if err := board.Place(ship.Type, shots); err != nil {
+				form.Errors.Add(ship.Type, "Ships may not overlap")
+			}
+		}
+	}
+	if !form.Valid() {
+		app.apiValidationError(w, r, form.Errors)
+		return
+	}
+
+	boardID, err := app.boards.Create(playerID, req.Name, req.Size)
+	if errors.Is(err, models.ErrDuplicateBoardName) {
+		app.apiValidationError(w, r, map[string][]string{"name": {"You already have a board with that name"}})
+		return
+	}
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	for _, ship := range app.fleet {
+		_, err = app.boards.Insert(playerID, boardID, ship.Type, coordinates[ship.Type])
+		if err != nil {
+			app.apiServerError(w, r, err)
+			return
+		}
+	}
+	app.apiSendBoard(w, r, http.StatusCreated, playerID, boardID)
+}
+
+// A random fleet placement - GET ?size=10&no_touching=true
+// - Nothing is saved; the ships come back in the shape POST /api/v1/boards takes
+func (app *application) apiRandomBoard(w http.ResponseWriter, r *http.Request) {
+	size := game.DefaultSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || !game.ValidSize(n) {
+			app.apiValidationError(w, r, map[string][]string{
+				"size": {fmt.Sprintf("Boards must be between %d and %d squares wide", game.MinSize, game.MaxSize)},
+			})
+			return
+		}
+		size = n
+	}
+	noTouching, _ := strconv.ParseBool(r.URL.Query().Get("no_touching"))
+	ships, err := app.randomShips(size, noTouching)
+	if err != nil {
+		app.apiValidationError(w, r, map[string][]string{"no_touching": {placementProblem(err)}})
+		return
+	}
+	app.apiRespond(w, r, http.StatusOK, map[string]interface{}{"size": size, "ships": ships})
+}
+
+// Place the fleet at random, as "row,COL" coordinates keyed by ship type
+func (app *application) randomShips(size int, noTouching bool) (map[string][]string, error) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	board, err := game.RandomBoard(size, app.fleet, rng, game.Placement{NoTouching: noTouching})
+	if err != nil {
+		return nil, err
+	}
+	ships := map[string][]string{}
+	for _, ship := range app.fleet {
+		for _, s := range board.ShipSquares(ship.Type) {
+			ships[ship.Type] = append(ships[ship.Type], fmt.Sprintf("%d,%s", s.CoordX, s.CoordY))
+		}
+	}
+	return ships, nil
+}
+
+// One of your boards, ships included
+func (app *application) apiGetBoard(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	app.apiSendBoard(w, r, http.StatusOK, app.apiPlayerID(r), id)
+}
+
+func (app *application) apiSendBoard(w http.ResponseWriter, r *http.Request, status, playerID, boardID int) {
+	b, err := app.boards.GetInfo(playerID, boardID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, r, http.StatusNotFound, "No such board")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+	p, err := app.boards.GetPositions(boardID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	board := newAPIBoard(b)
+	board.Ships = map[string][]string{}
+	for _, position := range p {
+		if position.ShipType.Valid {
+			board.Ships[position.ShipType.String] = append(board.Ships[position.ShipType.String], fmt.Sprintf("%d,%s", position.CoordX, position.CoordY))
+		}
+	}
+	app.apiRespond(w, r, status, board)
+}
+
+// END BOARDS
+// ----------------------------------------------------------------------------
+
+// ----------------------------------------------------------------------------
+// BEGIN CHALLENGES
+
+// Every challenge and battle you are part of
+func (app *application) apiListChallenges(w http.ResponseWriter, r *http.Request) {
+	b, err := app.battles.GetChallenges(app.apiPlayerID(r))
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.apiServerError(w, r, err)
+		return
+	}
+	battles := []apiBattle{}
+	for _, battle := range b {
+		battles = append(battles, newAPIBattle(battle))
+	}
+	app.apiRespond(w, r, http.StatusOK, battles)
+}
+
+// Challenge a player - POST {"player_id": 3, "board_id": 1, "rules": "classic", "salvo_shots": 0, "turn_time": "3d"}
+func (app *application) apiCreateChallenge(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	var req struct {
+		PlayerID		int			`json:"player_id"`
+		BoardID			int			`json:"board_id"`
+		Rules			string		`json:"rules"`
+		SalvoShots		int			`json:"salvo_shots"`
+		AIStrategy		string		`json:"ai_strategy"`
+		TurnTime		string		`json:"turn_time"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	fields := map[string][]string{}
+	if req.PlayerID == playerID {
+		fields["player_id"] = append(fields["player_id"], "You can't challenge yourself")
+	} else if _, err := app.players.Get(req.PlayerID); err != nil {
+		fields["player_id"] = append(fields["player_id"], "No such player")
+	}
+	if b, err := app.boards.GetInfo(playerID, req.BoardID); err != nil {
+		fields["board_id"] = append(fields["board_id"], "Not one of your boards")
+	} else if b.BattleID.Valid {
+		fields["board_id"] = append(fields["board_id"], "That board is a battle's copy; pick one of your saved boards")
+	}
+	rules, err := game.ParseRules(req.Rules, req.SalvoShots, app.fleet)
+	if err != nil {
+		fields["rules"] = append(fields["rules"], err.Error())
+	}
+	if rules.TurnTime, err = game.ParseTurnTime(req.TurnTime); err != nil {
+		fields["turn_time"] = append(fields["turn_time"], err.Error())
+	}
+	if _, err = game.ParseStrategy(req.AIStrategy); err != nil {
+		fields["ai_strategy"] = append(fields["ai_strategy"], err.Error())
+	}
+	if len(fields) > 0 {
+		app.apiValidationError(w, r, fields)
+		return
+	}
+
+	secretTurn, err := app.GenerateRandomString(32)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	battleID, err := app.battles.Create(playerID, req.BoardID, req.PlayerID, secretTurn, rules)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	if req.PlayerID == app.computerID {
+		if err = app.challengeComputer(battleID, req.AIStrategy); err != nil {
+			app.apiServerError(w, r, err)
+			return
+		}
+	} else {
+		app.events.publish(eventChallenge, battleID, map[string]string{"challenger": app.apiScreenName(r)}, req.PlayerID)
+		app.notify(req.PlayerID, battleID, models.NoticeChallenge, "%s has challenged you to a battle", app.apiScreenName(r))
+	}
+	app.apiSendBattle(w, r, http.StatusCreated, playerID, battleID)
+}
+
+// Accept a challenge - POST {"board_id": 2}
+// - The board has to be the same size as the challenger's
+func (app *application) apiAcceptChallenge(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	var req struct {
+		BoardID			int			`json:"board_id"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	if b, err := app.boards.GetInfo(playerID, req.BoardID); err != nil {
+		app.apiValidationError(w, r, map[string][]string{"board_id": {"Not one of your boards"}})
+		return
+	} else if b.BattleID.Valid {
+		app.apiValidationError(w, r, map[string][]string{"board_id": {"That board is a battle's copy; pick one of your saved boards"}})
+		return
+	}
+	_, err := app.battles.Accept(playerID, req.BoardID, battleID)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.apiError(w, r, http.StatusNotFound, "No open challenge with that id")
+		return
+	case errors.Is(err, models.ErrNotYourBattle):
+		app.apiError(w, r, http.StatusForbidden, "Only the player who was challenged can accept")
+		return
+	case errors.Is(err, models.ErrBoardSizeMismatch):
+		app.apiError(w, r, http.StatusConflict, "Your board is not the same size as your challenger's board")
+		return
+	case errors.Is(err, models.ErrBattleStatus):
+		app.apiError(w, r, http.StatusConflict, "That challenge is no longer open")
+		return
+	case err != nil:
+		app.apiServerError(w, r, err)
+		return
+	}
+	if b, err := app.battles.Get(playerID, battleID); err == nil {
+		app.events.publish(eventAccept, battleID, map[string]string{"opponent": app.apiScreenName(r)}, b.Player1ID)
+		app.notify(b.Player1ID, battleID, models.NoticeAccept, "%s has accepted your challenge", b.Player2ScreenName)
+	}
+	app.seenBattle(playerID, battleID)
+	app.apiSendBattle(w, r, http.StatusOK, playerID, battleID)
+}
+
+// Decline a challenge - POST, no body; only the player who was challenged can
+func (app *application) apiDeclineChallenge(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	err := app.battles.Decline(playerID, battleID)
+	if app.apiBattleStepFailed(w, r, err, "Only the player who was challenged can decline", "That challenge is no longer open") {
+		return
+	}
+	if b, err := app.battles.Get(playerID, battleID); err == nil {
+		app.events.publish(eventDecline, battleID, map[string]string{"opponent": app.apiScreenName(r)}, b.Player1ID)
+	}
+	app.seenBattle(playerID, battleID)
+	app.apiSendBattle(w, r, http.StatusOK, playerID, battleID)
+}
+
+// Withdraw a challenge - POST, no body; only the challenger can, and only before it's accepted
+func (app *application) apiWithdrawChallenge(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	err := app.battles.Withdraw(playerID, battleID)
+	if app.apiBattleStepFailed(w, r, err, "Only the challenger can withdraw a challenge", "That challenge has already been answered") {
+		return
+	}
+	if b, err := app.battles.Get(playerID, battleID); err == nil {
+		app.events.publish(eventWithdraw, battleID, map[string]string{"challenger": app.apiScreenName(r)}, b.Player2ID)
+	}
+	app.apiSendBattle(w, r, http.StatusOK, playerID, battleID)
+}
+
+// apiBattleStepFailed - report a decline, withdraw or resign that didn't happen
+// - Returns false if there was nothing to report
+func (app *application) apiBattleStepFailed(w http.ResponseWriter, r *http.Request, err error, notYours, tooLate string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, models.ErrNoRecord):
+		app.apiError(w, r, http.StatusNotFound, "No such battle")
+	case errors.Is(err, models.ErrNotYourBattle):
+		app.apiError(w, r, http.StatusForbidden, notYours)
+	case errors.Is(err, models.ErrBattleStatus):
+		app.apiError(w, r, http.StatusConflict, tooLate)
+	default:
+		app.apiServerError(w, r, err)
+	}
+	return true
+}
+
+// END CHALLENGES
+// ----------------------------------------------------------------------------
+
+// ----------------------------------------------------------------------------
+// BEGIN MATCHMAKING
+
+// Join the matchmaking queue - POST {"board_id": 1, "rules": "classic", "salvo_shots": 0, "turn_time": "5m"}
+// - 202; the battle starts when someone matches (a "match" event, or GET /api/v1/challenges)
+// - Joining again replaces your ticket
+func (app *application) apiJoinQueue(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	var req struct {
+		BoardID			int			`json:"board_id"`
+		Rules			string		`json:"rules"`
+		SalvoShots		int			`json:"salvo_shots"`
+		TurnTime		string		`json:"turn_time"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	fields := map[string][]string{}
+	rules, err := game.ParseRules(req.Rules, req.SalvoShots, app.fleet)
+	if err != nil {
+		fields["rules"] = append(fields["rules"], err.Error())
+	}
+	if rules.TurnTime, err = game.ParseTurnTime(req.TurnTime); err != nil {
+		fields["turn_time"] = append(fields["turn_time"], err.Error())
+	}
+	if len(fields) > 0 {
+		app.apiValidationError(w, r, fields)
+		return
+	}
+	t, err := app.enqueue(playerID, req.BoardID, rules)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.apiValidationError(w, r, map[string][]string{"board_id": {"Not one of your saved boards"}})
+		return
+	}
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.apiRespond(w, r, http.StatusAccepted, app.newAPITicket(t))
+}
+
+// Your place in the matchmaking queue; 404 if you aren't in it
+func (app *application) apiGetQueue(w http.ResponseWriter, r *http.Request) {
+	t, ok := app.queue.Waiting(app.apiPlayerID(r))
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "You aren't in the queue")
+		return
+	}
+	app.apiRespond(w, r, http.StatusOK, app.newAPITicket(t))
+}
+
+// Leave the matchmaking queue; 404 if you weren't in it (or were just matched)
+func (app *application) apiLeaveQueue(w http.ResponseWriter, r *http.Request) {
+	if !app.queue.Leave(app.apiPlayerID(r)) {
+		app.apiError(w, r, http.StatusNotFound, "You aren't in the queue")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// END MATCHMAKING
+// ----------------------------------------------------------------------------
+
+// ----------------------------------------------------------------------------
+// BEGIN NOTIFICATIONS
+
+// Your inbox - the unread count and your most recent notifications, newest first
+func (app *application) apiListNotifications(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	n, err := app.notifications.List(playerID, notificationsShown)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	inbox := apiInbox{Notifications: []apiNotification{}}
+	if inbox.Unread, err = app.notifications.Unread(playerID); err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	for _, notification := range n {
+		inbox.Notifications = append(inbox.Notifications, apiNotification{
+			ID:				notification.ID,
+			BattleID:		notification.BattleID,
+			Kind:			notification.Kind,
+			Message:		notification.Message,
+			Read:			notification.Read,
+			Created:		notification.Created,
+		})
+	}
+	app.apiRespond(w, r, http.StatusOK, inbox)
+}
+
+// Mark one notification read - 204
+func (app *application) apiReadNotification(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	notificationID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	err := app.notifications.MarkRead(playerID, notificationID)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.apiError(w, r, http.StatusNotFound, "No such notification")
+		return
+	}
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.publishUnread(playerID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Mark every notification read - 204
+func (app *application) apiReadAllNotifications(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	if err := app.notifications.MarkAllRead(playerID); err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.publishUnread(playerID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// END NOTIFICATIONS
+// ----------------------------------------------------------------------------
+
+// ----------------------------------------------------------------------------
+// BEGIN WEBHOOKS
+// - Reading them needs the read scope; adding, deleting and testing need play
+
+func newAPIWebhook(h *models.Webhook) apiWebhook {
+	events := h.Events
+	if events == nil {
+		events = []string{}
+	}
+	return apiWebhook{h.ID, h.URL, h.Secret, events, h.Created}
+}
+
+func newAPIDelivery(d *models.WebhookDelivery) apiDelivery {
+	return apiDelivery{d.ID, d.Event, d.BattleID, d.Status, d.Attempts, d.StatusCode, d.Error,
+		json.RawMessage(d.Payload), d.Created, d.Updated}
+}
+
+// Your webhooks
+func (app *application) apiListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := app.webhooks.List(app.apiPlayerID(r))
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	out := []apiWebhook{}
+	for _, h := range webhooks {
+		out = append(out, newAPIWebhook(h))
+	}
+	app.apiRespond(w, r, http.StatusOK, out)
+}
+
+// Add a webhook - {"url": "https://...", "events": ["strike", "winner"]}
+// - Leave events out for all of them; the secret comes back in the response
+func (app *application) apiCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL				string		`json:"url"`
+		Events			[]string	`json:"events"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	h, problems, err := app.addWebhook(app.apiPlayerID(r), req.URL, req.Events)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	if problems != nil {
+		app.apiValidationError(w, r, problems)
+		return
+	}
+	app.apiRespond(w, r, http.StatusCreated, newAPIWebhook(h))
+}
+
+// One of your webhooks and its recent deliveries, newest first
+func (app *application) apiGetWebhook(w http.ResponseWriter, r *http.Request) {
+	h, ok := app.apiWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveries, err := app.webhooks.Deliveries(h.ID, webhookDeliveriesShown)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	out := struct {
+		apiWebhook
+		Deliveries		[]apiDelivery	`json:"deliveries"`
+	}{newAPIWebhook(h), []apiDelivery{}}
+	for _, d := range deliveries {
+		out.Deliveries = append(out.Deliveries, newAPIDelivery(d))
+	}
+	app.apiRespond(w, r, http.StatusOK, out)
+}
+
+// Delete one of your webhooks - DELETE, no body
+func (app *application) apiDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	err := app.webhooks.Delete(app.apiPlayerID(r), webhookID)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.apiError(w, r, http.StatusNotFound, "No such webhook")
+		return
+	}
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Send a ping to one of your webhooks now (one attempt, no retries) and say how it went
+// - 200 either way; the delivery's status is "delivered" or "failed"
+func (app *application) apiTestWebhook(w http.ResponseWriter, r *http.Request) {
+	h, ok := app.apiWebhook(w, r)
+	if !ok {
+		return
+	}
+	d, err := app.testWebhook(h)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.apiRespond(w, r, http.StatusOK, newAPIDelivery(d))
+}
+
+// apiWebhook - the webhook named in the URL, if it's yours (otherwise a 404 has been sent)
+func (app *application) apiWebhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	webhookID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return nil, false
+	}
+	h, err := app.webhooks.Get(app.apiPlayerID(r), webhookID)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.apiError(w, r, http.StatusNotFound, "No such webhook")
+		return nil, false
+	}
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return nil, false
+	}
+	return h, true
+}
+
+// END WEBHOOKS
+// ----------------------------------------------------------------------------
+
+// ----------------------------------------------------------------------------
+// BEGIN BATTLES
+
+// The state of a battle: both boards' pins, whose turn it is, and the winner
+func (app *application) apiGetBattle(w http.ResponseWriter, r *http.Request) {
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	app.apiSendBattle(w, r, http.StatusOK, app.apiPlayerID(r), battleID)
+}
+
+// A battle's history - every move in the order it was made
+// - Either player may see it at any time; the fleets are revealed once the battle is over
+// - So may a spectator, if the battle can be watched (see models.Battle.Watchable)
+func (app *application) apiBattleMoves(w http.ResponseWriter, r *http.Request) {
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	b, err := app.battles.Get(app.apiPlayerID(r), battleID)
+	if errors.Is(err, models.ErrNoRecord) {
+		b, err = app.battles.Watch(battleID)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, r, http.StatusNotFound, "No such battle")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+	strikes, err := app.strikes.List(battleID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	replay := apiReplay{Battle: newAPIBattle(b), Finished: b.Status.Over(), Moves: []apiMove{}}
+	for _, s := range strikes {
+		if n := len(replay.Moves); n == 0 || replay.Moves[n-1].Move != s.Move {
+			replay.Moves = append(replay.Moves, apiMove{Move: s.Move, PlayerID: s.PlayerID, BoardID: s.BoardID, Fired: s.Created})
+		}
+		shot := apiShot{CoordX: s.CoordX, CoordY: s.CoordY, Result: s.Result, PinColor: "red"}
+		if s.Result == game.Miss.String() {
+			shot.PinColor = "gray"
+		}
+		if s.Result == game.Sunk.String() {
+			shot.ShipType = s.ShipType
+		}
+		move := &replay.Moves[len(replay.Moves)-1]
+		move.Shots = append(move.Shots, shot)
+	}
+	if replay.Finished {
+		replay.Fleets = map[string]map[string][]string{}
+		for side, boardID := range map[string]int{"challenger": b.Player1BoardID, "opponent": b.Player2BoardID} {
+			if boardID == 0 {
+				// declined or cancelled before this side had a board
+				continue
+			}
+			positions, err := app.boards.GetPositions(boardID)
+			if err != nil {
+				app.apiServerError(w, r, err)
+				return
+			}
+			fleet := map[string][]string{}
+			for _, p := range positions {
+				if p.ShipType.Valid {
+					fleet[p.ShipType.String] = append(fleet[p.ShipType.String], fmt.Sprintf("%d,%s", p.CoordX, p.CoordY))
+				}
+			}
+			replay.Fleets[side] = fleet
+		}
+	}
+	app.apiRespond(w, r, http.StatusOK, replay)
+}
+
+func (app *application) apiSendBattle(w http.ResponseWriter, r *http.Request, status, playerID, battleID int) {
+	b, err := app.battles.Get(playerID, battleID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, r, http.StatusNotFound, "No such battle")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+	state := apiBattleState{apiBattle: newAPIBattle(b), YourBoard: []apiPin{}, TargetBoard: []apiPin{}}
+	yourBoardID, targetBoardID := b.Player2BoardID, b.Player1BoardID
+	if b.Player1ID == playerID {
+		yourBoardID, targetBoardID = b.Player1BoardID, b.Player2BoardID
+	}
+	// Nothing to shoot at until the challenge has been accepted
+	if b.Player2Accepted && b.Player2BoardID != 0 {
+		_, state.TurnToken = app.battles.CheckTurn(battleID, playerID)
+		state.YourTurn = state.TurnToken != "" && !b.Status.Over()
+		if !state.YourTurn {
+			state.TurnToken = ""
+		}
+		state.ShotsPerTurn, err = app.positions.ShotsPerTurn(battleID, playerID)
+		if err != nil {
+			app.apiServerError(w, r, err)
+			return
+		}
+		for boardID, pins := range map[int]*[]apiPin{yourBoardID: &state.YourBoard, targetBoardID: &state.TargetBoard} {
+			p, err := app.positions.List(boardID, playerID)
+			if err != nil {
+				app.apiServerError(w, r, err)
+				return
+			}
+			for _, position := range p {
+				*pins = append(*pins, apiPin{position.CoordX, position.CoordY, position.PinColor})
+			}
+		}
+	}
+	app.apiRespond(w, r, status, state)
+}
+
+// Fire - POST {"shots": ["4,C"], "turn_token": "..."}
+// - A classic turn is one shot; a salvo is shots_per_turn of them
+func (app *application) apiStrike(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	var req struct {
+		Shots			[]string	`json:"shots"`
+		TurnToken		string		`json:"turn_token"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	var shots []game.Shot
+	for _, rc := range req.Shots {
+		s, err := game.ParseCoordinate(rc)
+		if err != nil {
+			app.apiValidationError(w, r, map[string][]string{"shots": {err.Error()}})
+			return
+		}
+		shots = append(shots, s)
+	}
+	if len(shots) == 0 {
+		app.apiValidationError(w, r, map[string][]string{"shots": {"Fire at least one shot"}})
+		return
+	}
+
+	b, err := app.battles.Get(playerID, battleID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, r, http.StatusNotFound, "No such battle")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+	switch {
+	case b.Status == models.StatusChallenged || b.Status == models.StatusAccepted:
+		app.apiError(w, r, http.StatusConflict, "This challenge has not been accepted yet")
+		return
+	case b.Status.Over():
+		app.apiError(w, r, http.StatusConflict, "This battle is over ("+string(b.Status)+")")
+		return
+	}
+	targetBoardID := b.Player1BoardID
+	if b.Player1ID == playerID {
+		targetBoardID = b.Player2BoardID
+	}
+	playerTakingTheirTurn, validTurn := app.checkTurn(playerID, battleID, req.TurnToken)
+	if !validTurn {
+		app.apiError(w, r, http.StatusConflict, "It is not your turn (or your turn_token is out of date)")
+		return
+	}
+
+	newSecret, err := app.GenerateRandomString(32)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	outcomes, winner, err := app.positions.Volley(playerID, playerTakingTheirTurn, battleID, targetBoardID, shots, req.TurnToken, newSecret)
+	switch {
+	case errors.Is(err, models.ErrStaleTurn):
+		app.apiError(w, r, http.StatusConflict, "It is not your turn (or your turn_token is out of date)")
+		return
+	case errors.Is(err, game.ErrWrongShotCount), errors.Is(err, game.ErrOutOfBounds), errors.Is(err, game.ErrAlreadyStruck):
+		app.apiValidationError(w, r, map[string][]string{"shots": {err.Error()}})
+		return
+	case err != nil:
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	type strikeResult struct {
+		Shots			[]apiShot	`json:"shots"`
+		Winner			bool		`json:"winner"`
+	}
+	result := strikeResult{Winner: winner}
+	for _, o := range outcomes {
+		result.Shots = append(result.Shots, apiShot{o.Shot.CoordX, o.Shot.CoordY, o.String(), o.PinColor(), o.SunkShip()})
+	}
+	app.announceStrike(playerID, battleID, result.Shots, winner)
+	app.apiRespond(w, r, http.StatusOK, result)
+}
+
+// Resign - POST, no body; give up a battle that has been accepted and your opponent wins
+func (app *application) apiResign(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	winner, err := app.battles.Resign(playerID, battleID)
+	if app.apiBattleStepFailed(w, r, err, "You are not playing in this battle", "That battle isn't being played; there is nothing to resign") {
+		return
+	}
+	app.announceResignation(playerID, battleID, winner)
+	app.apiSendBattle(w, r, http.StatusOK, playerID, battleID)
+}
+
+// A battle's chat - everything the two players have said, oldest first
+// - Only for the players; spectators don't get to read it
+func (app *application) apiListMessages(w http.ResponseWriter, r *http.Request) {
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	if _, err := app.battles.Get(app.apiPlayerID(r), battleID); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiError(w, r, http.StatusNotFound, "No such battle")
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+	m, err := app.messages.List(battleID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	messages := []apiMessage{}
+	for _, msg := range m {
+		messages = append(messages, newAPIMessage(msg))
+	}
+	app.apiRespond(w, r, http.StatusOK, messages)
+}
+
+// Say something to your opponent - POST {"body": "..."}
+// - 201 with the message; both players also get it as a "chat" event
+func (app *application) apiSendMessage(w http.ResponseWriter, r *http.Request) {
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	var req struct {
+		Body			string		`json:"body"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	body, problem := cleanMessage(req.Body)
+	if problem != "" {
+		app.apiValidationError(w, r, map[string][]string{"body": {problem}})
+		return
+	}
+	msg, err := app.sendMessage(app.apiPlayerID(r), battleID, body)
+	if errors.Is(err, models.ErrNoRecord) || errors.Is(err, models.ErrNotYourBattle) {
+		app.apiError(w, r, http.StatusNotFound, "No such battle")
+		return
+	}
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	app.apiRespond(w, r, http.StatusCreated, newAPIMessage(msg))
+}
+
+// Battles you can watch - everyone else's that are being played, newest first
+func (app *application) apiListWatchable(w http.ResponseWriter, r *http.Request) {
+	b, err := app.battles.ListWatchable(app.apiPlayerID(r))
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+	battles := []apiBattle{}
+	for _, battle := range b {
+		battles = append(battles, newAPIBattle(battle))
+	}
+	app.apiRespond(w, r, http.StatusOK, battles)
+}
+
+// Spectators - POST {"allowed": false} keeps other players from watching; true lets them watch again
+// - Either player may keep them out; "private" in the response is true while either does
+func (app *application) apiSetSpectators(w http.ResponseWriter, r *http.Request) {
+	playerID := app.apiPlayerID(r)
+	battleID, ok := apiID(r)
+	if !ok {
+		app.apiError(w, r, http.StatusNotFound, "")
+		return
+	}
+	var req struct {
+		Allowed			*bool		`json:"allowed"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "Request body must be JSON: "+err.Error())
+		return
+	}
+	if req.Allowed == nil {
+		app.apiValidationError(w, r, map[string][]string{"allowed": {"Say whether spectators are allowed (true or false)"}})
+		return
+	}
+	err := app.battles.SetPrivate(playerID, battleID, !*req.Allowed)
+	if app.apiBattleStepFailed(w, r, err, "You are not playing in this battle", "") {
+		return
+	}
+	app.apiSendBattle(w, r, http.StatusOK, playerID, battleID)
+}
+
+// END BATTLES
+// ----------------------------------------------------------------------------
+
+// Anything else under /api/ gets a JSON 404 rather than an HTML page
+func (app *application) apiNotFound(w http.ResponseWriter, r *http.Request) {
+	app.apiError(w, r, http.StatusNotFound, "No such API endpoint")
+}
@@ -0,0 +1,56 @@
+package main
+
+// Battle chat
+// - The two players in a battle can talk to each other while it's on (and after)
+// - Messages are saved, pushed to both players as a "chat" event, and shown
+//   alongside the moves in the replay
+// - Spectators never see them
+// - A message is saved as typed; every page escapes it when it shows it
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// The longest message a player can send, in characters
+const maxMessageLength = 500
+
+// cleanMessage - trim a message and check that it can be sent
+// - Control characters (other than new lines) are dropped
+// - Returns what's wrong with it, if anything
+func cleanMessage(body string) (string, string) {
+	if !utf8.ValidString(body) {
+		return "", "Message must be text"
+	}
+	body = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' {
+			return -1
+		}
+		return r
+	}, body))
+	switch n := utf8.RuneCountInString(body); {
+	case n == 0:
+		return "", "Say something"
+	case n > maxMessageLength:
+		return "", fmt.Sprintf("Keep it to %d characters (that's %d)", maxMessageLength, n)
+	}
+	return body, ""
+}
+
+// sendMessage - save a message and push it to both players
+func (app *application) sendMessage(playerID, battleID int, body string) (*models.Message, error) {
+	msg, err := app.messages.Insert(playerID, battleID, body)
+	if err != nil {
+		return nil, err
+	}
+	b, err := app.battles.Get(playerID, battleID)
+	if err != nil {
+		return nil, err
+	}
+	app.events.publish(eventChat, battleID, newAPIMessage(msg), b.Player1ID, b.Player2ID)
+	return msg, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+// The turn clock
+// - A challenger can give a battle a turn clock; whoever has to move next
+//   (answer the challenge, or fire) has that long to do it
+// - A goroutine looks for anyone who has run out of time: an unanswered
+//   challenge expires, a missed turn forfeits the battle to the other player
+
+import (
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"golang.org/x/xerrors"
+)
+
+// watchClocks - check the turn clocks every interval, for as long as the server runs
+func (app *application) watchClocks(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		app.enforceDeadlines()
+	}
+}
+
+// enforceDeadlines - expire or forfeit every battle that has run out of time,
+// and tell both players
+func (app *application) enforceDeadlines() {
+	overdue, err := app.battles.Overdue()
+	if err != nil {
+		app.errorLog.Println("Unable to check the turn clocks:", err)
+		return
+	}
+	for _, b := range overdue {
+		if b.Status == models.StatusChallenged {
+			err = app.battles.Expire(b.ID)
+			if xerrors.Is(err, models.ErrBattleStatus) {
+				// answered just in time
+				continue
+			}
+			if err != nil {
+				app.errorLog.Println("Unable to expire challenge:", err)
+				continue
+			}
+			app.infoLog.Printf("Challenge %d expired", b.ID)
+			app.events.publish(eventTimeout, b.ID, map[string]interface{}{"status": models.StatusExpired}, b.Player1ID, b.Player2ID)
+			if expired, err := app.battles.Get(b.Player1ID, b.ID); err == nil {
+				app.notify(b.Player1ID, b.ID, models.NoticeExpired, "%s didn't answer your challenge in time", opponentName(expired, b.Player1ID))
+				app.notify(b.Player2ID, b.ID, models.NoticeExpired, "The challenge from %s expired before you answered", opponentName(expired, b.Player2ID))
+			}
+			continue
+		}
+		winner, err := app.battles.Forfeit(b.ID)
+		if xerrors.Is(err, models.ErrBattleStatus) {
+			// moved just in time
+			continue
+		}
+		if err != nil {
+			app.errorLog.Println("Unable to forfeit battle:", err)
+			continue
+		}
+		app.infoLog.Printf("Battle %d forfeited; player %d ran out of time", b.ID, b.Turn.Int64)
+		app.events.publish(eventTimeout, b.ID, map[string]interface{}{"status": models.StatusAbandoned, "winner_id": winner}, b.Player1ID, b.Player2ID)
+		if over, err := app.battles.Get(winner, b.ID); err == nil {
+			loser := over.Player1ID
+			if loser == winner {
+				loser = over.Player2ID
+			}
+			app.notify(winner, b.ID, models.NoticeGameOver, "%s ran out of time.  You win!", opponentName(over, winner))
+			app.notify(loser, b.ID, models.NoticeGameOver, "You ran out of time against %s", opponentName(over, loser))
+		}
+	}
+}
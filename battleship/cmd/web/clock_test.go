@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/models/memory"
+)
+
+func TestEnforceDeadlines(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     bool
+		status     models.BattleStatus
+		kind       string
+		challenger string
+		opponent   string
+	}{
+		{"unanswered challenge", false, models.StatusExpired, models.NoticeExpired,
+			"ben didn't answer your challenge in time", "The challenge from amy expired before you answered"},
+		{"missed turn", true, models.StatusAbandoned, models.NoticeGameOver,
+			"ben ran out of time.  You win!", "You ran out of time against amy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			now := time.Now()
+			app.battles.(*memory.BattleModel).Store.Now = func() time.Time { return now }
+
+			amy, err := app.players.Insert("amy", "", "Tr1cky-Password")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ben, err := app.players.Insert("ben", "", "Tr1cky-Password")
+			if err != nil {
+				t.Fatal(err)
+			}
+			amysBoard, _ := app.boards.Create(amy, "amy's", game.DefaultSize)
+			bensBoard, _ := app.boards.Create(ben, "ben's", game.DefaultSize)
+			battleID, err := app.battles.Create(amy, amysBoard, ben, "first", game.Rules{Mode: game.Classic, TurnTime: time.Minute})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.accept {
+				if _, err = app.battles.Accept(ben, bensBoard, battleID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			app.enforceDeadlines()
+			if b, _ := app.battles.Get(amy, battleID); b.Status.Over() {
+				t.Fatalf("battle is %s before the clock ran out", b.Status)
+			}
+
+			now = now.Add(time.Minute)
+			app.enforceDeadlines()
+			b, err := app.battles.Get(amy, battleID)
+			if err != nil || b.Status != tt.status {
+				t.Fatalf("after the clock ran out the battle is %v (%v), want %s", b, err, tt.status)
+			}
+			for playerID, want := range map[int]string{amy: tt.challenger, ben: tt.opponent} {
+				notices, err := app.notifications.List(playerID, notificationsShown)
+				if err != nil || len(notices) == 0 {
+					t.Fatalf("player %d has notifications %v, %v", playerID, notices, err)
+				}
+				if n := notices[0]; n.Kind != tt.kind || n.Message != want || n.BattleID != battleID {
+					t.Errorf("player %d was told %s %q about battle %d, want %s %q about %d", playerID, n.Kind, n.Message, n.BattleID, tt.kind, want, battleID)
+				}
+			}
+		})
+	}
+}
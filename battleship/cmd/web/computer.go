@@ -0,0 +1,136 @@
+package main
+
+// The computer player
+// - A built-in player that can be challenged like anyone else
+// - It places a random fleet, accepts on the spot and takes its turns here,
+//   on the server, with the strategy the challenger picked
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"golang.org/x/xerrors"
+)
+
+// Screen names can't contain spaces, so no one can sign up as the computer
+const computerScreenName = "The Computer"
+
+// A short pause before the computer fires so its pins don't land on top of
+// the challenger's
+const computerThinking = 750 * time.Millisecond
+
+// challengeComputer - the computer has just been challenged
+// - Build it a board the size of the battle and accept
+// - It's the opponent, so it goes first
+func (app *application) challengeComputer(battleID int, strategy string) error {
+	b, err := app.battles.Get(app.computerID, battleID)
+	if err != nil {
+		return err
+	}
+	if err = app.battles.SetStrategy(battleID, strategy); err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	board, err := game.RandomBoard(b.BoardSize, app.fleet, rng, game.Placement{})
+	if err != nil {
+		return err
+	}
+	boardID, err := app.boards.Create(app.computerID, fmt.Sprintf("Battle %d", battleID), b.BoardSize)
+	if err != nil {
+		return err
+	}
+	for _, ship := range app.fleet {
+		var coords []string
+		for _, s := range board.ShipSquares(ship.Type) {
+			coords = append(coords, fmt.Sprintf("%d,%s", s.CoordX, s.CoordY))
+		}
+		if _, err = app.boards.Insert(app.computerID, boardID, ship.Type, coords); err != nil {
+			return err
+		}
+	}
+	_, err = app.battles.Accept(app.computerID, boardID, battleID)
+	if err != nil && !xerrors.Is(err, models.ErrNoRecord) && !xerrors.Is(err, models.ErrBattleStatus) {
+		return err
+	}
+	app.events.publish(eventAccept, battleID, map[string]string{"opponent": computerScreenName}, b.Player1ID)
+	go app.computerTurn(battleID)
+	return nil
+}
+
+// resumeComputer - take every turn the computer owes, one battle after another
+// - A turn is only taken after the player before it fires, so one the server
+//   was still thinking about when it stopped would otherwise never come
+func (app *application) resumeComputer() {
+	battles, err := app.battles.Waiting(app.computerID)
+	if err != nil {
+		app.errorLog.Println("Computer can't find its battles:", err)
+		return
+	}
+	for _, b := range battles {
+		app.computerTurn(b.ID)
+	}
+}
+
+// computerTurn - if it's the computer's turn in this battle, take it
+func (app *application) computerTurn(battleID int) {
+	time.Sleep(computerThinking)
+
+	b, err := app.battles.Get(app.computerID, battleID)
+	if err != nil {
+		app.errorLog.Println("Computer can't find its battle:", err)
+		return
+	}
+	if b.Status != models.StatusSetup && b.Status != models.StatusInProgress {
+		return
+	}
+	turn, secretTurn := app.battles.CheckTurn(battleID, app.computerID)
+	if turn != app.computerID {
+		return
+	}
+	strategy, err := game.ParseStrategy(b.AIStrategy)
+	if err != nil {
+		app.errorLog.Println("Computer doesn't know how to play:", err)
+		return
+	}
+
+	// Fire at the challenger's board, knowing only what a player would know
+	targetBoardID := b.Player1BoardID
+	if b.Player1ID == app.computerID {
+		targetBoardID = b.Player2BoardID
+	}
+	knowledge, err := app.positions.Knowledge(targetBoardID)
+	if err != nil {
+		app.errorLog.Println("Computer can't see the board:", err)
+		return
+	}
+	n, err := app.positions.ShotsPerTurn(battleID, app.computerID)
+	if err != nil {
+		app.errorLog.Println("Computer can't count its shots:", err)
+		return
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	shots := game.Aim(strategy, knowledge, n, rng)
+
+	newSecret, err := app.GenerateRandomString(32)
+	if err != nil {
+		app.errorLog.Println(err)
+		return
+	}
+	outcomes, winner, err := app.positions.Volley(app.computerID, app.computerID, battleID, targetBoardID, shots, secretTurn, newSecret)
+	if xerrors.Is(err, models.ErrStaleTurn) {
+		// Someone (another copy of this goroutine) already took the turn
+		return
+	}
+	if err != nil {
+		app.errorLog.Println("Computer's volley failed:", err)
+		return
+	}
+	var fired []apiShot
+	for _, o := range outcomes {
+		fired = append(fired, apiShot{o.Shot.CoordX, o.Shot.CoordY, o.String(), o.PinColor(), o.SunkShip()})
+	}
+	app.announceStrike(app.computerID, battleID, fired, winner)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+)
+
+// A battle where it was the computer's turn when the server stopped carries on
+// when it starts again
+func TestResumeComputer(t *testing.T) {
+	app := newTestApplication(t)
+	amy, err := app.players.Insert("amy", "", "Tr1cky-Password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amysBoard, _ := app.boards.Create(amy, "amy's", game.DefaultSize)
+	if _, err = app.boards.Insert(amy, amysBoard, "destroyer", []string{"1,A", "2,A"}); err != nil {
+		t.Fatal(err)
+	}
+	computersBoard, _ := app.boards.Create(app.computerID, "Battle 1", game.DefaultSize)
+	if _, err = app.boards.Insert(app.computerID, computersBoard, "destroyer", []string{"1,A", "2,A"}); err != nil {
+		t.Fatal(err)
+	}
+	// challenged and accepted, but the computer never got to fire
+	battleID, err := app.battles.Create(amy, amysBoard, app.computerID, "first", game.Rules{Mode: game.Classic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = app.battles.Accept(app.computerID, computersBoard, battleID); err != nil {
+		t.Fatal(err)
+	}
+	if turn, _ := app.battles.CheckTurn(battleID, app.computerID); turn != app.computerID {
+		t.Fatalf("the computer doesn't have the first turn")
+	}
+
+	app.resumeComputer()
+	if turn, _ := app.battles.CheckTurn(battleID, amy); turn != amy {
+		t.Error("it isn't amy's turn after the computer resumed")
+	}
+	strikes, err := app.strikes.List(battleID)
+	if err != nil || len(strikes) != 1 || strikes[0].PlayerID != app.computerID {
+		t.Errorf("Strikes = %+v, %v; want the computer's one shot", strikes, err)
+	}
+	if battles, err := app.battles.Waiting(app.computerID); err != nil || len(battles) != 0 {
+		t.Errorf("the computer still owes %d turns (%v)", len(battles), err)
+	}
+}
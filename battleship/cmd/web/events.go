@@ -0,0 +1,193 @@
+package main
+
+// Live updates pushed to players over Server-Sent Events
+// - Handlers publish an event once the model call behind it has committed
+// - Every logged in page keeps one EventSource open on /events
+// - A spectator's page opens /events?watch=<battle ID> for that battle's strikes too
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types
+// - challenge: someone challenged you
+// - accept: a challenge you issued was accepted
+// - decline: a challenge you issued was turned down
+// - withdraw: a challenge to you was taken back
+// - strike: shots landed in one of your battles (yours or your opponent's)
+// - turn: it is now your turn
+// - winner: one of your battles is over (resigned is set if someone gave up)
+// - timeout: the turn clock ran out; a challenge expired or a battle was forfeited
+// - match: matchmaking found you an opponent and started a battle
+// - chat: a message in one of your battles (yours or your opponent's)
+// - notice: your unread notification count changed (see notify.go)
+const (
+	eventChallenge = "challenge"
+	eventAccept    = "accept"
+	eventDecline   = "decline"
+	eventWithdraw  = "withdraw"
+	eventStrike    = "strike"
+	eventTurn      = "turn"
+	eventWinner    = "winner"
+	eventTimeout   = "timeout"
+	eventMatch     = "match"
+	eventChat      = "chat"
+	eventNotice    = "notice"
+)
+
+// Events spectators get too (see subscribe); they show nothing a spectator can't see anyway
+var spectatorEvents = map[string]bool{
+	eventStrike:  true,
+	eventTurn:    true,
+	eventWinner:  true,
+	eventTimeout: true,
+}
+
+// How long one event stream stays open
+// - Must stay under the server's WriteTimeout; the browser reconnects on its
+//   own (after eventRetry) and Last-Event-ID fills in anything it missed
+const (
+	eventStreamLifetime = 8 * time.Second
+	eventRetry          = time.Second
+)
+
+// How many recent events are kept so a reconnecting browser can catch up
+const eventBacklog = 256
+
+// event - one message to one or more players
+type event struct {
+	ID       int64       `json:"id"`
+	Type     string      `json:"type"`
+	BattleID int         `json:"battle_id"`
+	Data     interface{} `json:"data,omitempty"`
+
+	players []int
+}
+
+// write - send the event in text/event-stream format
+func (e event) write(w io.Writer) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+func (e event) isFor(playerID int) bool {
+	for _, p := range e.players {
+		if p == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// isForWatchers - should spectators of this battle get it?
+func (e event) isForWatchers(battleID int) bool {
+	return battleID != 0 && e.BattleID == battleID && spectatorEvents[e.Type]
+}
+
+// eventHub - fans events out to every open stream of the players involved
+// - forward (if set) also gets every event, on a goroutine of its own (see forwardEvent)
+type eventHub struct {
+	mu          sync.Mutex
+	nextID      int64
+	subscribers map[int]map[chan event]bool
+	watchers    map[int]map[chan event]bool
+	backlog     []event
+	forward     func(event)
+}
+
+// newEventHub - IDs start at the current time so a browser reconnecting to a
+// restarted server doesn't skip new events with an old Last-Event-ID
+func newEventHub() *eventHub {
+	return &eventHub{
+		nextID:      time.Now().UnixNano(),
+		subscribers: map[int]map[chan event]bool{},
+		watchers:    map[int]map[chan event]bool{},
+	}
+}
+
+// subscribe - open a stream for a player
+// - watchID is a battle they are watching as a spectator (0 for none)
+// - Returns anything the player missed since lastID (0 means a fresh start)
+func (h *eventHub) subscribe(playerID, watchID int, lastID int64) (chan event, []event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan event, 16)
+	if h.subscribers[playerID] == nil {
+		h.subscribers[playerID] = map[chan event]bool{}
+	}
+	h.subscribers[playerID][ch] = true
+	if watchID != 0 {
+		if h.watchers[watchID] == nil {
+			h.watchers[watchID] = map[chan event]bool{}
+		}
+		h.watchers[watchID][ch] = true
+	}
+
+	var missed []event
+	if lastID > 0 {
+		for _, e := range h.backlog {
+			if e.ID > lastID && (e.isFor(playerID) || e.isForWatchers(watchID)) {
+				missed = append(missed, e)
+			}
+		}
+	}
+	return ch, missed
+}
+
+// unsubscribe - the stream has closed
+func (h *eventHub) unsubscribe(playerID, watchID int, ch chan event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers[playerID], ch)
+	if len(h.subscribers[playerID]) == 0 {
+		delete(h.subscribers, playerID)
+	}
+	delete(h.watchers[watchID], ch)
+	if len(h.watchers[watchID]) == 0 {
+		delete(h.watchers, watchID)
+	}
+}
+
+// publish - send an event to the given players (and the battle's spectators; see spectatorEvents)
+// - Never blocks a handler; a stream that has fallen that far behind will
+//   be refreshed by the next event it does receive
+func (h *eventHub) publish(eventType string, battleID int, data interface{}, players ...int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	e := event{ID: h.nextID, Type: eventType, BattleID: battleID, Data: data, players: players}
+	h.backlog = append(h.backlog, e)
+	if len(h.backlog) > eventBacklog {
+		h.backlog = h.backlog[len(h.backlog)-eventBacklog:]
+	}
+	streams := map[chan event]bool{}
+	for _, p := range players {
+		for ch := range h.subscribers[p] {
+			streams[ch] = true
+		}
+	}
+	if e.isForWatchers(battleID) {
+		for ch := range h.watchers[battleID] {
+			streams[ch] = true
+		}
+	}
+	for ch := range streams {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	if h.forward != nil {
+		go h.forward(e)
+	}
+}
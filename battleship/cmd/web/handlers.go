@@ -9,13 +9,17 @@ import (
 	"fmt"
 	"golang.org/x/xerrors"
 	"html/template"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/519seven/cs610/battleship/pkg/forms"
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/matchmaking"
 	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/webhook"
 )
 
 // BEGIN AUTH
@@ -88,10 +92,8 @@ func (app *application) getSignupForm(w http.ResponseWriter, r *http.Request) {
 func (app *application) postSignup(w http.ResponseWriter, r *http.Request) {
 	// Create a new forms.Form struct containing the POSTed data from the
 	//  form, then use the validation methods to check the content.
-	fmt.Println("here...")
 	err := r.ParseForm()
 	if err != nil {
-		fmt.Println("Form is empty")
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
@@ -190,16 +192,210 @@ func (app *application) acceptBattle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	_, err = app.battles.Accept(playerID, app.session.GetInt(r, "boardID"), battleID)
+	if errors.Is(err, models.ErrBoardSizeMismatch) {
+		app.session.Put(r, "flash", "Your selected board is not the same size as your challenger's board!")
+		http.Redirect(w, r, "/status/battles/list", http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, models.ErrBattleStatus) {
+		app.session.Put(r, "flash", "That challenge is no longer open.")
+		http.Redirect(w, r, "/status/battles/list", http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, models.ErrNoRecord) {
+		app.session.Put(r, "flash", "Select one of your saved boards, then accept an open challenge!")
+		http.Redirect(w, r, "/board/list", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
 		app.session.Put(r, "flash", "The person who accepted this board was not the person challenged!")
 		app.serverError(w, err)
 		return
 	}
+	if b, err := app.battles.Get(playerID, battleID); err == nil {
+		app.events.publish(eventAccept, battleID, map[string]string{"opponent": app.session.GetString(r, "screenName")}, b.Player1ID)
+		app.notify(b.Player1ID, battleID, models.NoticeAccept, "%s has accepted your challenge", b.Player2ScreenName)
+	}
 	app.session.Put(r, "flash", "You have accepted the battle!")
 	http.Redirect(w, r, fmt.Sprintf("/battle/view/%d", battleID), http.StatusSeeOther)
 }
 
 
+// Decline battle (challenge) - only the player who was challenged can say no
+func (app *application) declineBattle(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	battleID, err := strconv.Atoi(r.PostForm.Get("battleID"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.battles.Decline(playerID, battleID)
+	if app.battleStepFailed(w, r, err, "That challenge is no longer open.") {
+		return
+	}
+	if b, err := app.battles.Get(playerID, battleID); err == nil {
+		app.events.publish(eventDecline, battleID, map[string]string{"opponent": b.Player2ScreenName}, b.Player1ID)
+	}
+	app.seenBattle(playerID, battleID)
+	app.session.Put(r, "flash", "You have declined the challenge.")
+	http.Redirect(w, r, "/status/battles/list", http.StatusSeeOther)
+}
+
+// Withdraw battle (challenge) - the challenger changed their mind before it was accepted
+func (app *application) withdrawBattle(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	battleID, err := strconv.Atoi(r.PostForm.Get("battleID"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.battles.Withdraw(playerID, battleID)
+	if app.battleStepFailed(w, r, err, "That challenge has already been answered.") {
+		return
+	}
+	if b, err := app.battles.Get(playerID, battleID); err == nil {
+		app.events.publish(eventWithdraw, battleID, map[string]string{"challenger": b.Player1ScreenName}, b.Player2ID)
+	}
+	app.session.Put(r, "flash", "You have withdrawn your challenge.")
+	http.Redirect(w, r, "/status/battles/list", http.StatusSeeOther)
+}
+
+// Resign battle - give up; the opponent wins
+func (app *application) resignBattle(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	battleID, err := strconv.Atoi(r.PostForm.Get("battleID"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	winner, err := app.battles.Resign(playerID, battleID)
+	if app.battleStepFailed(w, r, err, "That battle isn't being played; there is nothing to resign.") {
+		return
+	}
+	app.announceResignation(playerID, battleID, winner)
+	app.session.Put(r, "flash", "You have resigned. Better luck next time!")
+	http.Redirect(w, r, "/status/battles/list", http.StatusSeeOther)
+}
+
+// battleStepFailed - report a decline, withdraw or resign that didn't happen
+// - A battle that isn't yours looks the same as one that doesn't exist
+// - Returns false if there was nothing to report
+func (app *application) battleStepFailed(w http.ResponseWriter, r *http.Request, err error, tooLate string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, models.ErrNoRecord), errors.Is(err, models.ErrNotYourBattle):
+		app.notFound(w)
+	case errors.Is(err, models.ErrBattleStatus):
+		app.session.Put(r, "flash", tooLate)
+		http.Redirect(w, r, "/status/battles/list", http.StatusSeeOther)
+	default:
+		app.serverError(w, err)
+	}
+	return true
+}
+
+
+// Replay battle - step through a finished battle move by move
+// - The page loads the moves from /api/v1/battles/:id/moves
+func (app *application) replayBattle(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	battleID, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || battleID < 1 {
+		app.notFound(w)
+		return
+	}
+	b, err := app.battles.Get(playerID, battleID)
+	if err != nil {
+		if xerrors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	if !b.Status.Over() {
+		app.session.Put(r, "flash", "You can replay a battle once it's over.")
+		http.Redirect(w, r, "/status/battles/list", http.StatusSeeOther)
+		return
+	}
+	app.renderBattle(w, r, "replay.battle.page.tmpl", &templateDataBattle{
+		Battle:				b,
+	})
+}
+
+
+
+// Spectate - the battles a player can watch (everyone else's, unless a player keeps spectators out)
+func (app *application) listWatchable(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	b, err := app.battles.ListWatchable(playerID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderBattles(w, r, "list.spectate.page.tmpl", &templateDataBattles{
+		Battles: 			b,
+	})
+}
+
+
+// Spectate battle - watch someone else's battle; only the pins are shown, never the fleets
+// - The page loads the moves from /api/v1/battles/:id/moves and listens on /events?watch=:id
+// - A player in the battle is sent to their own view of it
+func (app *application) watchBattle(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	battleID, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || battleID < 1 {
+		app.notFound(w)
+		return
+	}
+	b, err := app.battles.Watch(battleID)
+	if err != nil {
+		if xerrors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	if playerID == b.Player1ID || playerID == b.Player2ID {
+		http.Redirect(w, r, fmt.Sprintf("/battle/view/%d", battleID), http.StatusSeeOther)
+		return
+	}
+	app.renderBattle(w, r, "spectate.battle.page.tmpl", &templateDataBattle{
+		Battle:				b,
+	})
+}
+
+
+// Spectators - a player keeps others from watching their battle, or lets them watch again
+func (app *application) setSpectators(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	battleID, err := strconv.Atoi(r.PostForm.Get("battleID"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	private := r.PostForm.Get("private") == "true"
+	err = app.battles.SetPrivate(playerID, battleID, private)
+	if errors.Is(err, models.ErrNoRecord) || errors.Is(err, models.ErrNotYourBattle) {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if private {
+		app.session.Put(r, "flash", "Nobody else can watch this battle.")
+	} else {
+		app.session.Put(r, "flash", "Other players can watch this battle, unless your opponent keeps them out.")
+	}
+	http.Redirect(w, r, fmt.Sprintf("/battle/view/%d", battleID), http.StatusSeeOther)
+}
+
+
 // Enter battle
 func (app *application) enterBattle(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Entering the battlefield...")
@@ -244,6 +440,7 @@ func (app *application) enterBattle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	app.seenBattle(playerID, battleID)
 	app.renderBattle(w, r, "enter.battle.page.tmpl", &templateDataBattle{
 		Battle:							b,
 		ChallengerID:					int(b.Player1ID),
@@ -252,6 +449,7 @@ func (app *application) enterBattle(w http.ResponseWriter, r *http.Request) {
 		OpponentID:						int(b.Player2ID),
 		OpponentBoardID:				int(b.Player2BoardID),
 		OpponentPositions:				o,
+		MaxMessageLength:				maxMessageLength,
 	})
 }
 
@@ -325,6 +523,7 @@ func (app *application) viewBattle(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	app.seenBattle(playerID, battleID)
 	app.renderBattle(w, r, "display.battle.page.tmpl", &templateDataBattle{
 		Battle:							b,
 		ChallengerID:					int(b.Player1ID),
@@ -358,71 +557,47 @@ func (app *application) createBoard(w http.ResponseWriter, r *http.Request) {
 	form.Required("boardName")
 	form.MaxLength("boardName", 35)
 
+	// Boards are square; anything from MinSize to MaxSize squares wide
+	boardSize := game.DefaultSize
+	if form.Get("boardSize") != "" {
+		boardSize, err = strconv.Atoi(form.Get("boardSize"))
+		if err != nil || !game.ValidSize(boardSize) {
+			form.Errors.Add("boardSize", fmt.Sprintf("Boards must be between %d and %d squares wide", game.MinSize, game.MaxSize))
+			boardSize = game.DefaultSize
+		}
+	}
+
 	// Before returning to the caller, let's check the validity of the ship coordinates
 	// - If anything is amiss, we can send those errors back as well
-	var carrier []string
-	cInd := 0
-	var battleship []string
-	bInd := 0
-	var cruiser []string
-	rInd := 0
-	var submarine []string
-	sInd := 0
-	var destroyer []string
-	dInd := 0
+	// - coordinates is keyed by ship type, e.g. "carrier" => ["4,H", "5,H", ...]
+	coordinates := map[string][]string{}
 	// Loop through the POSTed data, checking for their values
 	// - Add coordinates to a given ship's array
-    for row := 1; row < 11; row++ {
+    for row := 1; row <= boardSize; row++ {
 		rowStr := strconv.Itoa(row)
- 		for _, col := range "ABCDEFGHIJ" {
-			colStr := string(col)
+ 		for _, colStr := range game.Columns(boardSize) {
 			shipXY := form.Get("shipXY"+rowStr+colStr)
 			if shipXY != "" {
-				// Only I, the program, should be permitted to update this as a player enters a game
-				//battleID := r.URL.Query().Get("battleID")
-				// playerID should be gotten from somewhere else
-				//playerID = r.PostForm("playerID")
-
-				// Upper the values to simplify testing
-				// - Build the slices containing the submitted coordinates
-				switch strings.ToUpper(shipXY) {
-				case "C":
-					carrier = append(carrier, rowStr+","+colStr)
-					cInd += 1
-				case "B":
-					battleship = append(battleship, rowStr+","+colStr)
-					bInd += 1
-				case "R":
-					cruiser = append(cruiser, rowStr+","+colStr)
-					rInd += 1
-				case "S":
-					submarine = append(submarine, rowStr+","+colStr)
-					sInd += 1
-				case "D":
-					destroyer = append(destroyer, rowStr+","+colStr)
-					dInd += 1
-				default:
+				// The letter typed into the square tells us which ship it belongs to
+				ship, ok := app.fleet.ByAbbreviation(shipXY)
+				if !ok {
 					// Add this to Form's error object?
 					// - I don't think it helps to tell the user this info
 					//   unless they're struggling to build the board
 					fmt.Println("Unsupported character:", shipXY)
+					continue
 				}
+				coordinates[ship.Type] = append(coordinates[ship.Type], rowStr+","+colStr)
 			}
 		}
 	}
 
 	// Test our numbers, update .Valid property of our Form object
-	form.RequiredNumberOfItems("carrier", 5, cInd)
-	form.RequiredNumberOfItems("battleship", 4, bInd)
-	form.RequiredNumberOfItems("cruiser", 3, rInd)
-	form.RequiredNumberOfItems("submarine", 3, sInd)
-	form.RequiredNumberOfItems("destroyer", 2, dInd)
-
-	form.ValidNumberOfItems(carrier, "carrier")
-	form.ValidNumberOfItems(battleship, "battleship")
-	form.ValidNumberOfItems(cruiser, "cruiser")
-	form.ValidNumberOfItems(submarine, "submarine")
-	form.ValidNumberOfItems(destroyer, "destroyer")
+	board := game.NewBoard(boardSize, app.fleet)
+	for _, ship := range app.fleet {
+		form.RequiredNumberOfItems(ship.Type, ship.Length, len(coordinates[ship.Type]))
+		form.ValidNumberOfItems(board, coordinates[ship.Type], ship.Type)
+	}
 
 	// If our validation has failed anywhere along the way
 	// - Take the user back to their board
@@ -436,55 +611,102 @@ func (app *application) createBoard(w http.ResponseWriter, r *http.Request) {
 	// - We have a boardID, playerID, shipName, and a bunch of coordinates
 
 	// Create a new board, return boardID
-	boardID, _ := app.boards.Create(playerID, form.Get("boardName"))
-
-	// Carrier
-	_, err = app.boards.Insert(playerID, boardID, "carrier", carrier)
+	boardID, err := app.boards.Create(playerID, form.Get("boardName"), boardSize)
 	if err != nil {
-		app.serverError(w, err)
+		if errors.Is(err, models.ErrDuplicateBoardName) {
+			form.Errors.Add("boardName", "You already have a board with that name")
+			app.renderBoard(w, r, "create.board.page.tmpl", &templateDataBoard{Form: form})
+		} else {
+			app.serverError(w, err)
+		}
 		return
 	}
 
-	// Battleship
-	_, err = app.boards.Insert(playerID, boardID, "battleship", battleship)
-	if err != nil {
-		app.serverError(w, err)
-		return
+	// Save each ship in the fleet
+	for _, ship := range app.fleet {
+		_, err = app.boards.Insert(playerID, boardID, ship.Type, coordinates[ship.Type])
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
 	}
 
-	// Cruiser
-	_, err = app.boards.Insert(playerID, boardID, "cruiser", cruiser)
-	if err != nil {
-		app.serverError(w, err)
-		return
+	// Add status message to session data; create new if one doesn't exist
+	app.session.Put(r, "flash", "Board successfully created!")
+	// Send user back to list of boards
+	http.Redirect(w, r, "/board/list", http.StatusSeeOther)
+}
+
+
+// Display new form for creating a game board
+func (app *application) createBoardForm(w http.ResponseWriter, r *http.Request) {
+	// The size picker reloads this page with ?size=N
+	form := forms.New(url.Values{})
+	form.Set("boardSize", strconv.Itoa(game.DefaultSize))
+	if size, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil && game.ValidSize(size) {
+		form.Set("boardSize", strconv.Itoa(size))
 	}
+	app.renderBoard(w, r, "create.board.page.tmpl", &templateDataBoard {
+		Form: 				form,
+	})
+}
 
-	// Submarine
-	_, err = app.boards.Insert(playerID, boardID, "submarine", submarine)
+
+// Randomize board - let the server place the fleet
+// - "Randomize" fills in the grid so the player can look it over (and adjust it) before saving
+// - "Randomize and save" saves it straight away, just like pressing "Save board"
+// - "noTouching" keeps a gap around every ship
+func (app *application) randomizeBoard(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
 	if err != nil {
-		app.serverError(w, err)
+		app.clientError(w, http.StatusBadRequest)
 		return
 	}
+	form := forms.New(r.PostForm)
+	boardSize, err := strconv.Atoi(form.Get("boardSize"))
+	if err != nil || !game.ValidSize(boardSize) {
+		boardSize = game.DefaultSize
+		form.Set("boardSize", strconv.Itoa(boardSize))
+	}
 
-	// Destroyer
-	_, err = app.boards.Insert(playerID, boardID, "destroyer", destroyer)
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	board, err := game.RandomBoard(boardSize, app.fleet, rng, game.Placement{NoTouching: form.Get("noTouching") != ""})
 	if err != nil {
-		app.serverError(w, err)
+		form.Errors.Add("boardSize", placementProblem(err))
+		app.renderBoard(w, r, "create.board.page.tmpl", &templateDataBoard{Form: form})
 		return
 	}
 
-	// Add status message to session data; create new if one doesn't exist
-	app.session.Put(r, "flash", "Board successfully created!")
-	// Send user back to list of boards
-	http.Redirect(w, r, "/board/list", http.StatusSeeOther)
-}
+	// Write the letters into the grid, exactly as if the player had typed them
+	for row := 1; row <= boardSize; row++ {
+		for _, col := range game.Columns(boardSize) {
+			form.Del("shipXY" + strconv.Itoa(row) + col)
+		}
+	}
+	for _, ship := range app.fleet {
+		for _, s := range board.ShipSquares(ship.Type) {
+			form.Set(fmt.Sprintf("shipXY%d%s", s.CoordX, s.CoordY), ship.Abbreviation)
+		}
+	}
 
+	// r.PostForm now holds the new placement, so createBoard can take it from here
+	if form.Get("save") != "" {
+		app.createBoard(w, r)
+		return
+	}
+	app.renderBoard(w, r, "create.board.page.tmpl", &templateDataBoard{Form: form})
+}
 
-// Display new form for creating a game board
-func (app *application) createBoardForm(w http.ResponseWriter, r *http.Request) {
-	app.renderBoard(w, r, "create.board.page.tmpl", &templateDataBoard {
-		Form: 				forms.New(nil),
-	})
+// placementProblem - why the fleet couldn't be placed at random, for the player
+func placementProblem(err error) string {
+	var pe *game.PlacementError
+	if !errors.As(err, &pe) {
+		return "The fleet couldn't be placed on this board"
+	}
+	if pe.NoTouching {
+		return fmt.Sprintf("There's no room for the %s on this %dx%d board with a gap around every ship.  Try a bigger board, or let the ships touch.", pe.Ship.Name, pe.Size, pe.Size)
+	}
+	return fmt.Sprintf("There's no room for the %s on this %dx%d board.  Try a bigger board.", pe.Ship.Name, pe.Size, pe.Size)
 }
 
 
@@ -514,6 +736,7 @@ func (app *application) displayBoard(w http.ResponseWriter, r *http.Request) {
 		} else {
 			app.serverError(w, err)
 		}
+		return
 	}
 	app.renderBoard(w, r, "display.board.page.tmpl", &templateDataBoard{
 		Positions: 			p,
@@ -578,6 +801,121 @@ func (app *application) updateBoard(w http.ResponseWriter, r *http.Request) {
 // END BOARDS
 // ----------------------------------------------------------------------------
 
+// ----------------------------------------------------------------------------
+// BEGIN MATCHMAKING
+
+// Display the matchmaking page - join the queue, or see how long you've been in it
+func (app *application) showQueue(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	td := &templateDataPlayers{Waiting: app.queue.Len()}
+	if t, ok := app.queue.Waiting(playerID); ok {
+		td.Ticket = &t
+		td.Band = matchmaking.Band(time.Since(t.Joined))
+	}
+	app.renderPlayers(w, r, "matchmaking.page.tmpl", td)
+}
+
+
+// Join the matchmaking queue with the selected board
+func (app *application) joinQueue(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	boardID := app.session.GetInt(r, "boardID")
+	if boardID < 1 {
+		app.session.Put(r, "flash", "You must select your board first, then look for an opponent!")
+		http.Redirect(w, r, "/board/list", http.StatusSeeOther)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form := forms.New(r.PostForm)
+	salvoShots, _ := strconv.Atoi(form.Get("salvoShots"))
+	rules, err := game.ParseRules(form.Get("rules"), salvoShots, app.fleet)
+	if err == nil {
+		rules.TurnTime, err = game.ParseTurnTime(form.Get("turnTime"))
+	}
+	if err != nil {
+		app.session.Put(r, "flash", "Those rules don't make sense; you are not in the queue.")
+		http.Redirect(w, r, "/matchmaking", http.StatusSeeOther)
+		return
+	}
+	_, err = app.enqueue(playerID, boardID, rules)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.session.Put(r, "flash", "You must select one of your saved boards first, then look for an opponent!")
+		http.Redirect(w, r, "/board/list", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.session.Put(r, "flash", "You're in the queue!  Your battle starts as soon as we find you an opponent.")
+	http.Redirect(w, r, "/matchmaking", http.StatusSeeOther)
+}
+
+
+// Leave the matchmaking queue
+func (app *application) leaveQueue(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	if app.queue.Leave(playerID) {
+		app.session.Put(r, "flash", "You have left the queue.")
+	} else {
+		app.session.Put(r, "flash", "You weren't in the queue; if you were matched, your battle is waiting.")
+	}
+	http.Redirect(w, r, "/matchmaking", http.StatusSeeOther)
+}
+
+// END MATCHMAKING
+// ----------------------------------------------------------------------------
+
+
+// ----------------------------------------------------------------------------
+// BEGIN NOTIFICATIONS
+
+// Inbox - the player's most recent notifications, newest first
+func (app *application) showNotifications(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	n, err := app.notifications.List(playerID, notificationsShown)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderNotifications(w, r, "notifications.page.tmpl", &templateDataNotifications{
+		Notifications:		n,
+	})
+}
+
+// Mark notifications read - one (notificationID) or, without one, all of them
+func (app *application) readNotifications(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	var err error
+	if id := r.PostForm.Get("notificationID"); id != "" {
+		notificationID, convErr := strconv.Atoi(id)
+		if convErr != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+		err = app.notifications.MarkRead(playerID, notificationID)
+	} else {
+		err = app.notifications.MarkAllRead(playerID)
+	}
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.publishUnread(playerID)
+	http.Redirect(w, r, "/notifications", http.StatusSeeOther)
+}
+
+// END NOTIFICATIONS
+// ----------------------------------------------------------------------------
+
 // ----------------------------------------------------------------------------
 // BEGIN PLAYERS
 
@@ -605,6 +943,7 @@ func (app *application) challengePlayer(w http.ResponseWriter, r *http.Request)
 	if player1BoardID < 1 {
 		app.session.Put(r, "flash", "You must select your board first, then issue a challenge!")
 		http.Redirect(w, r, "/board/list", http.StatusSeeOther)
+		return
 	}
 
 	// Player2 information is retrieved from form
@@ -620,11 +959,47 @@ func (app *application) challengePlayer(w http.ResponseWriter, r *http.Request)
 			return
 		}
 	}
-	_, err = app.battles.Create(player1ID, player1BoardID, player2ID, secretTurn)
+	// Classic (one shot a turn) unless the challenger asked for a salvo
+	salvoShots, _ := strconv.Atoi(form.Get("salvoShots"))
+	rules, err := game.ParseRules(form.Get("rules"), salvoShots, app.fleet)
+	if err != nil {
+		app.session.Put(r, "flash", "Those rules don't make sense; challenge was not sent.")
+		http.Redirect(w, r, "/player/list", http.StatusSeeOther)
+		return
+	}
+	// No turn clock unless the challenger picked one
+	if rules.TurnTime, err = game.ParseTurnTime(form.Get("turnTime")); err != nil {
+		app.session.Put(r, "flash", "That turn clock doesn't make sense; challenge was not sent.")
+		http.Redirect(w, r, "/player/list", http.StatusSeeOther)
+		return
+	}
+	if _, err = game.ParseStrategy(form.Get("aiStrategy")); err != nil {
+		app.session.Put(r, "flash", "The Computer doesn't know that strategy; challenge was not sent.")
+		http.Redirect(w, r, "/player/list", http.StatusSeeOther)
+		return
+	}
+	battleID, err := app.battles.Create(player1ID, player1BoardID, player2ID, secretTurn, rules)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.session.Put(r, "flash", "You must select one of your saved boards first, then issue a challenge!")
+		http.Redirect(w, r, "/board/list", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
+	// The computer doesn't keep anyone waiting
+	if player2ID == app.computerID {
+		if err = app.challengeComputer(battleID, form.Get("aiStrategy")); err != nil {
+			app.serverError(w, err)
+			return
+		}
+		app.session.Put(r, "flash", "The Computer has accepted your challenge!")
+		http.Redirect(w, r, fmt.Sprintf("/battle/view/%d", battleID), http.StatusSeeOther)
+		return
+	}
+	app.events.publish(eventChallenge, battleID, map[string]string{"challenger": app.session.GetString(r, "screenName")}, player2ID)
+	app.notify(player2ID, battleID, models.NoticeChallenge, "%s has challenged you to a battle", app.session.GetString(r, "screenName"))
 	// This "update" now happens in "Create" - not ideal!
 	//app.battles.UpdateChallenge(player1ID, player2ID, false, battleID)
 
@@ -634,37 +1009,42 @@ func (app *application) challengePlayer(w http.ResponseWriter, r *http.Request)
 }
 
 
-// Find out if there are any challenges for the user
+// Find out if there are any challenges (or unread notifications) for the user
+// - JSON, for pages that can't keep an event stream open (see main.js)
+// - status is "challenge" (and redirect is where to answer it) while someone has challenged them
+// - unread is the count in the nav bar
 func (app *application) challengeStatus(w http.ResponseWriter, r *http.Request) {
-	// If we have a challenge, return JSON to client
-	playerID := app.session.GetInt(r, "authenticatedPlayerID")	// get from session??
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
 	challengerID, err := app.battles.GetChallenger(playerID)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
-	if challengerID > 0 {
-		type JsonResponse struct {
-			Status 			string 		`json:"status"`
-			NextPage		string		`json:"redirect"`
-			Time			time.Time	`json:"timestamp"`
-		}
-		redirect := "/status/battles/list"
-		var JR JsonResponse
+	unread, err := app.notifications.Unread(playerID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	type JsonResponse struct {
+		Status 			string 		`json:"status"`
+		NextPage		string		`json:"redirect,omitempty"`
+		Unread			int			`json:"unread"`
+		Time			time.Time	`json:"timestamp"`
+	}
+	var JR JsonResponse
+	if challengerID > 0 {
 		JR.Status = "challenge"
-		JR.NextPage = redirect
-		JR.Time = time.Now()
+		JR.NextPage = "/status/battles/list"
+	}
+	JR.Unread = unread
+	JR.Time = time.Now()
 
-		out, err := json.Marshal(JR)
-		if err != nil {
-			app.serverError(w, err)
-			return
-		}
-		app.renderJson(w, r, out)
-	} else {
-		// do nothing
+	out, err := json.Marshal(JR)
+	if err != nil {
+		app.serverError(w, err)
 		return
 	}
+	app.renderJson(w, r, out)
 }
 
 
@@ -702,8 +1082,210 @@ func (app *application) displayPlayer(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	// Your own page also lists your API tokens and webhooks
+	var tokens []*models.APIToken
+	var webhooks []*models.Webhook
+	if playerID == app.session.GetInt(r, "authenticatedPlayerID") {
+		tokens, err = app.tokens.List(playerID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		webhooks, err = app.webhooks.List(playerID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+	ratings, err := app.ratings.History(playerID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 	app.renderPlayer(w, r, "player.page.tmpl", &templateDataPlayer{
 		Player: 			s,
+		Ratings:			ratings,
+		Tokens:				tokens,
+		Scopes:				[]string{models.ScopeRead, models.ScopePlay},
+		Webhooks:			webhooks,
+		WebhookEvents:		webhook.Events,
+	})
+}
+
+
+// Create an API token for scripts and other non-browser clients
+// - The token is shown once, in the flash message; only its hash is kept
+func (app *application) createToken(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form := forms.New(r.PostForm)
+	form.Required("tokenName", "scope")
+	form.MaxLength("tokenName", 35)
+	for _, scope := range form.Values["scope"] {
+		if scope != models.ScopeRead && scope != models.ScopePlay {
+			form.Errors.Add("scope", "Unknown scope")
+		}
+	}
+	if !form.Valid() {
+		app.session.Put(r, "flash", "An API token needs a name and at least one scope.")
+		http.Redirect(w, r, fmt.Sprintf("/player/%d", playerID), http.StatusSeeOther)
+		return
+	}
+	token, err := app.tokens.Insert(playerID, form.Get("tokenName"), form.Values["scope"])
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.session.Put(r, "flash", fmt.Sprintf("Your new API token is %s - copy it now, it won't be shown again.", token))
+	http.Redirect(w, r, fmt.Sprintf("/player/%d", playerID), http.StatusSeeOther)
+}
+
+
+// Revoke one of your API tokens
+func (app *application) revokeToken(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	form := forms.New(r.PostForm)
+	tokenID, err := strconv.Atoi(form.Get("tokenID"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.tokens.Revoke(playerID, tokenID)
+	if err != nil {
+		if xerrors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	app.session.Put(r, "flash", "API token revoked.")
+	http.Redirect(w, r, fmt.Sprintf("/player/%d", playerID), http.StatusSeeOther)
+}
+
+
+// Register a webhook - the events of your battles get sent to it (see webhooks.go)
+// - No event boxes ticked is the same as all of them
+func (app *application) createWebhook(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	form := forms.New(r.PostForm)
+	h, problems, err := app.addWebhook(playerID, form.Get("webhookURL"), form.Values["event"])
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if problems != nil {
+		problem := problems["url"]
+		if len(problem) == 0 {
+			problem = problems["events"]
+		}
+		app.session.Put(r, "flash", problem[0]+".")
+		http.Redirect(w, r, fmt.Sprintf("/player/%d", playerID), http.StatusSeeOther)
+		return
+	}
+	app.session.Put(r, "flash", fmt.Sprintf("Webhook added; deliveries to %s are signed with the secret shown below.", h.URL))
+	http.Redirect(w, r, fmt.Sprintf("/player/%d", playerID), http.StatusSeeOther)
+}
+
+
+// Delete one of your webhooks
+func (app *application) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	webhookID, err := strconv.Atoi(r.PostForm.Get("webhookID"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.webhooks.Delete(playerID, webhookID)
+	if err != nil {
+		if xerrors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	app.session.Put(r, "flash", "Webhook deleted.")
+	http.Redirect(w, r, fmt.Sprintf("/player/%d", playerID), http.StatusSeeOther)
+}
+
+
+// Send a test ping to one of your webhooks and show how it went
+func (app *application) sendTestWebhook(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	webhookID, err := strconv.Atoi(r.PostForm.Get("webhookID"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	h, err := app.webhooks.Get(playerID, webhookID)
+	if err != nil {
+		if xerrors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	d, err := app.testWebhook(h)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if d.Status == models.DeliveryDelivered {
+		app.session.Put(r, "flash", fmt.Sprintf("Test delivered; %s answered %d.", h.URL, d.StatusCode))
+	} else {
+		app.session.Put(r, "flash", fmt.Sprintf("Test failed: %s", d.Error))
+	}
+	http.Redirect(w, r, fmt.Sprintf("/player/webhooks/%d", webhookID), http.StatusSeeOther)
+}
+
+
+// Display one of your webhooks and its delivery log
+func (app *application) showWebhook(w http.ResponseWriter, r *http.Request) {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	webhookID, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || webhookID < 1 {
+		app.notFound(w)
+		return
+	}
+	h, err := app.webhooks.Get(playerID, webhookID)
+	if err != nil {
+		if xerrors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	deliveries, err := app.webhooks.Deliveries(webhookID, webhookDeliveriesShown)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderPlayer(w, r, "webhook.page.tmpl", &templateDataPlayer{
+		Webhook:			h,
+		Deliveries:			deliveries,
+	})
+}
+
+
+// How many players the leaderboard shows
+const leaderboardSize = 50
+
+// Display the leaderboard - everyone who has finished a battle, best rated first
+func (app *application) leaderboard(w http.ResponseWriter, r *http.Request) {
+	p, err := app.ratings.Leaderboard(leaderboardSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderPlayers(w, r, "leaderboard.page.tmpl", &templateDataPlayers{
+		Players: 			p,
 	})
 }
 
@@ -750,26 +1332,44 @@ func (app *application) updatePosition(w http.ResponseWriter, r *http.Request) {
 	newSecret, err := app.GenerateRandomString(32)
 	if err != nil {
 		app.serverError(w, err)
+		return
 	}
 	battleID, err := strconv.Atoi(r.URL.Query().Get("battleID"))
 	if err != nil {
-		app.serverError(w, err)
+		app.clientError(w, http.StatusBadRequest)
+		return
 	}
 	boardID, err := strconv.Atoi(r.URL.Query().Get("boardID"))
 	if err != nil {
-		app.serverError(w, err)
+		app.clientError(w, http.StatusBadRequest)
+		return
 	}
 	playerID := app.session.GetInt(r, "authenticatedPlayerID")
 
+	// shipXY ends with the row and column ("...10J" or "...4C")
 	shipXY := r.URL.Query().Get("shipXY")
-	coordX, err := strconv.Atoi(shipXY[len(shipXY)-2:])		// get the second-to-last character (!!!BUG!!!)
-	if err != nil {
-		app.serverError(w, err)
+	if len(shipXY) < 2 {
+		app.clientError(w, http.StatusBadRequest)
+		return
 	}
 	coordY := shipXY[len(shipXY)-1:]						// get the last character
-	pinColor, _, winner, err := app.positions.Update(playerID, playerID, battleID, boardID, coordX, coordY, newSecret)
+	start := len(shipXY) - 1								// and the digits in front of it
+	for start > 0 && shipXY[start-1] >= '0' && shipXY[start-1] <= '9' {
+		start--
+	}
+	coordX, err := strconv.Atoi(shipXY[start : len(shipXY)-1])
 	if err != nil {
-		app.serverError(w, err)
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	secretTurn := r.URL.Query().Get("secretTurn")
+	pinColor, _, winner, err := app.positions.Update(playerID, playerID, battleID, boardID, coordX, coordY, secretTurn, newSecret)
+	if err != nil {
+		if strikeErrorCode(err) != "" {
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, err)
+		}
 		return
 	}
 	app.session.Put(r, "flash", fmt.Sprintf("Position (id #%s) has been updated...", pinColor))
@@ -814,23 +1414,40 @@ func (app *application) getStrikes(w http.ResponseWriter, r *http.Request) {
 			Turn 			string					`json:"turn"`
 			Positions		[]*models.Position		`json:"strikes"`
 			Winner			string					`json:"winner"`
+			BoardSize		int						`json:"board_size"`
+			Rules			string					`json:"rules"`
+			ShotsPerTurn	int						`json:"shots_per_turn"`
+			Deadline		*time.Time				`json:"deadline,omitempty"`
 		}
 		var JR JsonResponse
 
-		_, secretTurn := app.battles.CheckTurn(battleID, playerID)
-		JR.Turn = secretTurn
+		b, err := app.battles.Get(playerID, battleID)
 		if err != nil {
 			app.serverError(w, err)
-			app.errorLog.Println("Error", err.Error())
 			return
 		}
+		JR.BoardSize = b.BoardSize
+		JR.Rules = b.Rules
+		if b.Deadline.Valid && !b.Status.Over() {
+			JR.Deadline = &b.Deadline.Time
+		}
+		JR.ShotsPerTurn, err = app.positions.ShotsPerTurn(battleID, playerID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		// Nobody has a turn once the battle is over
+		if !b.Status.Over() {
+			_, JR.Turn = app.battles.CheckTurn(battleID, playerID)
+		}
 		positions, err := app.positions.List(boardID, playerID)
 		if err != nil {
 			app.serverError(w, err)
 			return
 		}
 		JR.Positions = positions
-		winner, err := app.positions.CheckWinner(playerID, boardID)
+		winner, err := app.positions.CheckWinner(playerID, battleID)
 		if err != nil {
 			app.serverError(w, err)
 			return
@@ -852,65 +1469,149 @@ func (app *application) getStrikes(w http.ResponseWriter, r *http.Request) {
 
 
 // When a player launches a strike, see if it is a hit (make pinColor=1) and record strike
+// - A classic turn sends coordX/coordY
+// - A salvo sends every shot of the volley as repeated "shot" fields ("row,col")
+// - A strike that is off the board, already fired on or out of turn comes back
+//   with valid=false, an error and a code (see strikeErrorCode)
 func (app *application) recordStrike(w http.ResponseWriter, r *http.Request) {
+	type ShotResult struct {
+		CoordX			int				`json:"coord_x"`
+		CoordY			string			`json:"coord_y"`
+		Result			string			`json:"result"`
+		PinColor		string			`json:"pin_color"`
+		ShipType		string			`json:"sunken_ship"`
+	}
 	type PlayerTurn struct {
 		Valid 			bool			`json:"valid"`
 		PinColor		string			`json:"pin_color"`
 		ShipType		string			`json:"sunken_ship"`
 		Winner			bool			`json:"winner"`
+		Shots			[]ShotResult	`json:"shots"`
+		Error			string			`json:"error,omitempty"`
+		Code			string			`json:"code,omitempty"`
+	}
+	var PT PlayerTurn
+	// A strike that can't be taken comes back with a code; the turn stays put
+	reject := func(code, message string) {
+		PT.Code = code
+		PT.Error = message
+		out, err := json.Marshal(PT)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		app.renderJson(w, r, out)
 	}
 
 	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
 	form := forms.New(r.PostForm)
-	battleID, _ := strconv.Atoi(form.Get("battleID"))
-	boardID, _ := strconv.Atoi(form.Get("boardID"))
-	coordX, _ := strconv.Atoi(form.Get("coordX"))
-	coordY := form.Get("coordY")
-	//fmt.Println("coordX:", coordX, "|coordY:", coordY)
+	battleID, err := strconv.Atoi(form.Get("battleID"))
+	if err != nil {
+		reject(strikeMalformed, "battleID must be a number")
+		return
+	}
+	boardID, err := strconv.Atoi(form.Get("boardID"))
+	if err != nil {
+		reject(strikeMalformed, "boardID must be a number")
+		return
+	}
+	var shots []game.Shot
+	for _, rc := range form.Values["shot"] {
+		s, err := game.ParseCoordinate(rc)
+		if err != nil {
+			reject(strikeMalformed, err.Error())
+			return
+		}
+		shots = append(shots, s)
+	}
+	if len(shots) == 0 {
+		coordX, err := strconv.Atoi(form.Get("coordX"))
+		if err != nil {
+			reject(strikeMalformed, "coordX must be a number")
+			return
+		}
+		shots = append(shots, game.Shot{CoordX: coordX, CoordY: form.Get("coordY")})
+	}
 	//fmt.Println("battleID:", battleID)
 	//fmt.Println("boardID:", boardID)
 	playerID := app.session.GetInt(r, "authenticatedPlayerID")
 	secretTurn := form.Get("secretTurn")
-	var out []byte;
-	var PT PlayerTurn
 
 	playerTakingTheirTurn, validTurn := app.checkTurn(playerID, battleID, secretTurn)
-	if validTurn {
-		// Make sure this player belongs to this battle
-		//checkBattle(playerID, battleID)
-		// Update the database with the new strike, update Turn to be the other player
-		newSecret, err := app.GenerateRandomString(32)
-		if err != nil {
-			app.serverError(w, err)
-		}
-		pinColor, shipType, winner, err := app.positions.Update(playerID, playerTakingTheirTurn, battleID, boardID, coordX, coordY, newSecret)
-		if err != nil {
-			app.infoLog.Println("Update failed for ", playerID, battleID, boardID, coordX, coordY)
-			app.errorLog.Println("Error", err.Error())
-		}
-		//fmt.Println("pinColor is", pinColor)
-		PT.Valid = true
-		PT.PinColor = pinColor
-		PT.ShipType = shipType
-		PT.Winner = winner
-		out, err = json.Marshal(PT)
-		if err != nil {
-			app.serverError(w, err)
-		}
-	} else {
+	if !validTurn {
 		app.errorLog.Println("Looks like somebody is attempting to hack or the person submitting the strike is not in sync with the database...")
-		PT.Valid = false
-		PT.PinColor = ""
-		PT.ShipType = "charlie"
-		PT.Winner = false
-		out, err = json.Marshal(PT)
-		if err != nil {
+		reject(strikeNotYourTurn, models.ErrStaleTurn.Error())
+		return
+	}
+	// Update the database with the new strike(s), update Turn to be the other player
+	// - The model checks the shots against the board and the pins already on it;
+	//   a shot it turns down leaves the turn with this player
+	newSecret, err := app.GenerateRandomString(32)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	outcomes, winner, err := app.positions.Volley(playerID, playerTakingTheirTurn, battleID, boardID, shots, secretTurn, newSecret)
+	if err != nil {
+		code := strikeErrorCode(err)
+		if code == "" {
 			app.serverError(w, err)
+			return
 		}
+		app.infoLog.Println("Strike turned down for ", playerID, battleID, boardID, shots, err)
+		reject(code, err.Error())
+		return
+	}
+	PT.Valid = true
+	for _, o := range outcomes {
+		PT.Shots = append(PT.Shots, ShotResult{o.Shot.CoordX, o.Shot.CoordY, o.String(), o.PinColor(), o.SunkShip()})
+	}
+	// A classic turn still gets its answer at the top level
+	if len(outcomes) == 1 {
+		PT.PinColor = outcomes[0].PinColor()
+		PT.ShipType = outcomes[0].SunkShip()
+	}
+	PT.Winner = winner
+	app.announceStrike(playerID, battleID, PT.Shots, winner)
+	out, err := json.Marshal(PT)
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
 	app.renderJson(w, r, out)
 }
 
+// Codes sent back with a strike that was turned down
+const (
+	strikeMalformed		= "malformed"
+	strikeNotYourTurn	= "not_your_turn"
+	strikeNotYourBattle	= "not_your_battle"
+	strikeOutOfBounds	= "out_of_bounds"
+	strikeAlreadyStruck	= "already_struck"
+	strikeWrongShots	= "wrong_shot_count"
+)
+
+// The code for a strike the model turned down ("" if it is not the player's doing)
+func strikeErrorCode(err error) string {
+	switch {
+	case errors.Is(err, models.ErrStaleTurn):
+		return strikeNotYourTurn
+	case errors.Is(err, models.ErrNotYourBattle), errors.Is(err, models.ErrNoRecord):
+		return strikeNotYourBattle
+	case errors.Is(err, game.ErrOutOfBounds):
+		return strikeOutOfBounds
+	case errors.Is(err, game.ErrAlreadyStruck):
+		return strikeAlreadyStruck
+	case errors.Is(err, game.ErrWrongShotCount):
+		return strikeWrongShots
+	}
+	return ""
+}
+
 // END STRIKE
 // ----------------------------------------------------------------------------
 // -----------------------------------------------------------------------------
@@ -928,3 +1629,110 @@ func (app *application) checkTurn(playerID, battleID int, secretTurn string) (in
 	}
 	return 0, false
 }
+
+// END TURN
+// ----------------------------------------------------------------------------
+// -----------------------------------------------------------------------------
+
+// ----------------------------------------------------------------------------
+// BEGIN EVENTS
+
+// Stream live updates to the logged in player (Server-Sent Events)
+// - ?watch=<battle ID> adds that battle's strikes, turns and result, for a spectator
+// - The stream closes itself before the server's WriteTimeout; the browser
+//   reconnects and sends Last-Event-ID so nothing is lost in between
+func (app *application) streamEvents(w http.ResponseWriter, r *http.Request) {
+	sw, ok := r.Context().Value(contextKeyStreamWriter).(http.ResponseWriter)
+	if !ok {
+		app.serverError(w, errors.New("events: route is missing the keepWriter middleware"))
+		return
+	}
+	flusher, ok := sw.(http.Flusher)
+	if !ok {
+		app.serverError(w, errors.New("events: streaming is not supported"))
+		return
+	}
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	lastID, _ := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+	// ?watch=<battle ID> - a spectator; checked again every time the stream reconnects
+	watchID, _ := strconv.Atoi(r.URL.Query().Get("watch"))
+	if watchID != 0 {
+		if _, err := app.battles.Watch(watchID); err != nil {
+			if xerrors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+	}
+	ch, missed := app.events.subscribe(playerID, watchID, lastID)
+	defer app.events.unsubscribe(playerID, watchID, ch)
+
+	sw.Header().Set("Content-Type", "text/event-stream")
+	sw.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintf(sw, "retry: %d\n\n", eventRetry.Milliseconds())
+	for _, e := range missed {
+		if err := e.write(sw); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	timeout := time.NewTimer(eventStreamLifetime)
+	defer timeout.Stop()
+	for {
+		select {
+		case e := <-ch:
+			if err := e.write(sw); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-timeout.C:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+
+// Tell both players about a strike the instant it has been recorded
+// - The shooter's other tabs and the opponent both see the pins land
+// - Either the turn moves to the opponent or the battle is over
+func (app *application) announceStrike(playerID, battleID int, shots interface{}, winner bool) {
+	b, err := app.battles.Get(playerID, battleID)
+	if err != nil {
+		app.errorLog.Println("Unable to announce strike:", err)
+		return
+	}
+	opponentID := b.Player2ID
+	if opponentID == playerID {
+		opponentID = b.Player1ID
+	}
+	app.events.publish(eventStrike, battleID, shots, playerID, opponentID)
+	if playerID != app.computerID {
+		// whoever just fired is done with their "your turn"
+		app.seenBattle(playerID, battleID)
+	}
+	if winner {
+		app.events.publish(eventWinner, battleID, map[string]int{"winner_id": playerID}, playerID, opponentID)
+		app.notify(playerID, battleID, models.NoticeGameOver, "You sank %s's fleet.  You win!", opponentName(b, playerID))
+		app.notify(opponentID, battleID, models.NoticeGameOver, "%s sank your fleet.  Better luck next time!", opponentName(b, opponentID))
+		return
+	}
+	app.events.publish(eventTurn, battleID, nil, opponentID)
+	app.notify(opponentID, battleID, models.NoticeTurn, "It's your turn against %s", opponentName(b, opponentID))
+	if opponentID == app.computerID {
+		go app.computerTurn(battleID)
+	}
+}
+
+// Tell both players a battle is over because one of them resigned
+func (app *application) announceResignation(playerID, battleID, winner int) {
+	app.events.publish(eventWinner, battleID, map[string]interface{}{"winner_id": winner, "resigned": true}, playerID, winner)
+	app.seenBattle(playerID, battleID)
+	if b, err := app.battles.Get(playerID, battleID); err == nil {
+		app.notify(winner, battleID, models.NoticeGameOver, "%s resigned.  You win!", opponentName(b, winner))
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/matchmaking"
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/models/memory"
+	"github.com/519seven/cs610/battleship/pkg/webhook"
+	"github.com/golangcollege/sessions"
+)
+
+// newTestApplication - the application main builds for -driver memory, without the sample players
+// - Server errors go to stderr, so a failing test shows what went wrong
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+	store := memory.NewStore()
+	app := &application{
+		errorLog:            log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		infoLog:             log.New(ioutil.Discard, "", 0),
+		fleet:               game.DefaultFleet,
+		battles:             &memory.BattleModel{Store: store},
+		boards:              &memory.BoardModel{Store: store},
+		messages:            &memory.MessageModel{Store: store},
+		notifications:       &memory.NotificationModel{Store: store},
+		players:             &memory.PlayerModel{Store: store},
+		positions:           &memory.PositionModel{Store: store, Fleet: game.DefaultFleet},
+		ratings:             &memory.RatingModel{Store: store},
+		ships:               &memory.ShipModel{Store: store},
+		strikes:             &memory.StrikeModel{Store: store},
+		tokens:              &memory.TokenModel{Store: store},
+		webhooks:            &memory.WebhookModel{Store: store},
+		events:              newEventHub(),
+		limiter:             newRateLimiter(60, 20),
+		queue:               matchmaking.NewQueue(),
+		session:             sessions.New([]byte("s6Ndh+pPbnzHbS*+9Pk8qGWhTzbpa@ge")),
+		webhookSender:       &webhook.Sender{},
+		serverWebhookSender: &webhook.Sender{Client: webhook.Trusted()},
+	}
+	if err := app.ships.Sync(app.fleet); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	if app.computerID, err = app.players.EnsureComputer(computerScreenName); err != nil {
+		t.Fatal(err)
+	}
+	if app.templateCache, err = newTemplateCache("../../ui/html/"); err != nil {
+		t.Fatal(err)
+	}
+	app.events.forward = app.forwardEvent
+	return app
+}
+
+// testClient - one browser: its own cookies, and the CSRF token from the last form it was shown
+type testClient struct {
+	t      *testing.T
+	server *httptest.Server
+	client *http.Client
+	csrf   string
+}
+
+var csrfTokenRX = regexp.MustCompile(`name='csrf_token' value='([^']+)'`)
+
+func newTestClient(t *testing.T, ts *httptest.Server) *testClient {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ts.Client()
+	return &testClient{t: t, server: ts, client: &http.Client{Transport: client.Transport, Jar: jar}}
+}
+
+// do - send a request (following redirects); the status, the path it ended up on and the body
+func (c *testClient) do(req *http.Request) (int, string, string) {
+	c.t.Helper()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if m := csrfTokenRX.FindSubmatch(body); m != nil {
+		c.csrf = html.UnescapeString(string(m[1]))
+	}
+	return resp.StatusCode, resp.Request.URL.Path, string(body)
+}
+
+func (c *testClient) get(path string) (int, string, string) {
+	c.t.Helper()
+	req, err := http.NewRequest(http.MethodGet, c.server.URL+path, nil)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.do(req)
+}
+
+// post - submit a form, with the CSRF token the last page had
+func (c *testClient) post(path string, form url.Values) (int, string, string) {
+	c.t.Helper()
+	form.Set("csrf_token", c.csrf)
+	req, err := http.NewRequest(http.MethodPost, c.server.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req)
+}
+
+// getJSON - a JSON answer from the server, decoded into v
+func (c *testClient) getJSON(path string, v interface{}) {
+	c.t.Helper()
+	code, _, body := c.get(path)
+	if code != http.StatusOK {
+		c.t.Fatalf("GET %s = %d: %s", path, code, body)
+	}
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		c.t.Fatalf("GET %s: %v in %s", path, err, body)
+	}
+}
+
+// postJSON - post a form that is answered with JSON
+func (c *testClient) postJSON(path string, form url.Values, v interface{}) {
+	c.t.Helper()
+	code, _, body := c.post(path, form)
+	if code != http.StatusOK {
+		c.t.Fatalf("POST %s = %d: %s", path, code, body)
+	}
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		c.t.Fatalf("POST %s: %v in %s", path, err, body)
+	}
+}
+
+// expect - a step ended up on the page it should have, showing what it should
+func (c *testClient) expect(step string, code int, path, body, wantPath, wantText string) {
+	c.t.Helper()
+	if code != http.StatusOK || path != wantPath || !strings.Contains(body, wantText) {
+		c.t.Fatalf("%s: %d on %s, want %q on %s", step, code, path, wantText, wantPath)
+	}
+}
+
+// signup - sign up and log in, through the forms a player would use
+func (c *testClient) signup(screenName, password string) {
+	c.t.Helper()
+	c.get("/signup")
+	code, path, body := c.post("/signup", url.Values{
+		"screenName":   {screenName},
+		"password":     {password},
+		"passwordConf": {password},
+	})
+	c.expect("signup "+screenName, code, path, body, "/login", "Your signup was successful")
+	code, path, body = c.post("/login", url.Values{"screenName": {screenName}, "password": {password}})
+	c.expect("login "+screenName, code, path, body, "/board/list", screenName)
+}
+
+// Ships for the boards in these tests, in every other column from row 1 down
+var testLayout = map[string]int{"C": 1, "B": 3, "R": 5, "S": 7, "D": 9}
+
+// createBoard - fill in the board form with testLayout, save it and select it
+func (c *testClient) createBoard(name string) {
+	c.t.Helper()
+	c.get("/board/create")
+	form := url.Values{"boardName": {name}}
+	columns := game.Columns(game.DefaultSize)
+	for _, ship := range game.DefaultFleet {
+		col := columns[testLayout[ship.Abbreviation]-1]
+		for row := 1; row <= ship.Length; row++ {
+			form.Set(fmt.Sprintf("shipXY%d%s", row, col), ship.Abbreviation)
+		}
+	}
+	code, path, body := c.post("/board/create", form)
+	c.expect("create "+name, code, path, body, "/board/list", "Board successfully created!")
+
+	boardID := regexp.MustCompile(`name=boardID value="(\d+)"`).FindStringSubmatch(body)
+	if boardID == nil {
+		c.t.Fatalf("%s isn't in the board list", name)
+	}
+	code, path, body = c.post("/board/select", url.Values{"boardID": {boardID[1]}})
+	c.expect("select "+name, code, path, body, "/board/list", "Board selected!")
+}
+
+// turn - the secret for a player's turn, from the page's polling endpoint ("" when it isn't theirs)
+func (c *testClient) turn(battleID, ownBoardID int) string {
+	c.t.Helper()
+	var status struct {
+		Turn string `json:"turn"`
+	}
+	c.getJSON(fmt.Sprintf("/status/strikes/%d/%d", battleID, ownBoardID), &status)
+	return status.Turn
+}
+
+type testStrike struct {
+	Valid  bool   `json:"valid"`
+	Winner bool   `json:"winner"`
+	Code   string `json:"code"`
+	Shots  []struct {
+		Result string `json:"result"`
+		Ship   string `json:"sunken_ship"`
+	} `json:"shots"`
+}
+
+// strike - fire one shot at a board, the way the battle page does
+func (c *testClient) strike(battleID, boardID int, secret string, s game.Shot) testStrike {
+	c.t.Helper()
+	var result testStrike
+	c.postJSON("/battle/strike", url.Values{
+		"battleID":   {strconv.Itoa(battleID)},
+		"boardID":    {strconv.Itoa(boardID)},
+		"secretTurn": {secret},
+		"coordX":     {strconv.Itoa(s.CoordX)},
+		"coordY":     {s.CoordY},
+	}, &result)
+	return result
+}
+
+func TestBattleThroughTheHandlers(t *testing.T) {
+	app := newTestApplication(t)
+	ts := httptest.NewTLSServer(app.routes())
+	defer ts.Close()
+
+	amy, ben := newTestClient(t, ts), newTestClient(t, ts)
+	amy.signup("amy", "B0mbs4way:(")
+	ben.signup("ben", "P34nutButter76")
+	amy.createBoard("amy's board")
+	ben.createBoard("ben's board")
+
+	// Amy challenges ben
+	players, err := app.players.List(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var benID int
+	for _, p := range players {
+		if p.ScreenName == "ben" {
+			benID = p.ID
+		}
+	}
+	amy.get("/player/list")
+	code, path, body := amy.post("/player/challenge", url.Values{"playerID": {strconv.Itoa(benID)}})
+	amy.expect("challenge", code, path, body, "/player/list", "Challenge created!")
+
+	// Ben hears about it and accepts
+	var inbox struct {
+		Status string `json:"status"`
+		Unread int    `json:"unread"`
+	}
+	ben.getJSON("/status/inbox", &inbox)
+	if inbox.Status != "challenge" || inbox.Unread != 1 {
+		t.Fatalf("ben's inbox = %+v, want a challenge and an unread notification", inbox)
+	}
+	_, _, body = ben.get("/status/battles/list")
+	m := regexp.MustCompile(`name='battleID' value='(\d+)'`).FindStringSubmatch(body)
+	if m == nil {
+		t.Fatal("the challenge isn't in ben's list")
+	}
+	battleID, _ := strconv.Atoi(m[1])
+	code, path, body = ben.post("/battle/accept", url.Values{"battleID": {m[1]}})
+	ben.expect("accept", code, path, body, fmt.Sprintf("/battle/view/%d", battleID), "You have accepted the battle!")
+
+	b, err := app.battles.Get(benID, battleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amysBoard, bensBoard := b.Player1BoardID, b.Player2BoardID
+
+	// The one challenged fires first; amy only ever misses, ben never does
+	var hits, misses []game.Shot
+	columns := game.Columns(game.DefaultSize)
+	for _, ship := range game.DefaultFleet {
+		col := columns[testLayout[ship.Abbreviation]-1]
+		for row := 1; row <= ship.Length; row++ {
+			hits = append(hits, game.Shot{CoordX: row, CoordY: col})
+		}
+	}
+	for _, col := range columns {
+		for row := 6; row <= game.DefaultSize; row++ {
+			misses = append(misses, game.Shot{CoordX: row, CoordY: col})
+		}
+	}
+	amy.get(fmt.Sprintf("/battle/view/%d", battleID))
+	ben.get(fmt.Sprintf("/battle/view/%d", battleID))
+
+	if secret := amy.turn(battleID, amysBoard); secret != "" {
+		t.Fatal("amy has the first turn")
+	}
+	if r := amy.strike(battleID, bensBoard, "guess", misses[0]); r.Valid || r.Code != strikeNotYourTurn {
+		t.Errorf("amy firing out of turn = %+v, want %s", r, strikeNotYourTurn)
+	}
+
+	var sunk []string
+	var last testStrike
+	for i, s := range hits {
+		secret := ben.turn(battleID, bensBoard)
+		if secret == "" {
+			t.Fatalf("it isn't ben's turn for shot %d", i)
+		}
+		last = ben.strike(battleID, amysBoard, secret, s)
+		if !last.Valid || len(last.Shots) != 1 || last.Shots[0].Result == "miss" {
+			t.Fatalf("ben's shot %d at %s = %+v, want a hit", i, s, last)
+		}
+		if ship := last.Shots[0].Ship; ship != "" {
+			sunk = append(sunk, ship)
+		}
+		if last.Winner {
+			if i != len(hits)-1 {
+				t.Fatalf("ben won with %d squares left to hit", len(hits)-1-i)
+			}
+			break
+		}
+		if r := ben.strike(battleID, amysBoard, secret, hits[i+1]); r.Code != strikeNotYourTurn {
+			t.Fatalf("ben firing twice = %+v, want %s", r, strikeNotYourTurn)
+		}
+		r := amy.strike(battleID, bensBoard, amy.turn(battleID, amysBoard), misses[i])
+		if !r.Valid || r.Shots[0].Result != "miss" {
+			t.Fatalf("amy's shot %d at %s = %+v, want a miss", i, misses[i], r)
+		}
+	}
+	if !last.Winner || len(sunk) != len(game.DefaultFleet) {
+		t.Fatalf("ben sank %v and won = %v; want the whole fleet and the battle", sunk, last.Winner)
+	}
+
+	// The battle is over for both of them
+	if secret := amy.turn(battleID, amysBoard); secret != "" {
+		t.Error("amy has a turn in a finished battle")
+	}
+	if b, err = app.battles.Get(benID, battleID); err != nil || b.Winner != benID || b.Status != models.StatusFinished {
+		t.Errorf("after the last ship went down: %+v, %v", b, err)
+	}
+	code, path, body = amy.get(fmt.Sprintf("/battle/replay/%d", battleID))
+	amy.expect("replay", code, path, body, fmt.Sprintf("/battle/replay/%d", battleID), "ben")
+
+	// Amy was told about the result
+	notices, err := app.notifications.List(b.Player1ID, 10)
+	if err != nil || len(notices) == 0 || notices[0].Kind != models.NoticeGameOver {
+		t.Errorf("amy's notifications = %+v, %v; want the result first", notices, err)
+	}
+}
+
+func TestSignupProblems(t *testing.T) {
+	app := newTestApplication(t)
+	ts := httptest.NewTLSServer(app.routes())
+	defer ts.Close()
+	c := newTestClient(t, ts)
+
+	tests := []struct {
+		name              string
+		screenName        string
+		password, confirm string
+		want              string
+	}{
+		{"blank", "", "", "", "This field cannot be blank"},
+		{"short", "amy", "Sh0rt!", "Sh0rt!", "This field is too short"},
+		{"weak", "amy", "lowercaseonly", "lowercaseonly", "Your password is too weak"},
+		{"mismatch", "amy", "B0mbs4way:(", "B0mbs4way:)", "do not match"},
+		{"space", "amy lee", "B0mbs4way:(", "B0mbs4way:(", "cannot contain whitespace"},
+	}
+	for _, tt := range tests {
+		code, path, body := c.post("/signup", url.Values{
+			"screenName":   {tt.screenName},
+			"password":     {tt.password},
+			"passwordConf": {tt.confirm},
+		})
+		if code != http.StatusOK || path != "/signup" || !strings.Contains(body, tt.want) {
+			t.Errorf("%s: %d on %s, want the form again with %q", tt.name, code, path, tt.want)
+		}
+	}
+}
+
+func TestPlacementProblem(t *testing.T) {
+	carrier, _ := game.DefaultFleet.Lookup("carrier")
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&game.PlacementError{Ship: carrier, Size: 8, NoTouching: true},
+			"There's no room for the Carrier on this 8x8 board with a gap around every ship.  Try a bigger board, or let the ships touch."},
+		{&game.PlacementError{Ship: carrier, Size: 8},
+			"There's no room for the Carrier on this 8x8 board.  Try a bigger board."},
+		{game.ErrNoRoom, "The fleet couldn't be placed on this board"},
+	}
+	for _, tt := range tests {
+		if got := placementProblem(tt.err); got != tt.want {
+			t.Errorf("placementProblem(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -13,8 +13,10 @@ import (
 	"bytes"
 	"crypto/rand"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"html/template"
+	"log"
 	"net/http"
 	"os"
 	"runtime/debug"
@@ -22,16 +24,18 @@ import (
 	"strings"
 	"strconv"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/519seven/cs610/battleship/pkg/models/sqlite3"
 	"github.com/justinas/nosurf"							 // csrf prevention
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/migrate"
 	"github.com/519seven/cs610/battleship/pkg/models"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // ----------------------------------------------------------------------------
 // DATABASE
 
-// Initialize the database - create tables, pupulate ship types
+// Open the SQLite database
+// - The tables come from sqlite3.Migrations (see migrateDB in main.go)
 func initializeDB(dsn string, initdb bool) (*sql.DB, error) {
 	// Do we need to remove the existing file before we begin?
 	if initdb == true {
@@ -41,52 +45,10 @@ func initializeDB(dsn string, initdb bool) (*sql.DB, error) {
 		}
 	}
 	// in this sense, we are initializing the connection to the database
-	db, err := sql.Open("sqlite3", dsn)
+	db, err := sqlite3.Open(dsn)
 	if err != nil {
 		return nil, err
 	}
-	// for sqlite3 this doesn't matter, it only allows one connection at a time
-	db.SetMaxOpenConns(3)
-	db.SetMaxIdleConns(2) // 2 is default
-
-	if err = db.Ping(); err != nil {
-		return nil, err
-	}
-	// create the tables if they don't exist
-	// in sqlite3, a unique, auto-incrementing rowid is automatically created
-	stmt, _ := db.Prepare(`CREATE TABLE IF NOT EXISTS Battles 
-		(player1ID INTEGER, player1Accepted BOOLEAN, player1BoardID INTEGER,
-		 player2ID INTEGER, player2Accepted BOOLEAN, player2BoardID INTEGER, 
-		 player1SunkenShips INTEGER DEFAULT 0, player2SunkenShips INTEGER DEFAULT 0,
-		 challengeDate DATETIME DEFAULT CURRENT_TIMESTAMP,
-		 turn INTEGER, secretTurn STRING, winner INTEGER DEFAULT 0)`)
-	stmt.Exec()
-	stmt, _ = db.Prepare(`CREATE TABLE IF NOT EXISTS Boards 
-		(boardName TEXT, playerID INTEGER, 
-		 created DATETIME DEFAULT CURRENT_TIMESTAMP, isChosen BOOLEAN)`)
-	stmt.Exec()
-	stmt, _ = db.Prepare(`CREATE TABLE IF NOT EXISTS Players 
-		(screenName TEXT NOT NULL UNIQUE, emailAddress TEXT, 
-		 hashedPassword TEXT, created DATETIME, loggedIn BOOLEAN, 
-		 inBattle BOOLEAN, lastLogin DATETIME)`)
-	stmt.Exec()
-	stmt, _ = db.Prepare(`CREATE TABLE IF NOT EXISTS Positions 
-		(boardID INTEGER, shipID INTEGER, playerID INTEGER, 
-		 coordX INTEGER, coordY TEXT, pinColor TEXT)`)
-	stmt.Exec()
-	stmt, _ = db.Prepare(`CREATE TABLE IF NOT EXISTS Ships 
-		(shipType TEXT, shipLength INTEGER)`)
-	stmt.Exec()
-	stmt, _ = db.Prepare(`INSERT INTO Ships (shipType, shipLength) VALUES 
-		('carrier', 5), ('battleship', 4), ('cruiser', 3), ('submarine', 3), ('destroyer', 2)`)
-	stmt.Exec()
-	// sample accounts
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("B0mbs4way:("), 13)
-	elvisPassword, err := bcrypt.GenerateFromPassword([]byte("P34nutButter76"), 13)
-	stmt, _ = db.Prepare(`INSERT INTO Players (screenName, emailAddress, hashedPassword) VALUES
-		('bob', 'bob@bob.com', ?), ('sue', 'sue@sue.com', ?), 
-		('elvis', 'elvis@graceland.com', ?), ('maria', 'maria@presley.com', ?)`)
-	stmt.Exec(hashedPassword, hashedPassword, elvisPassword, hashedPassword)
 	// I created a board for Bob in battleship.db.sample
 	// Running `make` will copy that db into place
 	fmt.Println("Using sample database; If behavior is unpredictable, ")
@@ -96,6 +58,44 @@ func initializeDB(dsn string, initdb bool) (*sql.DB, error) {
 	return db, nil
 }
 
+// Move the schema to a version
+// - "latest" applies whatever is pending; it never rolls a newer database back
+// - A number moves up or down to exactly that version
+// - "status" only lists which migrations have been applied
+func migrateDB(db *sql.DB, migrations []migrate.Migration, target string, infoLog *log.Logger) error {
+	current, err := migrate.Version(db, migrations)
+	if err != nil {
+		return err
+	}
+	latest := migrate.Latest(migrations)
+	version := latest
+	switch target {
+	case "status":
+		for _, m := range migrations {
+			state := "pending"
+			if m.Version <= current {
+				state = "applied"
+			}
+			infoLog.Printf("schema %d (%s): %s", m.Version, m.Name, state)
+		}
+		return nil
+	case "latest":
+		if current > latest {
+			return fmt.Errorf("the database schema (version %d) is newer than this build (version %d)", current, latest)
+		}
+	default:
+		version, err = strconv.Atoi(target)
+		if err != nil {
+			return fmt.Errorf("-migrate %q: use latest, status or a version number", target)
+		}
+	}
+	steps, err := migrate.To(db, migrations, version)
+	for _, step := range steps {
+		infoLog.Printf("schema %s", step)
+	}
+	return err
+}
+
 // -----------------------------------------------------------------------------
 // GENERAL HELPERS
 
@@ -126,7 +126,7 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 
 
 // Pre-processing HTML/template data based on data from database
-func (app *application) preprocessBoardFromData(p []*models.Position, battleID int, csrf_token string, permissions string) template.HTML {
+func (app *application) preprocessBoardFromData(p []*models.Position, battleID int, csrf_token string, permissions string, size int) template.HTML {
 	var boardID int = 0
 	var pinColor string = ""
 	var tableID string = ""
@@ -140,14 +140,14 @@ func (app *application) preprocessBoardFromData(p []*models.Position, battleID i
 	}
 
 	gameBoard := fmt.Sprintf("<table id=\"%s\"><th>&nbsp;</th>", tableID)
-	for _, col := range "ABCDEFGHIJ" {
-		gameBoard += fmt.Sprintf("<th>%s</th>", string(col))
+	for _, col := range game.Columns(size) {
+		gameBoard += fmt.Sprintf("<th>%s</th>", col)
 	}
-	for row := 1; row < 11; row++ {
+	for row := 1; row <= size; row++ {
 		gameBoard += "<tr>"
 		gameBoard += fmt.Sprintf("<td>%d</td>", row)
 		//rowStr := strconv.Itoa(row)
-		for _, col := range "ABCDEFGHIJ" {
+		for _, col := range game.Columns(size) {
 			var fieldHTML string; fieldHTML = ""
 			var fieldValue string; fieldValue = "&nbsp;"
 			var inputid string; inputid = ""
@@ -158,10 +158,11 @@ func (app *application) preprocessBoardFromData(p []*models.Position, battleID i
 				// This playerID ought to help us determine whose board these checkboxes belong to
 				if (boardID == 0) { boardID = onePosition.ID; }
 				//if (pinColor == "" || pinColor == "0") { pinColor = onePosition.PinColor; }
-				if onePosition.CoordX == row && onePosition.CoordY == string(col) {
+				if onePosition.CoordX == row && onePosition.CoordY == col {
 					if onePosition.ShipType.Valid {
-						if strings.ToUpper(onePosition.ShipType.String) == "CRUISER" {
-							fieldValue = "R"	// Cruiser is represented with an "R"
+						// Show the letter the player used to place this ship
+						if ship, ok := app.fleet.Lookup(onePosition.ShipType.String); ok {
+							fieldValue = ship.Abbreviation
 						} else {
 							fieldValue = strings.ToUpper(onePosition.ShipType.String[0:1])
 						}
@@ -182,7 +183,7 @@ func (app *application) preprocessBoardFromData(p []*models.Position, battleID i
 					break
 				}
 			}
-			fieldName := fmt.Sprintf("%d_shipXY%d%s", boardID, row, string(col))
+			fieldName := fmt.Sprintf("%d_shipXY%d%s", boardID, row, col)
 			//gameBoard += fmt.Sprintf("<td id=\"%d_%s\">", playerID, fieldName)
 			gameBoard += fmt.Sprintf("<td id=\"%s_%s\" style=\"background-color:%s\">", tableID, fieldName, pinColor)
 			if permissions == "ro" {
@@ -208,18 +209,18 @@ func (app *application) preprocessBoardFromData(p []*models.Position, battleID i
 
 
 // Pre-processing HTML/template data based on data found in the form request
-func (app *application) preprocessBoardFromRequest(r *http.Request) template.HTML {
+func (app *application) preprocessBoardFromRequest(r *http.Request, size int) template.HTML {
 	gameBoard := "<table><th>&nbsp;</th>"
-	for _, col := range "ABCDEFGHIJ" {
-		gameBoard += fmt.Sprintf("<th>%s</th>", string(col))
+	for _, col := range game.Columns(size) {
+		gameBoard += fmt.Sprintf("<th>%s</th>", col)
 	}
-	for row := 1; row < 11; row++ {
+	for row := 1; row <= size; row++ {
 		gameBoard += fmt.Sprintf("<tr><td>%d</td>", row)
 		rowStr := strconv.Itoa(row)
-		for _, col := range "ABCDEFGHIJ" {
+		for _, col := range game.Columns(size) {
 			gameBoard += fmt.Sprintf(
 				"<td><input type='text'	maxlength=1 size=6 name=\"shipXY%d%s\" value=\"%s\"></td>", 
-				row, string(col), r.PostForm.Get("shipXY"+rowStr+string(col)))
+				row, col, template.HTMLEscapeString(r.PostForm.Get("shipXY"+rowStr+col)))
 		}
 		gameBoard += "</tr>"
 	}
@@ -237,6 +238,7 @@ func (app *application) addDefaultDataLogin(td *templateDataLogin, r *http.Reque
 	td.CurrentYear = time.Now().Year()
 	td.Flash = app.session.PopString(r, "flash")
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	td.ScreenName = app.session.GetString(r, "screenName")
 	return td
 }
@@ -253,6 +255,7 @@ func (app *application) addDefaultDataAbout(td *templateDataAbout, r *http.Reque
 	td.CSRFToken = nosurf.Token(r)
 	td.CurrentYear = time.Now().Year()
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	return td
 }
 
@@ -306,26 +309,31 @@ func (app *application) addDefaultDataBattle(td *templateDataBattle, r *http.Req
 	td.CurrentYear = time.Now().Year()
 	td.Flash = app.session.PopString(r, "flash")
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	td.ScreenName = app.session.GetString(r, "screenName")
+	size := game.DefaultSize
+	if td.Battle != nil {
+		size = td.Battle.BoardSize
+	}
 	if td.ChallengerPositions != nil {
 		//fmt.Println("Positions is not nil.  We should build MainGrid here...", td.AuthenticatedPlayerID, td.ChallengerID)
 		if td.AuthenticatedPlayerID == td.ChallengerID {
-			td.ChallengerGrid = app.preprocessBoardFromData(td.ChallengerPositions, td.Battle.ID, td.CSRFToken, "ro")
+			td.ChallengerGrid = app.preprocessBoardFromData(td.ChallengerPositions, td.Battle.ID, td.CSRFToken, "ro", size)
 		} else {
-			td.ChallengerGrid = app.preprocessBoardFromData(td.OpponentPositions, td.Battle.ID, td.CSRFToken, "ro")
+			td.ChallengerGrid = app.preprocessBoardFromData(td.OpponentPositions, td.Battle.ID, td.CSRFToken, "ro", size)
 		}
 	} else {
-		td.ChallengerGrid = app.preprocessBoardFromRequest(r)
+		td.ChallengerGrid = app.preprocessBoardFromRequest(r, size)
 	}
 	if td.OpponentPositions != nil {
 		//fmt.Println("Positions is not nil.  We should build MainGrid here...", td.AuthenticatedPlayerID, td.ChallengerID)
 		if td.AuthenticatedPlayerID == td.ChallengerID {
-			td.OpponentGrid = app.preprocessBoardFromData(td.OpponentPositions, td.Battle.ID, td.CSRFToken, "hidden")
+			td.OpponentGrid = app.preprocessBoardFromData(td.OpponentPositions, td.Battle.ID, td.CSRFToken, "hidden", size)
 		} else {
-			td.OpponentGrid = app.preprocessBoardFromData(td.ChallengerPositions, td.Battle.ID, td.CSRFToken, "hidden")
+			td.OpponentGrid = app.preprocessBoardFromData(td.ChallengerPositions, td.Battle.ID, td.CSRFToken, "hidden", size)
 		}
 	} else {
-		td.OpponentGrid = app.preprocessBoardFromRequest(r)
+		td.OpponentGrid = app.preprocessBoardFromRequest(r, size)
 	}
 	return td
 }
@@ -368,6 +376,7 @@ func (app *application) addDefaultDataBattles(td *templateDataBattles, r *http.R
 	td.CurrentYear = time.Now().Year()
 	td.Flash = app.session.PopString(r, "flash")
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	td.ScreenName = app.session.GetString(r, "screenName")
 	return td
 }
@@ -401,12 +410,22 @@ func (app *application) addDefaultDataBoard(td *templateDataBoard, r *http.Reque
 	if td == nil {
 		td = &templateDataBoard{}
 	}
+	// A saved board knows its size; a new one gets it from the form
+	size := game.DefaultSize
+	if td.Board != nil {
+		size = td.Board.BoardSize
+	} else if td.Form != nil {
+		if n, err := strconv.Atoi(td.Form.Get("boardSize")); err == nil && game.ValidSize(n) {
+			size = n
+		}
+	}
 	if td.Positions != nil {
 		//fmt.Println("Positions is not nil.  We should build MainGrid here...")
-		td.MainGrid = app.preprocessBoardFromData(td.Positions, 0, "", "ro")
+		td.MainGrid = app.preprocessBoardFromData(td.Positions, 0, "", "ro", size)
 	} else {
-		td.MainGrid = app.preprocessBoardFromRequest(r)
+		td.MainGrid = app.preprocessBoardFromRequest(r, size)
 	}
+	td.BoardSizes = boardSizes()
 	// Default the boardID to 0
 	activeBoardID := app.session.GetInt(r, "boardID")
 	if activeBoardID > 0 {
@@ -418,7 +437,9 @@ func (app *application) addDefaultDataBoard(td *templateDataBoard, r *http.Reque
 	td.CSRFToken = nosurf.Token(r)
 	td.CurrentYear = time.Now().Year()
 	td.Flash = app.session.PopString(r, "flash")
+	td.Fleet = app.fleet
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	td.ScreenName = app.session.GetString(r, "screenName")
 	return td
 }
@@ -462,6 +483,7 @@ func (app *application) addDefaultDataBoards(td *templateDataBoards, r *http.Req
 	td.CurrentYear = time.Now().Year()
 	td.Flash = app.session.PopString(r, "flash")
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	td.ScreenName = app.session.GetString(r, "screenName")
 	return td
 }
@@ -522,6 +544,73 @@ func (app *application) renderJson(w http.ResponseWriter, r *http.Request, out [
 }
 
 
+// ----------------------------------------------------------------------------
+// API
+// - Every /api/v1 response is an envelope: {"data": ...} or {"error": {...}}
+// - The CSRF token rides along in a header so cookie-based clients can POST
+
+// apiEnvelope - the one shape every API response takes
+type apiEnvelope struct {
+	Data			interface{}			`json:"data,omitempty"`
+	Error			*apiErrorBody		`json:"error,omitempty"`
+}
+
+// apiErrorBody - what went wrong
+// - Fields holds validation errors keyed by field name (same as forms.Errors)
+type apiErrorBody struct {
+	Status			int					`json:"status"`
+	Message			string				`json:"message"`
+	Fields			map[string][]string	`json:"fields,omitempty"`
+}
+
+// Send data to an API client
+func (app *application) apiRespond(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	app.apiWrite(w, r, status, apiEnvelope{Data: data})
+}
+
+// Send an error to an API client
+// - A message of "" uses the standard text for the status code
+func (app *application) apiError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	app.apiWrite(w, r, status, apiEnvelope{Error: &apiErrorBody{Status: status, Message: message}})
+}
+
+// Send validation errors to an API client (422)
+func (app *application) apiValidationError(w http.ResponseWriter, r *http.Request, fields map[string][]string) {
+	status := http.StatusUnprocessableEntity
+	app.apiWrite(w, r, status, apiEnvelope{Error: &apiErrorBody{Status: status, Message: "Validation failed", Fields: fields}})
+}
+
+// The API version of serverError - log the details, tell the client nothing
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
+	app.errorLog.Output(2, trace)
+	app.apiError(w, r, http.StatusInternalServerError, "")
+}
+
+func (app *application) apiWrite(w http.ResponseWriter, r *http.Request, status int, env apiEnvelope) {
+	out, err := json.Marshal(env)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-CSRF-Token", nosurf.Token(r))
+	w.WriteHeader(status)
+	w.Write(out)
+}
+
+// Read a JSON request body into dst
+// - Unknown fields are an error so typos don't silently do nothing
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	return dec.Decode(dst)
+}
+
+
 // ----------------------------------------------------------------------------
 // LOGIN
 
@@ -555,6 +644,41 @@ func (app *application) renderLogin(w http.ResponseWriter, r *http.Request, name
 }
 
 
+// ----------------------------------------------------------------------------
+// NOTIFICATIONS
+
+// Add default data to the inbox
+func (app *application) addDefaultDataNotifications(td *templateDataNotifications, r *http.Request) *templateDataNotifications {
+	if td == nil {
+		td = &templateDataNotifications{}
+	}
+	td.AuthenticatedPlayerID = app.session.GetInt(r, "authenticatedPlayerID")
+	td.CSRFToken = nosurf.Token(r)
+	td.CurrentYear = time.Now().Year()
+	td.Flash = app.session.PopString(r, "flash")
+	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
+	td.ScreenName = app.session.GetString(r, "screenName")
+	return td
+}
+
+func (app *application) renderNotifications(w http.ResponseWriter, r *http.Request, name string, td *templateDataNotifications) {
+	ts, ok := app.templateCache[name]
+	if !ok {
+		app.serverError(w, fmt.Errorf("The template %s does not exist", name))
+		return
+	}
+	// write to buffer first to catch errors that may occur
+	buf := new(bytes.Buffer)
+	err := ts.Execute(buf, app.addDefaultDataNotifications(td, r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	buf.WriteTo(w)
+}
+
+
 // ----------------------------------------------------------------------------
 // PLAYER
 
@@ -567,6 +691,7 @@ func (app *application) addDefaultDataPlayer(td *templateDataPlayer, r *http.Req
 	td.CurrentYear = time.Now().Year()
 	td.Flash = app.session.PopString(r, "flash")
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	td.ScreenName = app.session.GetString(r, "screenName")
 	return td
 }
@@ -587,11 +712,7 @@ func (app *application) renderPlayer(w http.ResponseWriter, r *http.Request, nam
 		td.ActiveBoardID = 0
 	}
 	td.AuthenticatedPlayerID = app.session.GetInt(r, "authenticatedPlayerID")
-	td.CSRFToken = nosurf.Token(r)
-	td.CurrentYear = time.Now().Year()
-	td.Flash = app.session.PopString(r, "flash")
-	td.IsAuthenticated = app.isAuthenticated(r)
-	td.ScreenName = app.session.GetString(r, "screenName")
+	// the rest (including the flash, which can only be popped once) comes from addDefaultData*
 	// write to buffer first to catch errors that may occur
 	buf := new(bytes.Buffer)
 	// execute template set, passing the dynamic data with the copyright year
@@ -612,7 +733,9 @@ func (app *application) addDefaultDataPlayers(td *templateDataPlayers, r *http.R
 	td.CSRFToken = nosurf.Token(r)
 	td.CurrentYear = time.Now().Year()
 	td.Flash = app.session.PopString(r, "flash")
+	td.Fleet = app.fleet
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	td.ScreenName = app.session.GetString(r, "screenName")
 	return td
 }
@@ -633,11 +756,7 @@ func (app *application) renderPlayers(w http.ResponseWriter, r *http.Request, na
 		td.ActiveBoardID = 0
 	}
 	td.AuthenticatedPlayerID = app.session.GetInt(r, "authenticatedPlayerID")
-	td.CSRFToken = nosurf.Token(r)
-	td.CurrentYear = time.Now().Year()
-	td.Flash = app.session.PopString(r, "flash")
-	td.IsAuthenticated = app.isAuthenticated(r)
-	td.ScreenName = app.session.GetString(r, "screenName")
+	// the rest (including the flash, which can only be popped once) comes from addDefaultData*
 	// write to buffer first to catch errors that may occur
 	buf := new(bytes.Buffer)
 	// execute template set, passing the dynamic data with the copyright year
@@ -660,6 +779,7 @@ func (app *application) addDefaultDataSignup(td *templateDataSignup, r *http.Req
 	td.CSRFToken = nosurf.Token(r)
 	td.CurrentYear = time.Now().Year()
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.Unread = app.unread(r)
 	return td
 }
 
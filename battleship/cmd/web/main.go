@@ -2,14 +2,25 @@ package main
 
 import (
 	"crypto/tls"
+	"database/sql"
+	"errors"
 	"flag"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/matchmaking"
+	"github.com/519seven/cs610/battleship/pkg/migrate"
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/models/memory"
+	"github.com/519seven/cs610/battleship/pkg/models/mysql"
+	"github.com/519seven/cs610/battleship/pkg/models/sqldb"
 	"github.com/519seven/cs610/battleship/pkg/models/sqlite3"
+	"github.com/519seven/cs610/battleship/pkg/webhook"
 	"github.com/golangcollege/sessions"
 )
 
@@ -20,14 +31,31 @@ type application struct {
 	errorLog      	*log.Logger
 	infoLog       	*log.Logger
 
-	battles       	*sqlite3.BattleModel
-	boards        	*sqlite3.BoardModel
-	players       	*sqlite3.PlayerModel
-	positions     	*sqlite3.PositionModel
-	ships         	*sqlite3.ShipModel
+	fleet			game.Fleet
+	computerID		int
 
+	battles       	models.BattleRepository
+	boards        	models.BoardRepository
+	messages		models.MessageRepository
+	notifications	models.NotificationRepository
+	players       	models.PlayerRepository
+	positions     	models.PositionRepository
+	ratings			models.RatingRepository
+	ships         	models.ShipRepository
+	strikes			models.StrikeRepository
+	tokens			models.TokenRepository
+	webhooks		models.WebhookRepository
+
+	events			*eventHub
+	limiter			*rateLimiter
+	queue			*matchmaking.Queue
 	session			*sessions.Session
 	templateCache 	map[string]*template.Template
+
+	webhookSender		*webhook.Sender			// players' webhooks: public addresses only
+	serverWebhookSender	*webhook.Sender
+	serverWebhooks		[]string				// -webhook: every battle's events go here too
+	serverWebhookSecret	string
 }
 
 // Context - every http.Request that the handlers process has a context.Context object
@@ -38,6 +66,12 @@ type contextKey string
 
 const contextKeyIsAuthenticated = contextKey("isAuthenticated")
 
+// The API token a request was authenticated with (see authenticateToken)
+const contextKeyAPIToken = contextKey("apiToken")
+
+// The real ResponseWriter, kept for handlers that stream (see keepWriter)
+const contextKeyStreamWriter = contextKey("streamWriter")
+
 // Why models?
 // 1. Database logic isn't tied to our handlers which means that
 //    handler responsibilities are limited to HTTP stuff
@@ -56,9 +90,18 @@ const contextKeyIsAuthenticated = contextKey("isAuthenticated")
 // main
 func main() {
 	port := flag.String("port", ":5033", "HTTPS port on which to listen")
-	dsn := flag.String("dsn", "./battleship.db", "SQLite data source name")
+	driver := flag.String("driver", "sqlite3", "Database driver: sqlite3, mysql or memory")
+	dsn := flag.String("dsn", "./battleship.db", "Data source name (a file for sqlite3, user:password@tcp(host)/dbname for mysql)")
 	initdb := flag.Bool("initialize", false, "Start with a fresh database")
+	migrateTo := flag.String("migrate", "", "Move the database schema to a version (a number, or latest) and exit; status lists the migrations")
 	debug := flag.Bool("debug", false, "Output debugging information to browser")
+	apiRate := flag.Int("api-rate", 60, "API requests per minute allowed for each API token")
+	apiBurst := flag.Int("api-burst", 20, "API requests an API token may make at once")
+	fleetFile := flag.String("fleet", "", "JSON file describing the fleet (default is the classic five ships)")
+	clockCheck := flag.Duration("clock-check", 15*time.Second, "How often to look for players who have run out of time on a turn clock")
+	matchEvery := flag.Duration("match-every", 2*time.Second, "How often to pair up players waiting in the matchmaking queue")
+	serverWebhooks := flag.String("webhook", "", "URLs (comma separated) that get every battle's events as signed JSON")
+	webhookSecret := flag.String("webhook-secret", "", "Secret for signing what is sent to the -webhook URLs")
 	// 32 bytes long secret for encrypting and authenticating the session cookies
 	secret := flag.String("secret", "nquR81XagSrAEHYXJSFw8y2PLbyWlF1Z", "Secret key")
 	flag.Parse()
@@ -66,11 +109,117 @@ func main() {
 	infoLog := log.New(os.Stdout,  "INFO   ", log.Ldate|log.Ltime)
 	errorLog := log.New(os.Stderr, "ERROR  ", log.Ldate|log.Ltime|log.Lshortfile)
 
-	db, err := initializeDB(*dsn, *initdb)
+	// Which ships are we playing with?
+	// - Every rule that cares about ships (board creation, sinking, the legend) reads this
+	fleet := game.DefaultFleet
+	var err error
+	if *fleetFile != "" {
+		fleet, err = game.LoadFleetFile(*fleetFile)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+	}
+
+	// New instance of application containing dependencies
+	// - The models come from whichever database -driver picks
+	app := &application{
+		debug:			*debug,
+		errorLog:      	errorLog,
+		infoLog:       	infoLog,
+
+		fleet:			fleet,
+	}
+	var db *sql.DB
+	var migrations []migrate.Migration
+	var dialect sqldb.Dialect
+	switch *driver {
+	case "sqlite3":
+		db, err = initializeDB(*dsn, *initdb)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		migrations, dialect = sqlite3.Migrations, sqlite3.Dialect
+	case "mysql":
+		db, err = mysql.Open(*dsn)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		if *initdb {
+			if _, err = migrate.To(db, mysql.Migrations, 0); err != nil {
+				errorLog.Fatal(err)
+			}
+		}
+		migrations, dialect = mysql.Migrations, mysql.Dialect
+	case "memory":
+		// Nothing is saved; every start is a fresh game with the sample players
+		store := memory.NewStore()
+		app.battles = &memory.BattleModel{Store: store}
+		app.boards = &memory.BoardModel{Store: store}
+		app.messages = &memory.MessageModel{Store: store}
+		app.notifications = &memory.NotificationModel{Store: store}
+		app.players = &memory.PlayerModel{Store: store}
+		app.positions = &memory.PositionModel{Store: store, Fleet: fleet}
+		app.ratings = &memory.RatingModel{Store: store}
+		app.ships = &memory.ShipModel{Store: store}
+		app.strikes = &memory.StrikeModel{Store: store}
+		app.tokens = &memory.TokenModel{Store: store}
+		app.webhooks = &memory.WebhookModel{Store: store}
+		for _, sample := range []struct{ screenName, password string }{
+			{"bob", "B0mbs4way:("}, {"sue", "B0mbs4way:("}, {"elvis", "P34nutButter76"}, {"maria", "B0mbs4way:("},
+		} {
+			if _, err = app.players.Insert(sample.screenName, "", sample.password); err != nil {
+				errorLog.Fatal(err)
+			}
+		}
+	default:
+		errorLog.Fatalf("Unknown -driver %q (use sqlite3, mysql or memory)", *driver)
+	}
+	if db != nil {
+		defer db.Close()
+		// The same SQL on either database (see pkg/models/sqldb)
+		m := sqldb.New(db, dialect, fleet)
+		app.battles = m.Battles
+		app.boards = m.Boards
+		app.messages = m.Messages
+		app.notifications = m.Notifications
+		app.players = m.Players
+		app.positions = m.Positions
+		app.ratings = m.Ratings
+		app.ships = m.Ships
+		app.strikes = m.Strikes
+		app.tokens = m.Tokens
+		app.webhooks = m.Webhooks
+	}
+
+	// Bring the schema up to date, or move it where -migrate says and stop
+	if *migrateTo != "" {
+		if db == nil {
+			errorLog.Fatalf("The %s driver has no schema to migrate", *driver)
+		}
+		if err = migrateDB(db, migrations, *migrateTo, infoLog); err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
+	if db != nil {
+		if err = migrateDB(db, migrations, "latest", infoLog); err != nil {
+			errorLog.Fatal(err)
+		}
+	}
+
+	// Saved boards have to fit the fleet, or their battles couldn't be played
+	if err = app.ships.Sync(fleet); err != nil {
+		if errors.Is(err, models.ErrFleetMismatch) {
+			errorLog.Fatalf("%v (start with the -fleet this database was played with)", err)
+		}
+		errorLog.Fatal(err)
+	}
+
+	// The built-in computer player (see computer.go)
+	app.computerID, err = app.players.EnsureComputer(computerScreenName)
 	if err != nil {
 		errorLog.Fatal(err)
 	}
-	defer db.Close()
 
 	// Initialize new template cache
 	templateCache, err := newTemplateCache("./ui/html/")
@@ -84,22 +233,36 @@ func main() {
 	session := sessions.New([]byte(*secret))
 	session.Lifetime = 12 * time.Hour
 
-	// New instance of application containing dependencies
-	// - Some are sql.<models> instances
-	app := &application{
-		debug:			*debug,
-		errorLog:      	errorLog,
-		infoLog:       	infoLog,
+	app.events = newEventHub()
+	app.limiter = newRateLimiter(*apiRate, *apiBurst)
+	app.queue = matchmaking.NewQueue()
+	app.session = session
+	app.templateCache = templateCache
 
-		battles:       	&sqlite3.BattleModel{DB: db},
-		boards:        	&sqlite3.BoardModel{DB: db},
-		players:       	&sqlite3.PlayerModel{DB: db},
-		positions:     	&sqlite3.PositionModel{DB: db},
-		ships:         	&sqlite3.ShipModel{DB: db},
-
-		session:		session,
-		templateCache: 	templateCache,
+	// Send battles' events to webhooks (see webhooks.go)
+	app.webhookSender = &webhook.Sender{}
+	app.serverWebhookSender = &webhook.Sender{Client: webhook.Trusted()}
+	for _, url := range strings.Split(*serverWebhooks, ",") {
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+		if !webhook.ValidURL(url) {
+			errorLog.Fatalf("-webhook %q is not an http:// or https:// URL", url)
+		}
+		app.serverWebhooks = append(app.serverWebhooks, url)
+	}
+	if len(app.serverWebhooks) > 0 && *webhookSecret == "" {
+		errorLog.Fatal("-webhook needs a -webhook-secret to sign with")
 	}
+	app.serverWebhookSecret = *webhookSecret
+	app.events.forward = app.forwardEvent
+
+	// Expire and forfeit battles whose turn clock has run out (see clock.go)
+	go app.watchClocks(*clockCheck)
+	// Pair up players waiting for a battle (see matchmaker.go)
+	go app.watchQueue(*matchEvery)
+	// Take the turns the computer was thinking about when the server last stopped (see computer.go)
+	go app.resumeComputer()
 
 	// Struct to hold non-default TLS settings
 	tlsConfig := &tls.Config {
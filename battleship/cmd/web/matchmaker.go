@@ -0,0 +1,91 @@
+package main
+
+// Matchmaking
+// - Instead of picking someone off the list of players, a player can join the
+//   queue with a board and the rules they want (see pkg/matchmaking)
+// - A goroutine pairs up waiting players, creates and accepts the battle for
+//   them, and tells both
+// - The queue lives in memory; a restart empties it
+
+import (
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/matchmaking"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// enqueue - put a player in the matchmaking queue with one of their saved boards
+// - ErrNoRecord if the board isn't theirs, or is a battle's copy
+func (app *application) enqueue(playerID, boardID int, rules game.Rules) (matchmaking.Ticket, error) {
+	board, err := app.boards.GetInfo(playerID, boardID)
+	if err != nil {
+		return matchmaking.Ticket{}, err
+	}
+	if board.BattleID.Valid {
+		return matchmaking.Ticket{}, models.ErrNoRecord
+	}
+	p, err := app.players.Get(playerID)
+	if err != nil {
+		return matchmaking.Ticket{}, err
+	}
+	return app.queue.Join(matchmaking.Ticket{
+		PlayerID:   playerID,
+		ScreenName: p.ScreenName,
+		BoardID:    boardID,
+		BoardSize:  board.BoardSize,
+		Rating:     p.Rating,
+		Rules:      rules,
+		Joined:     time.Now(),
+	}), nil
+}
+
+// watchQueue - pair up waiting players every interval, for as long as the server runs
+func (app *application) watchQueue(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		app.pairPlayers()
+	}
+}
+
+// pairPlayers - start a battle for every match the queue can make right now
+func (app *application) pairPlayers() {
+	for _, m := range app.queue.Pair(time.Now()) {
+		battleID, err := app.startMatch(m)
+		if err != nil {
+			// Neither of them asked to leave; they keep their place for the next try
+			app.errorLog.Printf("Unable to start a battle for %s and %s: %v", m.Challenger.ScreenName, m.Opponent.ScreenName, err)
+			app.queue.Join(m.Challenger)
+			app.queue.Join(m.Opponent)
+			continue
+		}
+		app.infoLog.Printf("Matched %s (%d) and %s (%d) in battle %d", m.Challenger.ScreenName, m.Challenger.Rating,
+			m.Opponent.ScreenName, m.Opponent.Rating, battleID)
+		app.events.publish(eventMatch, battleID, map[string]string{"opponent": m.Opponent.ScreenName}, m.Challenger.PlayerID)
+		app.events.publish(eventMatch, battleID, map[string]string{"opponent": m.Challenger.ScreenName}, m.Opponent.PlayerID)
+		app.notify(m.Challenger.PlayerID, battleID, models.NoticeAccept, "You have been matched with %s", m.Opponent.ScreenName)
+		app.notify(m.Opponent.PlayerID, battleID, models.NoticeAccept, "You have been matched with %s; you fire first", m.Challenger.ScreenName)
+	}
+}
+
+// startMatch - challenge and accept on both players' behalf
+// - If the opponent's side fails, the challenge is withdrawn rather than left
+//   waiting for an answer nobody was asked for
+func (app *application) startMatch(m matchmaking.Match) (int, error) {
+	secretTurn, err := app.GenerateRandomString(32)
+	if err != nil {
+		return 0, err
+	}
+	battleID, err := app.battles.Create(m.Challenger.PlayerID, m.Challenger.BoardID, m.Opponent.PlayerID, secretTurn, m.Challenger.Rules)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = app.battles.Accept(m.Opponent.PlayerID, m.Opponent.BoardID, battleID); err != nil {
+		if werr := app.battles.Withdraw(m.Challenger.PlayerID, battleID); werr != nil {
+			app.errorLog.Printf("Unable to withdraw battle %d: %v", battleID, werr)
+		}
+		return 0, err
+	}
+	return battleID, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// flakyBattles - a battle repository that fails whichever step it's told to
+type flakyBattles struct {
+	models.BattleRepository
+	failCreate, failAccept bool
+	created                int
+}
+
+var errFlaky = errors.New("the database went away")
+
+func (f *flakyBattles) Create(player1ID, player1BoardID, player2ID int, secretTurn string, rules game.Rules) (int, error) {
+	if f.failCreate {
+		return 0, errFlaky
+	}
+	id, err := f.BattleRepository.Create(player1ID, player1BoardID, player2ID, secretTurn, rules)
+	f.created = id
+	return id, err
+}
+
+func (f *flakyBattles) Accept(player2ID, boardID, battleID int) (int, error) {
+	if f.failAccept {
+		return 0, errFlaky
+	}
+	return f.BattleRepository.Accept(player2ID, boardID, battleID)
+}
+
+func TestPairPlayers(t *testing.T) {
+	tests := []struct {
+		name                   string
+		failCreate, failAccept bool
+		status                 models.BattleStatus
+	}{
+		{"create fails", true, false, ""},
+		{"accept fails", false, true, models.StatusCancelled},
+		{"both work", false, false, models.StatusSetup},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			battles := &flakyBattles{BattleRepository: app.battles, failCreate: tt.failCreate, failAccept: tt.failAccept}
+			app.battles = battles
+			app.errorLog.SetOutput(ioutil.Discard)
+
+			joined := time.Now().Add(-time.Minute)
+			var tickets []int
+			for _, screenName := range []string{"amy", "ben"} {
+				playerID, err := app.players.Insert(screenName, "", "Tr1cky-Password")
+				if err != nil {
+					t.Fatal(err)
+				}
+				boardID, _ := app.boards.Create(playerID, screenName+"'s", game.DefaultSize)
+				ticket, err := app.enqueue(playerID, boardID, game.Rules{Mode: game.Classic})
+				if err != nil {
+					t.Fatal(err)
+				}
+				// as if they'd been waiting a while
+				app.queue.Leave(playerID)
+				ticket.Joined = joined
+				app.queue.Join(ticket)
+				tickets = append(tickets, playerID)
+			}
+
+			app.pairPlayers()
+			for _, playerID := range tickets {
+				ticket, waiting := app.queue.Waiting(playerID)
+				matched := tt.status == models.StatusSetup
+				if waiting == matched {
+					t.Errorf("player %d waiting = %v after %s", playerID, waiting, tt.name)
+				}
+				if waiting && !ticket.Joined.Equal(joined) {
+					t.Errorf("player %d lost their place: joined %v, want %v", playerID, ticket.Joined, joined)
+				}
+			}
+			if battles.created == 0 {
+				return
+			}
+			b, err := app.battles.Get(tickets[0], battles.created)
+			if err != nil || b.Status != tt.status {
+				t.Errorf("battle %d is %v (%v), want %s", battles.created, b, err, tt.status)
+			}
+		})
+	}
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
-	//"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/models"
 	"github.com/justinas/nosurf"	// csrf protection
 
 )
@@ -27,6 +31,16 @@ func (app *application) logRequest(next http.Handler) http.Handler {
 	})
 }
 
+// keepWriter - the session middleware buffers the whole response so that it
+//              can set its cookie first; an event stream has to reach the
+//              browser as it happens, so hang on to the real ResponseWriter
+func keepWriter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextKeyStreamWriter, w)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // noSurf - prevent CSRF by adding a token to a hidden field in each form
 //          and check that the token and cookie info match
 func noSurf(next http.Handler) http.Handler {
@@ -40,6 +54,27 @@ func noSurf(next http.Handler) http.Handler {
 	return csrfHandler
 }
 
+// noSurfAPI - same CSRF protection for cookie-authenticated API calls
+//             (send the X-CSRF-Token header from any earlier API response),
+//             but a failure is answered with a JSON error envelope
+func (app *application) noSurfAPI(next http.Handler) http.Handler {
+	csrfHandler := nosurf.New(next)
+	csrfHandler.SetBaseCookie(http.Cookie{
+		HttpOnly: 	true,
+		Path: 		"/",
+		Secure:		true,
+	})
+	csrfHandler.SetFailureHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.apiError(w, r, http.StatusBadRequest, "Missing or invalid CSRF token")
+	}))
+	// A browser never sends an Authorization header across sites on its own, and
+	// authenticateToken refuses any request whose header isn't a valid token
+	csrfHandler.ExemptFunc(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") != ""
+	})
+	return csrfHandler
+}
+
 // recoverPanic - unwind the stack when an error occurs
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -60,8 +95,69 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateToken - "Authorization: Bearer <token>" for non-browser clients
+// - Sits in front of authenticate; a request with a token never falls back
+//   to the session cookie, and a bad token is refused outright
+// - Each token is rate limited
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header || token == "" {
+			app.apiError(w, r, http.StatusUnauthorized, "The Authorization header must be: Bearer <token>")
+			return
+		}
+		t, err := app.tokens.Authenticate(token)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				app.apiError(w, r, http.StatusUnauthorized, "Unknown or revoked API token")
+			} else {
+				app.apiServerError(w, r, err)
+			}
+			return
+		}
+		if ok, wait := app.limiter.allow(t.ID); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			app.apiError(w, r, http.StatusTooManyRequests, "Slow down; too many requests with this API token")
+			return
+		}
+		ctx := context.WithValue(r.Context(), contextKeyIsAuthenticated, true)
+		ctx = context.WithValue(ctx, contextKeyAPIToken, t)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireScope - a token has to carry the scope for this route
+// - Session (browser) logins can do everything
+func (app *application) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t := apiToken(r); t != nil && !t.HasScope(scope) {
+				app.apiError(w, r, http.StatusForbidden, fmt.Sprintf("This API token does not have the %q scope", scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// The API token this request was authenticated with, if any
+func apiToken(r *http.Request) *models.APIToken {
+	t, _ := r.Context().Value(contextKeyAPIToken).(*models.APIToken)
+	return t
+}
+
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// already authenticated by an API token (see authenticateToken)
+		if apiToken(r) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
 		// check if authenticatedPlayerID is present; if not, call the next handler
 		//fmt.Println("checking for authenticatedPlayerID")					// debug
 		exists := app.session.Exists(r, "authenticatedPlayerID")
@@ -116,4 +212,16 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 		w.Header().Add("Cache-Control", "no-store")
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// requireAuthenticationAPI - an API client gets a 401 instead of a redirect
+func (app *application) requireAuthenticationAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.apiError(w, r, http.StatusUnauthorized, "Log in first")
+			return
+		}
+		w.Header().Add("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+// Notifications
+// - Each player has an inbox of what happened in their battles while they may
+//   not have been looking: challenges, accepted challenges, their turn and results
+// - They are saved, so they are still waiting after the player logs in again
+// - The nav bar shows how many are unread; a "notice" event keeps that count
+//   current on every open page
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// How many notifications the inbox shows
+const notificationsShown = 50
+
+// notify - put a notification in a player's inbox and update the count on their pages
+// - The computer player has no inbox
+// - The result of a battle (or a challenge that expired) is all that matters about it
+//   now; the rest is marked read
+// - Whatever it is about has already happened, so a failure here is only logged
+func (app *application) notify(playerID, battleID int, kind, format string, args ...interface{}) {
+	if playerID == 0 || playerID == app.computerID {
+		return
+	}
+	if kind == models.NoticeGameOver || kind == models.NoticeExpired {
+		if err := app.notifications.MarkBattleRead(playerID, battleID); err != nil {
+			app.errorLog.Println("Unable to mark notifications read:", err)
+		}
+	}
+	if _, err := app.notifications.Insert(playerID, battleID, kind, fmt.Sprintf(format, args...)); err != nil {
+		app.errorLog.Println("Unable to save a notification:", err)
+		return
+	}
+	app.publishUnread(playerID)
+}
+
+// publishUnread - tell a player's pages how many notifications they haven't read
+func (app *application) publishUnread(playerID int) {
+	n, err := app.notifications.Unread(playerID)
+	if err != nil {
+		app.errorLog.Println("Unable to count notifications:", err)
+		return
+	}
+	app.events.publish(eventNotice, 0, map[string]int{"unread": n}, playerID)
+}
+
+// unread - the logged in player's unread count for the nav bar (0 if nobody is logged in)
+func (app *application) unread(r *http.Request) int {
+	playerID := app.session.GetInt(r, "authenticatedPlayerID")
+	if playerID == 0 || !app.isAuthenticated(r) {
+		return 0
+	}
+	n, err := app.notifications.Unread(playerID)
+	if err != nil {
+		app.errorLog.Println("Unable to count notifications:", err)
+		return 0
+	}
+	return n
+}
+
+// seenBattle - a player has opened one of their battles; whatever it was about, they've seen it
+func (app *application) seenBattle(playerID, battleID int) {
+	if err := app.notifications.MarkBattleRead(playerID, battleID); err != nil {
+		app.errorLog.Println("Unable to mark notifications read:", err)
+		return
+	}
+	app.publishUnread(playerID)
+}
+
+// opponentName - the screen name of whoever a player is up against in a battle
+func opponentName(b *models.Battle, playerID int) string {
+	if b.Player1ID == playerID {
+		return b.Player2ScreenName
+	}
+	return b.Player1ScreenName
+}
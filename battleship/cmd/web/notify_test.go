@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// A long battle leaves each player one "your turn", not one for every strike
+func TestTurnNoticesDontPileUp(t *testing.T) {
+	app := newTestApplication(t)
+	amy, err := app.players.Insert("amy", "", "Tr1cky-Password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ben, err := app.players.Insert("ben", "", "Tr1cky-Password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amysBoard, _ := app.boards.Create(amy, "amy's", game.DefaultSize)
+	bensBoard, _ := app.boards.Create(ben, "ben's", game.DefaultSize)
+	battleID, err := app.battles.Create(amy, amysBoard, ben, "first", game.Rules{Mode: game.Classic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = app.battles.Accept(ben, bensBoard, battleID); err != nil {
+		t.Fatal(err)
+	}
+
+	turnNotices := func(playerID int) (all, unread int) {
+		notices, err := app.notifications.List(playerID, notificationsShown)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range notices {
+			if n.BattleID == battleID && n.Kind == models.NoticeTurn {
+				all++
+				if !n.Read {
+					unread++
+				}
+			}
+		}
+		return all, unread
+	}
+
+	// ben fires first; firing marks the "your turn" that came before it read
+	for i := 0; i < 10; i++ {
+		striker, waiting := ben, amy
+		if i%2 == 1 {
+			striker, waiting = amy, ben
+		}
+		app.announceStrike(striker, battleID, nil, false)
+		if all, unread := turnNotices(waiting); all != 1 || unread != 1 {
+			t.Fatalf("after strike %d the player whose turn it is has %d turn notices (%d unread), want 1", i+1, all, unread)
+		}
+		if all, unread := turnNotices(striker); all > 1 || unread != 0 {
+			t.Fatalf("after strike %d the player who fired has %d turn notices (%d unread), want at most 1, read", i+1, all, unread)
+		}
+	}
+}
+
+// Marking notifications read changes them, so it takes the "play" scope
+func TestNotificationRoutesNeedPlay(t *testing.T) {
+	app := newTestApplication(t)
+	ts := httptest.NewTLSServer(app.routes())
+	defer ts.Close()
+
+	amy, err := app.players.Insert("amy", "", "Tr1cky-Password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	noticeID, err := app.notifications.Insert(amy, 0, models.NoticeChallenge, "Something happened")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readOnly, err := app.tokens.Insert(amy, "dashboard", []string{models.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := app.tokens.Insert(amy, "bot", []string{models.ScopeRead, models.ScopePlay})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{fmt.Sprintf("/api/v1/notifications/%d/read", noticeID), readOnly, http.StatusForbidden},
+		{"/api/v1/notifications/read", readOnly, http.StatusForbidden},
+		{fmt.Sprintf("/api/v1/notifications/%d/read", noticeID), player, http.StatusNoContent},
+		{"/api/v1/notifications/read", player, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("POST %s with %s = %d, want %d", tt.path, tt.token[:4], resp.StatusCode, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/519seven/cs610/battleship/pkg/models"
 	"github.com/bmizerany/pat"		// router
 	"github.com/justinas/alice"		// middleware
 )
@@ -18,7 +19,16 @@ func (app *application) routes() http.Handler {
 	// b.) csrf protection (noSurf)
 	dynamicMiddleware := alice.New(app.session.Enable, noSurf, app.authenticate)
 
-	strikeMiddleware := alice.New(app.session.Enable, app.authenticate)
+	// The JSON API
+	// - Browser sessions send the CSRF token in the X-CSRF-Token header
+	// - Scripts send "Authorization: Bearer <token>" instead (no CSRF, scoped, rate limited)
+	apiMiddleware := alice.New(app.session.Enable, app.noSurfAPI, app.authenticateToken, app.authenticate)
+	apiAuthMiddleware := apiMiddleware.Append(app.requireAuthenticationAPI)
+	apiReadMiddleware := apiAuthMiddleware.Append(app.requireScope(models.ScopeRead))
+	apiPlayMiddleware := apiAuthMiddleware.Append(app.requireScope(models.ScopePlay))
+
+	// Live updates stream past the session's response buffer
+	eventMiddleware := alice.New(keepWriter, app.session.Enable, app.authenticate, app.requireAuthentication)
 
 	mux := pat.New()
 	// More specific routes at the top, less specific routes follow...
@@ -26,17 +36,26 @@ func (app *application) routes() http.Handler {
 	// Basics - home and about
 	mux.Get("/", dynamicMiddleware.ThenFunc(app.home))
 	mux.Get("/about", dynamicMiddleware.ThenFunc(app.about))
+	// Live updates (Server-Sent Events) - strikes, turns, challenges and winners
+	// - ?watch=<battle ID> for a spectator
+	mux.Get("/events", eventMiddleware.ThenFunc(app.streamEvents))
 	// BATTLES
 	// display list of battles
 	mux.Get("/status/battles/list", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.listBattles))
 	// View challenges you may have (create a challenge under "Players")
 	mux.Get("/status/challenge", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.listBattles))
+	// anything waiting (a challenge, unread notifications)? JSON for browsers without live updates
+	mux.Get("/status/inbox", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.challengeStatus))
 	// "accept" a challenge from another player
 	mux.Post("/status/confirm/:battleID", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.confirmStatus))
 	// get list of strikes to see if anything has changed
 	mux.Get("/status/strikes/:battleID/:boardID", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.getStrikes))
 	// accept a challenge and redirect to /battle/view
 	mux.Post("/battle/accept", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.acceptBattle))
+	// turn a challenge down (opponent), take it back (challenger) or give up a battle (either)
+	mux.Post("/battle/decline", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.declineBattle))
+	mux.Post("/battle/withdraw", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.withdrawBattle))
+	mux.Post("/battle/resign", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.resignBattle))
 	// access a battlefield and continue battling - the battleID will be sent in form post
 	mux.Post("/battle/get/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.getBattle))
 	// view a non-playable version of a battle - the battleID will be sent in form post
@@ -44,18 +63,41 @@ func (app *application) routes() http.Handler {
 	mux.Get("/battle/view/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.viewBattle))
 	// Enter the battle (shows board with selections that the users can click on)
 	mux.Post("/battle/enter/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.enterBattle))
-	mux.Post("/battle/strike", strikeMiddleware.Append(app.requireAuthentication).ThenFunc(app.recordStrike))
+	mux.Post("/battle/strike", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.recordStrike))
+	// step through a finished battle move by move
+	mux.Get("/battle/replay/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.replayBattle))
+	// watch other players' battles (see setSpectators for keeping them out)
+	mux.Get("/spectate", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.listWatchable))
+	mux.Get("/spectate/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.watchBattle))
+	mux.Post("/battle/spectators", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.setSpectators))
 	// BOARDS
 	mux.Post("/board/create", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.createBoard))	// save board info
 	mux.Get("/board/create", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.createBoardForm))	// display board if GET
+	mux.Post("/board/randomize", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.randomizeBoard))	// place the fleet at random
 	mux.Get("/board/list", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.listBoards))
 	mux.Post("/board/select", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.selectBoard))
 	mux.Post("/board/update/:id", dynamicMiddleware.ThenFunc(app.updateBoard))
 	mux.Get("/board/:id", dynamicMiddleware.ThenFunc(app.displayBoard))
+	// MATCHMAKING
+	mux.Get("/matchmaking", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.showQueue))
+	mux.Post("/matchmaking/join", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.joinQueue))
+	mux.Post("/matchmaking/leave", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.leaveQueue))
+	// NOTIFICATIONS
+	mux.Get("/notifications", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.showNotifications))
+	mux.Post("/notifications/read", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.readNotifications))
 	// PLAYERS
 	// Create a challenge; challenge an opponent
 	mux.Post("/player/challenge", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.challengePlayer))
 	mux.Get("/player/list", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.listPlayers))
+	mux.Get("/leaderboard", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.leaderboard))
+	// API tokens (created, listed and revoked on your player page)
+	mux.Post("/player/tokens", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.createToken))
+	mux.Post("/player/tokens/revoke", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.revokeToken))
+	// Webhooks (added and deleted on your player page; each has a page with its delivery log)
+	mux.Post("/player/webhooks", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.createWebhook))
+	mux.Post("/player/webhooks/delete", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.deleteWebhook))
+	mux.Post("/player/webhooks/test", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.sendTestWebhook))
+	mux.Get("/player/webhooks/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.showWebhook))
 	mux.Get("/player/update/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.updatePlayer))
 	mux.Get("/player/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.displayPlayer))
 	// POSITIONS
@@ -69,6 +111,45 @@ func (app *application) routes() http.Handler {
 	mux.Post("/logout", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.postLogout))
 	mux.Post("/updatePlayer", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.updatePlayer))
 
+	// API (v1)
+	mux.Post("/api/v1/login", apiMiddleware.ThenFunc(app.apiLogin))
+	mux.Post("/api/v1/logout", apiAuthMiddleware.ThenFunc(app.apiLogout))
+	mux.Get("/api/v1/players", apiReadMiddleware.ThenFunc(app.apiListPlayers))
+	mux.Get("/api/v1/players/:id", apiReadMiddleware.ThenFunc(app.apiGetPlayer))
+	mux.Get("/api/v1/players/:id/ratings", apiReadMiddleware.ThenFunc(app.apiPlayerRatings))
+	mux.Get("/api/v1/leaderboard", apiReadMiddleware.ThenFunc(app.apiLeaderboard))
+	mux.Get("/api/v1/boards", apiReadMiddleware.ThenFunc(app.apiListBoards))
+	mux.Post("/api/v1/boards", apiPlayMiddleware.ThenFunc(app.apiCreateBoard))
+	mux.Get("/api/v1/boards/random", apiReadMiddleware.ThenFunc(app.apiRandomBoard))
+	mux.Get("/api/v1/boards/:id", apiReadMiddleware.ThenFunc(app.apiGetBoard))
+	mux.Get("/api/v1/challenges", apiReadMiddleware.ThenFunc(app.apiListChallenges))
+	mux.Post("/api/v1/challenges", apiPlayMiddleware.ThenFunc(app.apiCreateChallenge))
+	mux.Post("/api/v1/challenges/:id/accept", apiPlayMiddleware.ThenFunc(app.apiAcceptChallenge))
+	mux.Post("/api/v1/challenges/:id/decline", apiPlayMiddleware.ThenFunc(app.apiDeclineChallenge))
+	mux.Post("/api/v1/challenges/:id/withdraw", apiPlayMiddleware.ThenFunc(app.apiWithdrawChallenge))
+	mux.Get("/api/v1/matchmaking", apiReadMiddleware.ThenFunc(app.apiGetQueue))
+	mux.Post("/api/v1/matchmaking", apiPlayMiddleware.ThenFunc(app.apiJoinQueue))
+	mux.Post("/api/v1/matchmaking/leave", apiPlayMiddleware.ThenFunc(app.apiLeaveQueue))
+	mux.Get("/api/v1/notifications", apiReadMiddleware.ThenFunc(app.apiListNotifications))
+	mux.Post("/api/v1/notifications/read", apiPlayMiddleware.ThenFunc(app.apiReadAllNotifications))
+	mux.Post("/api/v1/notifications/:id/read", apiPlayMiddleware.ThenFunc(app.apiReadNotification))
+	mux.Get("/api/v1/webhooks", apiReadMiddleware.ThenFunc(app.apiListWebhooks))
+	mux.Post("/api/v1/webhooks", apiPlayMiddleware.ThenFunc(app.apiCreateWebhook))
+	mux.Get("/api/v1/webhooks/:id", apiReadMiddleware.ThenFunc(app.apiGetWebhook))
+	mux.Del("/api/v1/webhooks/:id", apiPlayMiddleware.ThenFunc(app.apiDeleteWebhook))
+	mux.Post("/api/v1/webhooks/:id/test", apiPlayMiddleware.ThenFunc(app.apiTestWebhook))
+	mux.Get("/api/v1/battles/watchable", apiReadMiddleware.ThenFunc(app.apiListWatchable))
+	mux.Get("/api/v1/battles/:id", apiReadMiddleware.ThenFunc(app.apiGetBattle))
+	mux.Get("/api/v1/battles/:id/moves", apiReadMiddleware.ThenFunc(app.apiBattleMoves))
+	mux.Get("/api/v1/battles/:id/messages", apiReadMiddleware.ThenFunc(app.apiListMessages))
+	mux.Post("/api/v1/battles/:id/messages", apiPlayMiddleware.ThenFunc(app.apiSendMessage))
+	mux.Post("/api/v1/battles/:id/strikes", apiPlayMiddleware.ThenFunc(app.apiStrike))
+	mux.Post("/api/v1/battles/:id/resign", apiPlayMiddleware.ThenFunc(app.apiResign))
+	mux.Post("/api/v1/battles/:id/spectators", apiPlayMiddleware.ThenFunc(app.apiSetSpectators))
+	mux.Get("/api/", apiMiddleware.ThenFunc(app.apiNotFound))
+	mux.Post("/api/", apiMiddleware.ThenFunc(app.apiNotFound))
+	mux.Del("/api/", apiMiddleware.ThenFunc(app.apiNotFound))
+
 	fileServer := http.FileServer(http.Dir("./ui/static/"))
 	// remove a specific prefix from the request's URL path
 	// before passing the request on to the file server
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"html/template"
 	"path/filepath"
 	"time"
 
 	"github.com/519seven/cs610/battleship/pkg/forms"
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/matchmaking"
 	"github.com/519seven/cs610/battleship/pkg/models"
 )
 
@@ -25,6 +28,8 @@ type templateDataAbout struct {
 	IsAuthenticated			bool
 	// Authenticated user's screen name
 	ScreenName				string
+	// How many notifications they haven't read (shown in the nav bar)
+	Unread					int
 }
 // Battle (the battle containing two boards)
 type templateDataBattle struct {
@@ -36,6 +41,7 @@ type templateDataBattle struct {
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	ChallengerID			int
 	ChallengerBoardID		int
 	OpponentID				int
@@ -47,6 +53,7 @@ type templateDataBattle struct {
 	ChallengerGrid			template.HTML
 	OpponentPositions		[]*models.Position
 	OpponentGrid			template.HTML
+	MaxMessageLength		int
 }
 // Battles (list)
 type templateDataBattles struct {
@@ -58,6 +65,7 @@ type templateDataBattles struct {
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	Battle      			*models.Battle
 	Battles     			[]*models.Battle
 }
@@ -68,10 +76,13 @@ type templateDataBoard struct {
 	CSRFToken				string
 	CurrentYear 			int
 	Flash					string
+	Fleet					game.Fleet
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	Board					*models.Board
+	BoardSizes				[]int
 	Positions		      	[]*models.Position
 	MainGrid				template.HTML
 }
@@ -85,6 +96,7 @@ type templateDataBoards struct {
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	Board					*models.Board
 	Boards					[]*models.Board
 }
@@ -96,8 +108,20 @@ type templateDataLogin struct {
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	Login      				*models.Login
 }
+// Notifications (the inbox)
+type templateDataNotifications struct {
+	AuthenticatedPlayerID	int
+	CSRFToken				string
+	CurrentYear 			int
+	Flash					string
+	IsAuthenticated			bool
+	ScreenName				string
+	Unread					int
+	Notifications			[]*models.Notification
+}
 // Player
 type templateDataPlayer struct {
 	ActiveBoardID			int
@@ -108,8 +132,16 @@ type templateDataPlayer struct {
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	Player      			*models.Player
 	Players     			[]*models.Player
+	Ratings					[]*models.Rating
+	Scopes					[]string
+	Tokens					[]*models.APIToken
+	Webhooks				[]*models.Webhook
+	WebhookEvents			[]string
+	Webhook					*models.Webhook					// the one whose delivery log is showing
+	Deliveries				[]*models.WebhookDelivery
 }
 // Player List
 type templateDataPlayers struct {
@@ -118,11 +150,20 @@ type templateDataPlayers struct {
 	CSRFToken				string
 	CurrentYear 			int
 	Flash					string
+	Fleet					game.Fleet
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	Player      			*models.Player
 	Players     			[]*models.Player
+	Scopes					[]string
+	Tokens					[]*models.APIToken
+	// Matchmaking: your place in the queue (nil if you aren't in it), how many
+	// are waiting and how far from your rating an opponent may be right now
+	Ticket					*matchmaking.Ticket
+	Waiting					int
+	Band					int
 }
 /*
 // Position
@@ -133,6 +174,7 @@ type templateDataPosition struct {
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	Position    			*models.Position
 	Positions   			[]*models.Position
 	MainGrid				template.HTML
@@ -146,6 +188,7 @@ type templateDataSignup struct {
 	Form					*forms.Form
 	IsAuthenticated			bool
 	ScreenName				string
+	Unread					int
 	Signup      			*models.Signup
 }
 
@@ -154,6 +197,41 @@ func humanDate(t time.Time) string {
 	return t.Format("Jan 02 2006 at 15:04")
 }
 
+// humanDuration - a turn clock the way a player would say it ("5 minutes", "3 days")
+func humanDuration(d time.Duration) string {
+	n, unit := int64(d/time.Second), "second"
+	switch {
+	case d == 0:
+		return "no limit"
+	case d%(24*time.Hour) == 0:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d%time.Hour == 0:
+		n, unit = int64(d/time.Hour), "hour"
+	case d%time.Minute == 0:
+		n, unit = int64(d/time.Minute), "minute"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// inc - for counting from 1 in templates
+func inc(i int) int {
+	return i + 1
+}
+
+// streak - a run of wins or losses the way a scoreboard shows it ("W3", "L2")
+func streak(n int) string {
+	switch {
+	case n > 0:
+		return fmt.Sprintf("W%d", n)
+	case n < 0:
+		return fmt.Sprintf("L%d", -n)
+	}
+	return "-"
+}
+
 // Give us an iterate function to use in templates
 func iterateRows(count uint, start uint) []uint {
 	var i uint
@@ -166,13 +244,16 @@ func iterateRows(count uint, start uint) []uint {
 
 // Iterate over letters
 func iterateColumns(count uint) []string {
-	var i uint
-	var alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	var items []string
-	for i = 0; i < (count); i++ {
-		items = append(items, string(alphabet[i]))
+	return game.Columns(int(count))
+}
+
+// Every board size a player may choose from
+func boardSizes() []int {
+	var sizes []int
+	for i := game.MinSize; i <= game.MaxSize; i++ {
+		sizes = append(sizes, i)
 	}
-	return items
+	return sizes
 }
 
 // Initialize template.FuncMap and store it in a GLOBAL variable
@@ -180,8 +261,11 @@ func iterateColumns(count uint) []string {
 //   - acts as a go-between for custom template functions and the functions themselves
 var functions = template.FuncMap{
 	"humanDate": humanDate,
+	"humanDuration": humanDuration,
+	"inc": inc,
 	"iterateColumns": iterateColumns,
 	"iterateRows": iterateRows,
+	"streak": streak,
 }
 
 // Cache templates
@@ -0,0 +1,311 @@
+package main
+
+// Webhooks
+// - A player can register URLs that get the events of their battles as signed
+//   JSON (see pkg/webhook); whoever runs the server can send every battle's
+//   events to URLs of their own with -webhook
+// - They hear whatever the event hub hears (see eventHub.forward): challenge,
+//   accept, strike and winner go straight through, and every ship a strike
+//   sinks is a "sunk" event of its own
+// - A battle forfeited on the turn clock has a winner too, and a matchmaking
+//   "match" is a challenge and an accept at once
+// - Deliveries happen in the background and are retried (see webhook.Sender);
+//   a player's webhook keeps a delivery log, -webhook URLs use the server log
+// - A player's webhook only reaches public addresses and never follows a
+//   redirect (see webhook.Guarded); -webhook URLs may go anywhere
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/webhook"
+)
+
+// Limits
+// - How many webhooks one player may have, and how long a URL may be
+// - How many deliveries a webhook's log shows
+const (
+	maxWebhooks            = 5
+	maxWebhookURLLength    = 255
+	webhookDeliveriesShown = 25
+)
+
+// Hub events webhooks carry, and what they are called in a payload
+// - A match also becomes an accept, a strike a sunk for every ship it sank (see webhookPayloads)
+var webhookEvents = map[string]string{
+	eventChallenge: webhook.Challenge,
+	eventAccept:    webhook.Accept,
+	eventStrike:    webhook.Strike,
+	eventWinner:    webhook.Winner,
+	eventTimeout:   webhook.Winner,
+	eventMatch:     webhook.Challenge,
+}
+
+// validateWebhook - what's wrong with a webhook someone wants to register (nil if nothing)
+// - The URL's host is looked up; it can't go to the server's own network (see webhook.CheckURL)
+func validateWebhook(url string, events []string) map[string][]string {
+	problems := map[string][]string{}
+	switch {
+	case strings.TrimSpace(url) == "":
+		problems["url"] = append(problems["url"], "A webhook needs a URL")
+	case len(url) > maxWebhookURLLength:
+		problems["url"] = append(problems["url"], fmt.Sprintf("The URL can't be longer than %d characters", maxWebhookURLLength))
+	case !webhook.ValidURL(url):
+		problems["url"] = append(problems["url"], "The URL must start with http:// or https://")
+	default:
+		err := webhook.CheckURL(url)
+		if errors.Is(err, webhook.ErrBlockedAddress) {
+			problems["url"] = append(problems["url"], "The URL has to go to a public address")
+		} else if err != nil {
+			problems["url"] = append(problems["url"], "Unable to find the URL's host")
+		}
+	}
+	for _, e := range events {
+		if !webhook.IsEvent(e) {
+			problems["events"] = append(problems["events"], fmt.Sprintf("Unknown event %q (use %s)", e, strings.Join(webhook.Events, ", ")))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// addWebhook - register a webhook for a player with a new secret
+// - problems (and no webhook) if the URL or events won't do, or they already have maxWebhooks
+func (app *application) addWebhook(playerID int, url string, events []string) (*models.Webhook, map[string][]string, error) {
+	url = strings.TrimSpace(url)
+	if problems := validateWebhook(url, events); problems != nil {
+		return nil, problems, nil
+	}
+	existing, err := app.webhooks.List(playerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(existing) >= maxWebhooks {
+		return nil, map[string][]string{"url": {fmt.Sprintf("You can't have more than %d webhooks", maxWebhooks)}}, nil
+	}
+	// Every event is the same as asking for none, which also picks up events added later
+	if len(events) == len(webhook.Events) {
+		events = nil
+	}
+	secret, err := app.GenerateRandomString(32)
+	if err != nil {
+		return nil, nil, err
+	}
+	webhookID, err := app.webhooks.Insert(playerID, url, secret, events)
+	if err != nil {
+		return nil, nil, err
+	}
+	h, err := app.webhooks.Get(playerID, webhookID)
+	return h, nil, err
+}
+
+// webhookPayloads - what an event from the hub becomes (nothing, if webhooks don't carry it)
+// - Players is both players in the battle, even when only one of them hears the event live
+// - A strike's first player is the one who fired (see announceStrike)
+// - A timeout with a winner is a "winner" (see enforceDeadlines); an expired challenge has none
+// - A "match" is published for each player (see pairPlayers); only the challenger's is sent
+func (app *application) webhookPayloads(e event) []webhook.Payload {
+	name, ok := webhookEvents[e.Type]
+	if !ok || len(e.players) == 0 {
+		return nil
+	}
+	if data, ok := e.Data.(map[string]interface{}); e.Type == eventTimeout && (!ok || data["winner_id"] == nil) {
+		return nil
+	}
+	players := e.players
+	b, err := app.battles.Get(e.players[0], e.BattleID)
+	if err == nil {
+		players = []int{b.Player1ID, b.Player2ID}
+	}
+	p := webhook.Payload{
+		ID:       strconv.FormatInt(e.ID, 10),
+		Event:    name,
+		BattleID: e.BattleID,
+		Players:  players,
+		Data:     e.Data,
+		Sent:     time.Now(),
+	}
+	// another payload for the same event
+	more := func(i int, name string, data interface{}) webhook.Payload {
+		next := p
+		next.ID = fmt.Sprintf("%s.%d", p.ID, i)
+		next.Event = name
+		next.Data = data
+		return next
+	}
+
+	switch e.Type {
+	case eventMatch:
+		if b == nil || e.players[0] != b.Player1ID {
+			return nil
+		}
+		p.Data = map[string]interface{}{"challenger": b.Player1ScreenName, "matchmaking": true}
+		return []webhook.Payload{p, more(1, webhook.Accept, map[string]interface{}{"opponent": b.Player2ScreenName, "matchmaking": true})}
+	case eventStrike:
+		shooter := e.players[0]
+		p.Data = map[string]interface{}{"player_id": shooter, "shots": e.Data}
+		payloads := []webhook.Payload{p}
+		for i, ship := range sunkShips(e.Data) {
+			payloads = append(payloads, more(i+1, webhook.Sunk, map[string]interface{}{"player_id": shooter, "ship": ship}))
+		}
+		return payloads
+	}
+	return []webhook.Payload{p}
+}
+
+// sunkShips - the ships a strike's shots sank
+// - The web and API handlers each have their own shot type; both have a "sunken_ship"
+func sunkShips(shots interface{}) []string {
+	data, err := json.Marshal(shots)
+	if err != nil {
+		return nil
+	}
+	var results []struct {
+		Ship string `json:"sunken_ship"`
+	}
+	if json.Unmarshal(data, &results) != nil {
+		return nil
+	}
+	var ships []string
+	for _, r := range results {
+		if r.Ship != "" {
+			ships = append(ships, r.Ship)
+		}
+	}
+	return ships
+}
+
+// forwardEvent - send an event from the hub to every webhook that wants it
+// - Runs on its own goroutine (see eventHub.publish); each delivery gets another
+func (app *application) forwardEvent(e event) {
+	for _, p := range app.webhookPayloads(e) {
+		body, err := json.Marshal(p)
+		if err != nil {
+			app.errorLog.Println("Unable to encode a webhook payload:", err)
+			continue
+		}
+		for _, url := range app.serverWebhooks {
+			go app.sendServerWebhook(url, p.Event, body)
+		}
+		for _, playerID := range p.Players {
+			if playerID == 0 || playerID == app.computerID {
+				continue
+			}
+			hooks, err := app.webhooks.Listening(playerID, p.Event)
+			if err != nil {
+				app.errorLog.Println("Unable to find webhooks:", err)
+				continue
+			}
+			for _, h := range hooks {
+				go app.sendWebhook(h, p, body)
+			}
+		}
+	}
+}
+
+// sendWebhook - deliver to a player's webhook, keeping its delivery log up to date
+func (app *application) sendWebhook(h *models.Webhook, p webhook.Payload, body []byte) {
+	deliveryID, err := app.webhooks.LogDelivery(h.ID, p.BattleID, p.Event, string(body))
+	if err != nil {
+		app.errorLog.Println("Unable to log a webhook delivery:", err)
+		return
+	}
+	d := webhook.Delivery{URL: h.URL, Secret: h.Secret, Event: p.Event, Body: body}
+	result := app.webhookSender.Send(d, func(r webhook.Result) {
+		app.logAttempt(deliveryID, r, r.Retry())
+	})
+	// Still worth another try, but out of attempts
+	if result.Retry() {
+		app.logAttempt(deliveryID, result, false)
+	}
+}
+
+// sendServerWebhook - deliver to a -webhook URL; how it went goes to the server log
+// - Whoever runs the server picked the URL, so it may be on their own network
+func (app *application) sendServerWebhook(url, event string, body []byte) {
+	d := webhook.Delivery{URL: url, Secret: app.serverWebhookSecret, Event: event, Body: body}
+	result := app.serverWebhookSender.Send(d, nil)
+	if result.OK() {
+		app.infoLog.Printf("Webhook %s %s: %d after %d attempt(s)", event, url, result.StatusCode, result.Attempt)
+		return
+	}
+	why := attemptError(result)
+	if result.Err != nil {
+		why = result.Err.Error()
+	}
+	app.errorLog.Printf("Webhook %s %s failed after %d attempt(s): %s", event, url, result.Attempt, why)
+}
+
+// testWebhook - send one ping to a webhook right now, no retries, and log it like any other delivery
+func (app *application) testWebhook(h *models.Webhook) (*models.WebhookDelivery, error) {
+	p := webhook.Payload{
+		ID:      fmt.Sprintf("ping.%d", time.Now().UnixNano()),
+		Event:   webhook.Ping,
+		Players: []int{h.PlayerID},
+		Data:    map[string]interface{}{"webhook_id": h.ID, "message": "Testing, testing - this webhook works"},
+		Sent:    time.Now(),
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	deliveryID, err := app.webhooks.LogDelivery(h.ID, 0, p.Event, string(body))
+	if err != nil {
+		return nil, err
+	}
+	result := app.webhookSender.Post(webhook.Delivery{URL: h.URL, Secret: h.Secret, Event: p.Event, Body: body}, 1)
+	app.logAttempt(deliveryID, result, false)
+	return &models.WebhookDelivery{
+		ID:         deliveryID,
+		WebhookID:  h.ID,
+		Event:      p.Event,
+		Payload:    string(body),
+		Status:     deliveryStatus(result, false),
+		Attempts:   result.Attempt,
+		StatusCode: result.StatusCode,
+		Error:      attemptError(result),
+		Created:    p.Sent,
+		Updated:    time.Now(),
+	}, nil
+}
+
+// logAttempt - put an attempt in the delivery log; more is true if another attempt is coming
+func (app *application) logAttempt(deliveryID int, r webhook.Result, more bool) {
+	err := app.webhooks.UpdateDelivery(deliveryID, r.Attempt, r.StatusCode, deliveryStatus(r, more), attemptError(r))
+	if err != nil {
+		app.errorLog.Println("Unable to log a webhook delivery:", err)
+	}
+}
+
+func deliveryStatus(r webhook.Result, more bool) string {
+	switch {
+	case r.OK():
+		return models.DeliveryDelivered
+	case more:
+		return models.DeliveryPending
+	}
+	return models.DeliveryFailed
+}
+
+// attemptError - why an attempt didn't work, for the delivery log ("" if it did)
+// - A player sees this, so every network error reads the same; otherwise the
+//   "Test" button would tell them which ports are open wherever the URL goes
+func attemptError(r webhook.Result) string {
+	if errors.Is(r.Err, webhook.ErrBlockedAddress) {
+		return "The URL goes to an address that isn't public"
+	}
+	if r.Err != nil {
+		return "No answer from the receiver"
+	}
+	if !r.OK() {
+		return fmt.Sprintf("The receiver answered %d", r.StatusCode)
+	}
+	return ""
+}
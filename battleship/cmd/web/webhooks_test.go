@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/webhook"
+)
+
+func TestWebhookPayloads(t *testing.T) {
+	app := newTestApplication(t)
+	amy, err := app.players.Insert("amy", "", "Tr1cky-Password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ben, err := app.players.Insert("ben", "", "Tr1cky-Password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amysBoard, _ := app.boards.Create(amy, "amy's", game.DefaultSize)
+	battleID, err := app.battles.Create(amy, amysBoard, ben, "first", game.Rules{Mode: game.Classic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type want struct {
+		id, event string
+		data      interface{}
+	}
+	shots := []map[string]interface{}{
+		{"coordinate": "1,A", "hit": true, "sunken_ship": "destroyer"},
+		{"coordinate": "5,E", "hit": false},
+		{"coordinate": "3,C", "hit": true, "sunken_ship": "cruiser"},
+	}
+	tests := []struct {
+		name  string
+		event event
+		want  []want
+	}{
+		{"challenge", event{ID: 1, Type: eventChallenge, Data: map[string]string{"challenger": "amy"}, players: []int{ben}},
+			[]want{{"1", webhook.Challenge, map[string]string{"challenger": "amy"}}}},
+		{"strike that sinks two ships", event{ID: 2, Type: eventStrike, Data: shots, players: []int{ben, amy}},
+			[]want{
+				{"2", webhook.Strike, map[string]interface{}{"player_id": ben, "shots": shots}},
+				{"2.1", webhook.Sunk, map[string]interface{}{"player_id": ben, "ship": "destroyer"}},
+				{"2.2", webhook.Sunk, map[string]interface{}{"player_id": ben, "ship": "cruiser"}},
+			}},
+		{"strike that misses", event{ID: 3, Type: eventStrike, Data: shots[1:2], players: []int{ben, amy}},
+			[]want{{"3", webhook.Strike, map[string]interface{}{"player_id": ben, "shots": shots[1:2]}}}},
+		{"forfeited on the turn clock", event{ID: 4, Type: eventTimeout, Data: map[string]interface{}{"status": models.StatusAbandoned, "winner_id": amy}, players: []int{amy, ben}},
+			[]want{{"4", webhook.Winner, map[string]interface{}{"status": models.StatusAbandoned, "winner_id": amy}}}},
+		{"expired challenge", event{ID: 5, Type: eventTimeout, Data: map[string]interface{}{"status": models.StatusExpired}, players: []int{amy, ben}},
+			nil},
+		{"match, as the challenger hears it", event{ID: 6, Type: eventMatch, Data: map[string]string{"opponent": "ben"}, players: []int{amy}},
+			[]want{
+				{"6", webhook.Challenge, map[string]interface{}{"challenger": "amy", "matchmaking": true}},
+				{"6.1", webhook.Accept, map[string]interface{}{"opponent": "ben", "matchmaking": true}},
+			}},
+		{"match, as the opponent hears it", event{ID: 7, Type: eventMatch, Data: map[string]string{"opponent": "amy"}, players: []int{ben}},
+			nil},
+		{"turn", event{ID: 8, Type: eventTurn, players: []int{ben}},
+			nil},
+	}
+	for _, tt := range tests {
+		tt.event.BattleID = battleID
+		payloads := app.webhookPayloads(tt.event)
+		if len(payloads) != len(tt.want) {
+			t.Errorf("%s: %d payloads %+v, want %d", tt.name, len(payloads), payloads, len(tt.want))
+			continue
+		}
+		for i, p := range payloads {
+			w := tt.want[i]
+			if p.ID != w.id || p.Event != w.event || p.BattleID != battleID || !reflect.DeepEqual(p.Players, []int{amy, ben}) || !reflect.DeepEqual(p.Data, w.data) {
+				t.Errorf("%s: payload %d = %+v, want %s %s %v for battle %d between %d and %d", tt.name, i, p, w.id, w.event, w.data, battleID, amy, ben)
+			}
+		}
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://93.184.216.34/battleship", ""},
+		{"", "A webhook needs a URL"},
+		{"ftp://93.184.216.34/", "The URL must start with http:// or https://"},
+		{"http://127.0.0.1:4000/", "The URL has to go to a public address"},
+		{"http://localhost:4000/", "The URL has to go to a public address"},
+		{"http://10.0.0.1/", "The URL has to go to a public address"},
+		{"http://[::1]/", "The URL has to go to a public address"},
+		{"http://169.254.169.254/latest/meta-data/", "The URL has to go to a public address"},
+	}
+	for _, tt := range tests {
+		problems := validateWebhook(tt.url, nil)
+		got := ""
+		if len(problems["url"]) > 0 {
+			got = problems["url"][0]
+		}
+		if got != tt.want {
+			t.Errorf("validateWebhook(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+// A webhook whose host has come to point at the server's own network since it
+// was registered doesn't get through, and the delivery log doesn't say what's there
+func TestTestWebhookStaysOffTheServersNetwork(t *testing.T) {
+	app := newTestApplication(t)
+	reached := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer ts.Close()
+
+	amy, err := app.players.Insert("amy", "", "Tr1cky-Password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhookID, err := app.webhooks.Insert(amy, ts.URL, "secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := app.webhooks.Get(amy, webhookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := app.testWebhook(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reached || d.Status != models.DeliveryFailed || d.Error != "The URL goes to an address that isn't public" {
+		t.Errorf("testWebhook(%s) = %s %q (reached %v), want failed without reaching it", ts.URL, d.Status, d.Error, reached)
+	}
+}
@@ -4,7 +4,6 @@ go 1.13
 
 require (
 	github.com/bmizerany/pat v0.0.0-20170815010413-6226ea591a40
-	github.com/briandowns/GoPasswordUtilities v0.0.0-20160218000643-2e3306706fa0
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/golangcollege/sessions v1.1.0
 	github.com/justinas/alice v0.0.0-20171023064455-03f45bd4b7da
@@ -1,15 +1,16 @@
 package forms
 
 import (
+	"bufio"
 	"fmt"
 	"net/url"
+	"os"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"unicode/utf8"
 
-	B "github.com/519seven/cs610/battleship/pkg/models/sqlite3"
-	gpu "github.com/briandowns/GoPasswordUtilities"
+	"github.com/519seven/cs610/battleship/pkg/game"
 )
 
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
@@ -73,15 +74,65 @@ func (f *Form) MinLength(field string, d int) {
 }
 
 // PasswordComplexity - make sure password meets basic complexity requirements
+// - A point for each of lower case, upper case, numbers and special characters,
+//   and a point off if the password turns up in WordList; it needs 2
+// - A server without a word list still checks the rest
 func (f *Form) PasswordComplexity() {
 	pass := f.Get("password")
-	gpuPass := gpu.New(pass)    
-    gpuPass.ProcessPassword()
-    if gpuPass.ComplexityRating() == "Horrible" || gpuPass.ComplexityRating() == "Weak" {
+	score := 0
+	for _, class := range passwordClasses {
+		if class.MatchString(pass) {
+			score++
+		}
+	}
+	if inWordList(pass) {
+		score--
+	}
+	if score < 2 {
 		f.Errors.Add("password", "Your password is too weak. Please use alpha-numeric, mixed case, and special characters.")
 	}
 }
 
+// WordList - the dictionary PasswordComplexity looks passwords up in (one word a line)
+// - It is read once, so set it before the first password is checked
+var WordList = "/usr/share/dict/words"
+
+var passwordClasses = []*regexp.Regexp{
+	regexp.MustCompile(`[a-z]`),
+	regexp.MustCompile(`[A-Z]`),
+	regexp.MustCompile(`[0-9]`),
+	regexp.MustCompile(`[\!\@\#\$\%\^\&\*\(\\\)\-_\=\+\,\.\?\/\:\;\{\}\[\]~]`),
+}
+
+// The words in WordList, lower case; read the first time a password is checked
+var (
+	words     map[string]bool
+	wordsOnce sync.Once
+)
+
+func loadWords() {
+	words = map[string]bool{}
+	file, err := os.Open(WordList)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		words[strings.ToLower(strings.TrimSpace(scanner.Text()))] = true
+	}
+}
+
+// inWordList - is the password, in any case, a word in WordList? (false if there is no WordList)
+func inWordList(pass string) bool {
+	if pass == "" {
+		return false
+	}
+	wordsOnce.Do(loadWords)
+	return words[strings.ToLower(pass)]
+}
+
 // PermittedValues - matches one of a set of specific permitted values
 func (f *Form) PermittedValues(field string, opts ...string) {
 	value := f.Get(field)
@@ -119,24 +170,6 @@ func (f *Form) RequiredNumberOfItems(shipType string, requiredNumber int, counte
 	}
 }
 
-// ShipLength - how many pins should this ship get?
-func ShipLength(shipName string) int {
-	// the first coordinate is given so we want n-1 more
-	switch shipName {
-	case "carrier":
-		return 5
-	case "battleship":
-		return 4
-	case "cruiser":
-		return 3
-	case "submarine":
-		return 3
-	case "destroyer":
-		return 2
-	}
-	return 0
-}
-
 // MaxLength - for checking maximum number of characters
 func (f *Form) SpacesAbsent(field string) {
 	value := f.Get(field)
@@ -147,44 +180,26 @@ func (f *Form) SpacesAbsent(field string) {
 
 
 // ValidNumberOfItems - make sure that the ship in question has proper pin placement
-func (f *Form) ValidNumberOfItems(coordinates []string, shipName string) {
-	// once we have a boardID, update coordinates table with each ship's XY
-	// First, we have to define a set of coordinates to a ship
-	// If our coordinates don't meet our requirements,
-	// return to the form with an error message
-	// loop through the values, pick a row, find out what is adjacent
-	// figure out which ship it is, remember the ship
-	// if our ship definitions are violated, fail this routine
-	searchDirection := "initialize"
-	numberOfFathomsRemaining := ShipLength(shipName) - 1
+func (f *Form) ValidNumberOfItems(board *game.Board, coordinates []string, shipName string) {
+	// The rules live in the game package; we just translate the form's
+	// "row,col" strings and turn a broken rule into a message for the user
+	ship, ok := board.Fleet.Lookup(shipName)
+	if !ok {
+		f.Errors.Add(shipName, fmt.Sprintf("%s is not part of the fleet", shipName))
+		return
+	}
+	var shots []game.Shot
 	for _, rc := range coordinates {
-		s := strings.Split(rc, ",")
-		//fmt.Println(s)
-		row, col := s[0], s[1]
-		nxtR, _ := strconv.Atoi(row)
-		nextRow := strconv.Itoa(nxtR+1)
-		nextCol := string(B.GetNextChar(col, 10))
-		if (searchDirection == "initialize" || searchDirection == "row") && numberOfFathomsRemaining > 0 && B.MatchFound(row+","+nextCol, strings.Join(coordinates, " ")) {
-			// is the next column in the slice?
-			//fmt.Println("match found in the next column: ", row+"|"+nextCol, "searching this row only")
-			searchDirection = "row"
-			numberOfFathomsRemaining -= 1
-		}
-		if (searchDirection == "initialize" || searchDirection == "column") && numberOfFathomsRemaining > 0 && B.MatchFound(nextRow+","+col, strings.Join(coordinates, " ")) {
-			// is the next row in the slice?
-			//fmt.Println("match found in the next row: ", nextRow+"|"+col, "searching this column only")
-			searchDirection = "column"
-			numberOfFathomsRemaining -= 1
+		s, err := game.ParseCoordinate(rc)
+		if err != nil {
+			f.Errors.Add(shipName, err.Error())
+			return
 		}
+		shots = append(shots, s)
 	}
-	// after looping through all of the coordinates of a ship, we ought to be at 0 fathoms remaining
-	// (a fathom is a unit of measurement based on one's outstretched arms)
-	if numberOfFathomsRemaining != 0 {
-		// we did not receive enough coordinates to satisfy the requirement for this ship
-		//log.Info("numberOfFathomsRemaining is not 0")
-		fmt.Println("numberOfFathomsRemaining is not 0!  Sending you back to the form with your data.", numberOfFathomsRemaining)
-		fmt.Println("The ship that is in error is:", shipName)
-		msg := fmt.Sprintf("Unable to calculate the correct number of coordinates (%d) necessary for a %s", ShipLength(shipName), shipName)
+	err := board.ValidatePlacement(ship.Length, shots)
+	if err != nil {
+		msg := fmt.Sprintf("Unable to calculate the correct number of coordinates (%d) necessary for a %s", ship.Length, ship.Name)
 		f.Errors.Add(shipName, msg)
 	}
 }
@@ -0,0 +1,53 @@
+package forms
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPasswordComplexity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	words := filepath.Join(dir, "words")
+	if err = ioutil.WriteFile(words, []byte("Battleship\npassword1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(list string) { WordList, wordsOnce = list, sync.Once{} }(WordList)
+
+	tests := []struct {
+		wordList string
+		password string
+		valid    bool
+	}{
+		{words, "B0mbs4way:(", true},
+		{words, "P34nutButter76", true},
+		{words, "lowercaseonly", false},
+		{words, "", false},
+		{words, "Short1!", true},    // MinLength's problem, not this one's
+		{words, "password1", false}, // two kinds of character, but in the word list
+		{words, "PASSWORD1", false}, // in any case
+		{words, "Battleship", false},
+		{words, "Battleship1", true}, // not a word, only part of one
+		{words, "Ship$", true},
+		{words, "ship", false},
+		{filepath.Join(dir, "missing"), "password1", true},
+		{filepath.Join(dir, "missing"), "ship", false},
+	}
+	for _, tt := range tests {
+		if WordList != tt.wordList {
+			WordList, wordsOnce = tt.wordList, sync.Once{}
+		}
+		f := New(url.Values{"password": {tt.password}})
+		f.PasswordComplexity()
+		if f.Valid() != tt.valid {
+			t.Errorf("PasswordComplexity(%q) with %s: valid = %v, want %v", tt.password, filepath.Base(tt.wordList), f.Valid(), tt.valid)
+		}
+	}
+}
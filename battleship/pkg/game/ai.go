@@ -0,0 +1,257 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Knowledge - what a shooter can know about the board they are firing at
+// - Every pin they have dropped, and whether it hit
+// - Which squares belong to ships that have been sunk (sinking a ship is announced)
+// - The lengths of the ships still afloat
+type Knowledge struct {
+	Size      int
+	Misses    map[Shot]bool
+	Hits      map[Shot]bool
+	Sunk      map[Shot]bool
+	Remaining []int
+
+	pending map[Shot]bool
+}
+
+// Knowledge - this board as the opponent sees it
+func (b *Board) Knowledge() Knowledge {
+	k := Knowledge{
+		Size:    b.Size,
+		Misses:  map[Shot]bool{},
+		Hits:    map[Shot]bool{},
+		Sunk:    map[Shot]bool{},
+		pending: map[Shot]bool{},
+	}
+	for s := range b.struck {
+		shipType, ok := b.squares[s]
+		switch {
+		case !ok:
+			k.Misses[s] = true
+		case b.isSunk(shipType):
+			k.Sunk[s] = true
+		default:
+			k.Hits[s] = true
+		}
+	}
+	for _, ship := range b.Fleet {
+		if _, placed := b.ships[ship.Type]; placed && !b.isSunk(ship.Type) {
+			k.Remaining = append(k.Remaining, ship.Length)
+		}
+	}
+	return k
+}
+
+// Is this square worth shooting at? (on the board, not yet fired upon)
+func (k Knowledge) open(row, col int) bool {
+	if row < 1 || row > k.Size || col < 0 || col >= k.Size {
+		return false
+	}
+	s := square(row, col)
+	return !k.Misses[s] && !k.Hits[s] && !k.Sunk[s] && !k.pending[s]
+}
+
+// Could a ship be sitting on this square? (hits count, misses and sunk ships don't)
+func (k Knowledge) available(row, col int) bool {
+	if row < 1 || row > k.Size || col < 0 || col >= k.Size {
+		return false
+	}
+	s := square(row, col)
+	return !k.Misses[s] && !k.Sunk[s]
+}
+
+func (k Knowledge) openSquares() []Shot {
+	var squares []Shot
+	for row := 1; row <= k.Size; row++ {
+		for col := 0; col < k.Size; col++ {
+			if k.open(row, col) {
+				squares = append(squares, square(row, col))
+			}
+		}
+	}
+	return squares
+}
+
+// Strategy - how the computer picks its next shot
+// - Next returns false when there is nothing left to shoot at
+type Strategy interface {
+	Name() string
+	Next(k Knowledge, rng *rand.Rand) (Shot, bool)
+}
+
+// Strategies, easiest first
+const (
+	StrategyRandom      = "random"
+	StrategyHunt        = "hunt"
+	StrategyProbability = "probability"
+)
+
+// Strategies - every strategy the computer knows, easiest first
+var Strategies = []Strategy{RandomFire{}, HuntTarget{}, ProbabilityDensity{}}
+
+// ParseStrategy - find a strategy by name ("" is the default, hunt/target)
+func ParseStrategy(name string) (Strategy, error) {
+	if name == "" {
+		name = StrategyHunt
+	}
+	for _, st := range Strategies {
+		if st.Name() == name {
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("game: unknown strategy %q", name)
+}
+
+// Aim - pick n different shots for one turn (n > 1 in a salvo)
+func Aim(st Strategy, k Knowledge, n int, rng *rand.Rand) []Shot {
+	pending := map[Shot]bool{}
+	for s := range k.pending {
+		pending[s] = true
+	}
+	k.pending = pending
+
+	var shots []Shot
+	for len(shots) < n {
+		s, ok := st.Next(k, rng)
+		if !ok {
+			break
+		}
+		k.pending[s] = true
+		shots = append(shots, s)
+	}
+	return shots
+}
+
+// RandomFire - shoot anywhere that hasn't been shot yet
+type RandomFire struct{}
+
+func (RandomFire) Name() string { return StrategyRandom }
+
+func (RandomFire) Next(k Knowledge, rng *rand.Rand) (Shot, bool) {
+	squares := k.openSquares()
+	if len(squares) == 0 {
+		return Shot{}, false
+	}
+	return squares[rng.Intn(len(squares))], true
+}
+
+// HuntTarget - search on a checkerboard until something is hit, then work
+// along the hits until the ship goes down
+type HuntTarget struct{}
+
+func (HuntTarget) Name() string { return StrategyHunt }
+
+func (HuntTarget) Next(k Knowledge, rng *rand.Rand) (Shot, bool) {
+	// Target: two hits in a row means the ship lies along that line
+	var ends, neighbors []Shot
+	for h := range k.Hits {
+		row, col := h.CoordX, h.col()
+		for _, d := range [][2]int{{0, 1}, {1, 0}} {
+			if !k.Hits[square(row+d[0], col+d[1])] {
+				continue
+			}
+			// walk to both ends of the line of hits
+			r, c := row, col
+			for k.Hits[square(r+d[0], c+d[1])] {
+				r, c = r+d[0], c+d[1]
+			}
+			if k.open(r+d[0], c+d[1]) {
+				ends = append(ends, square(r+d[0], c+d[1]))
+			}
+			if k.open(row-d[0], col-d[1]) {
+				ends = append(ends, square(row-d[0], col-d[1]))
+			}
+		}
+		for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			if k.open(row+d[0], col+d[1]) {
+				neighbors = append(neighbors, square(row+d[0], col+d[1]))
+			}
+		}
+	}
+	if len(ends) > 0 {
+		return ends[rng.Intn(len(ends))], true
+	}
+	if len(neighbors) > 0 {
+		return neighbors[rng.Intn(len(neighbors))], true
+	}
+
+	// Hunt: every ship covers at least one square of a grid spaced by the
+	// shortest ship still afloat
+	spacing := 2
+	for i, length := range k.Remaining {
+		if i == 0 || length < spacing {
+			spacing = length
+		}
+	}
+	if spacing < 1 {
+		spacing = 1
+	}
+	var squares []Shot
+	for _, s := range k.openSquares() {
+		if (s.CoordX+s.col())%spacing == 0 {
+			squares = append(squares, s)
+		}
+	}
+	if len(squares) == 0 {
+		return RandomFire{}.Next(k, rng)
+	}
+	return squares[rng.Intn(len(squares))], true
+}
+
+// ProbabilityDensity - count every way the remaining ships could still be
+// placed and shoot where the most of them overlap
+// - Placements through known hits count for a lot more, so it finishes off
+//   a wounded ship before hunting for the next one
+type ProbabilityDensity struct{}
+
+func (ProbabilityDensity) Name() string { return StrategyProbability }
+
+func (ProbabilityDensity) Next(k Knowledge, rng *rand.Rand) (Shot, bool) {
+	density := map[Shot]int{}
+	for _, length := range k.Remaining {
+		for row := 1; row <= k.Size; row++ {
+			for col := 0; col < k.Size; col++ {
+				for _, d := range [][2]int{{0, 1}, {1, 0}} {
+					fits, hits := true, 0
+					for i := 0; i < length && fits; i++ {
+						r, c := row+i*d[0], col+i*d[1]
+						fits = k.available(r, c)
+						if fits && k.Hits[square(r, c)] {
+							hits++
+						}
+					}
+					if !fits {
+						continue
+					}
+					weight := 1 + 50*hits
+					for i := 0; i < length; i++ {
+						r, c := row+i*d[0], col+i*d[1]
+						if k.open(r, c) {
+							density[square(r, c)] += weight
+						}
+					}
+				}
+			}
+		}
+	}
+
+	var best []Shot
+	bestDensity := 0
+	for s, n := range density {
+		switch {
+		case n > bestDensity:
+			best, bestDensity = []Shot{s}, n
+		case n == bestDensity:
+			best = append(best, s)
+		}
+	}
+	if len(best) == 0 {
+		return RandomFire{}.Next(k, rng)
+	}
+	return best[rng.Intn(len(best))], true
+}
@@ -0,0 +1,198 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// set - shots as a set, the way Knowledge keeps them
+func set(t *testing.T, coords ...string) map[Shot]bool {
+	t.Helper()
+	m := map[Shot]bool{}
+	for _, s := range shots(t, coords...) {
+		m[s] = true
+	}
+	return m
+}
+
+func TestKnowledge(t *testing.T) {
+	b := newFleetBoard(t)
+	for _, s := range shots(t, "1A", "1B", "1C", "1D", "1E", "9J", "5E") {
+		if _, err := b.Strike(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	k := b.Knowledge()
+
+	tests := []struct {
+		name string
+		got  map[Shot]bool
+		want map[Shot]bool
+	}{
+		{"misses", k.Misses, set(t, "5E")},
+		{"hits", k.Hits, set(t, "9J")},
+		{"sunk", k.Sunk, set(t, "1A", "1B", "1C", "1D", "1E")},
+	}
+	for _, tt := range tests {
+		if len(tt.got) != len(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			continue
+		}
+		for s := range tt.want {
+			if !tt.got[s] {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+				break
+			}
+		}
+	}
+	// only the destroyer is afloat; the ships that were never placed don't count
+	if len(k.Remaining) != 1 || k.Remaining[0] != 2 {
+		t.Errorf("Remaining = %v, want [2]", k.Remaining)
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"", StrategyHunt, true},
+		{StrategyRandom, StrategyRandom, true},
+		{StrategyHunt, StrategyHunt, true},
+		{StrategyProbability, StrategyProbability, true},
+		{"cheat", "", false},
+	}
+	for _, tt := range tests {
+		st, err := ParseStrategy(tt.name)
+		if (err == nil) != tt.ok || (err == nil && st.Name() != tt.want) {
+			t.Errorf("ParseStrategy(%q) = %v, %v; want %q", tt.name, st, err, tt.want)
+		}
+	}
+}
+
+// Whole games against random fleets: every shot is on the board and new, and
+// the fleet goes down before the board runs out
+func TestAimNeverWastesAShot(t *testing.T) {
+	tests := []struct {
+		size  int
+		salvo int
+	}{
+		{MinSize, 1},
+		{DefaultSize, 1},
+		{DefaultSize, 5},
+		{MaxSize, 3},
+	}
+	for _, st := range Strategies {
+		for _, tt := range tests {
+			rng := rand.New(rand.NewSource(int64(tt.size*10 + tt.salvo)))
+			b, err := RandomBoard(tt.size, DefaultFleet, rng, Placement{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fired := 0
+			for !b.FleetDestroyed() {
+				aimed := Aim(st, b.Knowledge(), tt.salvo, rng)
+				if len(aimed) == 0 || len(aimed) > tt.salvo {
+					t.Fatalf("%s on %d squares: %d shots with %d of %d fired, want 1 to %d", st.Name(), tt.size, len(aimed), fired, tt.size*tt.size, tt.salvo)
+				}
+				turn := map[Shot]bool{}
+				for _, s := range aimed {
+					if !b.InBounds(s) || b.Struck(s) || turn[s] {
+						t.Fatalf("%s on %d squares aimed at %s after %d shots: off the board or already struck", st.Name(), tt.size, s, fired)
+					}
+					turn[s] = true
+				}
+				if _, err = b.Volley(aimed); err != nil {
+					t.Fatal(err)
+				}
+				fired += len(aimed)
+			}
+			if fired > tt.size*tt.size {
+				t.Errorf("%s on %d squares took %d shots", st.Name(), tt.size, fired)
+			}
+		}
+	}
+}
+
+func TestAimRunsOutOfSquares(t *testing.T) {
+	k := Knowledge{Size: MinSize, Misses: map[Shot]bool{}, Hits: map[Shot]bool{}, Sunk: map[Shot]bool{}, Remaining: []int{2}}
+	for row := 1; row <= MinSize; row++ {
+		for col := 0; col < MinSize; col++ {
+			k.Misses[square(row, col)] = true
+		}
+	}
+	delete(k.Misses, square(4, 4))
+	delete(k.Misses, square(4, 5))
+	for _, st := range Strategies {
+		aimed := Aim(st, k, 3, rand.New(rand.NewSource(1)))
+		if len(aimed) != 2 {
+			t.Errorf("%s with 2 open squares aimed %v, want both of them", st.Name(), aimed)
+		}
+	}
+}
+
+// Once something is hit, the smarter strategies stay on it
+func TestAimFollowsUpAHit(t *testing.T) {
+	tests := []struct {
+		name   string
+		hits   []string
+		misses []string
+		want   []string
+	}{
+		{"one hit", []string{"5E"}, nil, []string{"4E", "6E", "5D", "5F"}},
+		{"one hit against the edge", []string{"1A"}, nil, []string{"2A", "1B"}},
+		{"one hit, some of it missed around", []string{"5E"}, []string{"4E", "5D"}, []string{"6E", "5F"}},
+		{"two hits across", []string{"5E", "5F"}, nil, []string{"5D", "5G"}},
+		{"two hits down", []string{"5E", "6E"}, nil, []string{"4E", "7E"}},
+		{"two hits, one end missed", []string{"5E", "5F"}, []string{"5D"}, []string{"5G"}},
+	}
+	for _, st := range []Strategy{HuntTarget{}, ProbabilityDensity{}} {
+		for _, tt := range tests {
+			want := set(t, tt.want...)
+			k := Knowledge{
+				Size:      DefaultSize,
+				Hits:      set(t, tt.hits...),
+				Misses:    set(t, tt.misses...),
+				Sunk:      map[Shot]bool{},
+				Remaining: []int{5, 4, 3, 3, 2},
+			}
+			for seed := int64(0); seed < 20; seed++ {
+				s, ok := st.Next(k, rand.New(rand.NewSource(seed)))
+				if !ok || !want[s] {
+					t.Errorf("%s, %s: aimed at %s, want one of %v", st.Name(), tt.name, s, tt.want)
+					break
+				}
+			}
+		}
+	}
+}
+
+// The strategies are listed easiest first, and they play that way
+func TestStrategiesGetHarder(t *testing.T) {
+	const games = 20
+	average := map[string]float64{}
+	for _, st := range Strategies {
+		rng := rand.New(rand.NewSource(7))
+		total := 0
+		for i := 0; i < games; i++ {
+			b, err := RandomBoard(DefaultSize, DefaultFleet, rng, Placement{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for !b.FleetDestroyed() {
+				if _, err = b.Volley(Aim(st, b.Knowledge(), 1, rng)); err != nil {
+					t.Fatal(err)
+				}
+				total++
+			}
+		}
+		average[st.Name()] = float64(total) / games
+	}
+	for i := 1; i < len(Strategies); i++ {
+		easier, harder := Strategies[i-1].Name(), Strategies[i].Name()
+		if average[harder] >= average[easier] {
+			t.Errorf("%s takes %.1f shots to sink a fleet and %s %.1f; want %s to take fewer", easier, average[easier], harder, average[harder], harder)
+		}
+	}
+}
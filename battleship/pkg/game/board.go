@@ -0,0 +1,178 @@
+package game
+
+import (
+	"sort"
+)
+
+// Board - one player's ocean: where the ships are and where the pins are
+type Board struct {
+	Size  int
+	Fleet Fleet
+
+	ships   map[string][]Shot
+	squares map[Shot]string
+	struck  map[Shot]bool
+}
+
+// NewBoard - an empty size x size board for the given fleet
+func NewBoard(size int, fleet Fleet) *Board {
+	return &Board{
+		Size:    size,
+		Fleet:   fleet,
+		ships:   map[string][]Shot{},
+		squares: map[Shot]string{},
+		struck:  map[Shot]bool{},
+	}
+}
+
+// InBounds - is this coordinate on the board?
+func (b *Board) InBounds(s Shot) bool {
+	c := s.col()
+	return s.CoordX >= 1 && s.CoordX <= b.Size && c >= 0 && c < b.Size
+}
+
+// Place - put a ship from the fleet on the board
+// - The ship must be in the fleet, have the right number of squares,
+//   be one unbroken row or column and not sit on top of another ship
+func (b *Board) Place(shipType string, coords []Shot) error {
+	ship, ok := b.Fleet.Lookup(shipType)
+	if !ok {
+		return ErrUnknownShip
+	}
+	if _, placed := b.ships[shipType]; placed {
+		return ErrOverlap
+	}
+	if err := b.ValidatePlacement(ship.Length, coords); err != nil {
+		return err
+	}
+	for _, s := range coords {
+		if _, taken := b.squares[s.normalize()]; taken {
+			return ErrOverlap
+		}
+	}
+	for _, s := range coords {
+		s = s.normalize()
+		b.squares[s] = shipType
+		b.ships[shipType] = append(b.ships[shipType], s)
+	}
+	return nil
+}
+
+// ValidatePlacement - check the shape of a ship without placing it
+func (b *Board) ValidatePlacement(length int, coords []Shot) error {
+	if len(coords) != length {
+		return ErrShipLength
+	}
+	for _, s := range coords {
+		if !b.InBounds(s) {
+			return ErrOutOfBounds
+		}
+	}
+	// Sort down the row, then across the column; a straight ship will
+	// then step by exactly one square in one direction only
+	sorted := make([]Shot, len(coords))
+	copy(sorted, coords)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].CoordX != sorted[j].CoordX {
+			return sorted[i].CoordX < sorted[j].CoordX
+		}
+		return sorted[i].col() < sorted[j].col()
+	})
+	for i := 1; i < len(sorted); i++ {
+		dRow := sorted[i].CoordX - sorted[0].CoordX
+		dCol := sorted[i].col() - sorted[0].col()
+		if !((dRow == 0 && dCol == i) || (dCol == 0 && dRow == i)) {
+			return ErrShipShape
+		}
+	}
+	return nil
+}
+
+// Strike - fire at a coordinate and report what happened
+func (b *Board) Strike(s Shot) (Outcome, error) {
+	if !b.InBounds(s) {
+		return Outcome{}, &ShotError{s, ErrOutOfBounds}
+	}
+	s = s.normalize()
+	if b.struck[s] {
+		return Outcome{}, &ShotError{s, ErrAlreadyStruck}
+	}
+	b.struck[s] = true
+
+	shipType, ok := b.squares[s]
+	if !ok {
+		return Outcome{Shot: s, Result: Miss}, nil
+	}
+	o := Outcome{Shot: s, Result: Hit, Ship: shipType}
+	if b.isSunk(shipType) {
+		o.Result = Sunk
+		o.FleetDestroyed = b.FleetDestroyed()
+	}
+	return o, nil
+}
+
+// Volley - fire several shots as one turn
+// - Every shot is checked before any of them lands, so a bad volley
+//   leaves the board untouched
+func (b *Board) Volley(shots []Shot) ([]Outcome, error) {
+	seen := map[Shot]bool{}
+	for _, s := range shots {
+		if !b.InBounds(s) {
+			return nil, &ShotError{s, ErrOutOfBounds}
+		}
+		s = s.normalize()
+		if b.struck[s] || seen[s] {
+			return nil, &ShotError{s, ErrAlreadyStruck}
+		}
+		seen[s] = true
+	}
+	var outcomes []Outcome
+	for _, s := range shots {
+		o, err := b.Strike(s)
+		if err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, nil
+}
+
+// Struck - has this coordinate already been fired upon?
+func (b *Board) Struck(s Shot) bool {
+	return b.struck[s.normalize()]
+}
+
+// Unstruck - how many squares are left to fire on
+func (b *Board) Unstruck() int {
+	return b.Size*b.Size - len(b.struck)
+}
+
+// SunkShips - how many ships on this board have gone down
+func (b *Board) SunkShips() int {
+	n := 0
+	for shipType := range b.ships {
+		if b.isSunk(shipType) {
+			n++
+		}
+	}
+	return n
+}
+
+// SurvivingShips - how many ships on this board are still afloat
+func (b *Board) SurvivingShips() int {
+	return len(b.ships) - b.SunkShips()
+}
+
+// FleetDestroyed - every ship that was placed has been sunk
+func (b *Board) FleetDestroyed() bool {
+	return len(b.ships) > 0 && b.SunkShips() == len(b.ships)
+}
+
+func (b *Board) isSunk(shipType string) bool {
+	for _, s := range b.ships[shipType] {
+		if !b.struck[s] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// shots - "1A 1B 1C" style coordinates, for writing boards out by hand
+func shots(t *testing.T, coords ...string) []Shot {
+	t.Helper()
+	var s []Shot
+	for _, c := range coords {
+		var row int
+		var col string
+		for i := range c {
+			if c[i] < '0' || c[i] > '9' {
+				col = c[i:]
+				break
+			}
+			row = row*10 + int(c[i]-'0')
+		}
+		s = append(s, Shot{CoordX: row, CoordY: col})
+	}
+	return s
+}
+
+// newFleetBoard - a default board with the carrier down row 1 and the destroyer in column J
+func newFleetBoard(t *testing.T) *Board {
+	t.Helper()
+	b := NewBoard(DefaultSize, DefaultFleet)
+	if err := b.Place("carrier", shots(t, "1A", "1B", "1C", "1D", "1E")); err != nil {
+		t.Fatalf("placing the carrier: %v", err)
+	}
+	if err := b.Place("destroyer", shots(t, "9J", "10J")); err != nil {
+		t.Fatalf("placing the destroyer: %v", err)
+	}
+	return b
+}
+
+func TestPlace(t *testing.T) {
+	tests := []struct {
+		name   string
+		ship   string
+		coords []string
+		want   error
+	}{
+		{"across a row", "cruiser", []string{"5C", "5D", "5E"}, nil},
+		{"down a column", "cruiser", []string{"3F", "4F", "5F"}, nil},
+		{"out of order", "cruiser", []string{"5E", "5C", "5D"}, nil},
+		{"lowercase columns", "cruiser", []string{"5c", "5d", "5e"}, nil},
+		{"not in the fleet", "rowboat", []string{"5C"}, ErrUnknownShip},
+		{"already placed", "carrier", []string{"3A", "3B", "3C", "3D", "3E"}, ErrOverlap},
+		{"on top of another ship", "cruiser", []string{"1C", "2C", "3C"}, ErrOverlap},
+		{"on top of another ship, lowercase", "cruiser", []string{"1c", "2c", "3c"}, ErrOverlap},
+		{"too short", "cruiser", []string{"5C", "5D"}, ErrShipLength},
+		{"too long", "cruiser", []string{"5C", "5D", "5E", "5F"}, ErrShipLength},
+		{"diagonal", "cruiser", []string{"3C", "4D", "5E"}, ErrShipShape},
+		{"gapped", "cruiser", []string{"5C", "5D", "5F"}, ErrShipShape},
+		{"bent", "cruiser", []string{"5C", "5D", "6D"}, ErrShipShape},
+		{"off the board", "cruiser", []string{"5I", "5J", "5K"}, ErrOutOfBounds},
+		{"row zero", "cruiser", []string{"0C", "1C", "2C"}, ErrOutOfBounds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFleetBoard(t)
+			err := b.Place(tt.ship, shots(t, tt.coords...))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Place(%s, %v) = %v, want %v", tt.ship, tt.coords, err, tt.want)
+			}
+			if tt.want != nil && b.SurvivingShips() != 2 {
+				t.Errorf("a failed placement changed the board: %d ships afloat", b.SurvivingShips())
+			}
+		})
+	}
+}
+
+func TestValidatePlacement(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		coords []string
+		want   error
+	}{
+		{"row", 4, []string{"2B", "2C", "2D", "2E"}, nil},
+		{"column", 4, []string{"2B", "3B", "4B", "5B"}, nil},
+		{"single square", 1, []string{"7G"}, nil},
+		{"diagonal down", 3, []string{"1A", "2B", "3C"}, ErrShipShape},
+		{"diagonal up", 3, []string{"3A", "2B", "1C"}, ErrShipShape},
+		{"gap in a row", 3, []string{"4A", "4B", "4D"}, ErrShipShape},
+		{"gap in a column", 3, []string{"4A", "5A", "7A"}, ErrShipShape},
+		{"same square twice", 3, []string{"4A", "4A", "4B"}, ErrShipShape},
+		{"wrong length", 3, []string{"4A", "4B"}, ErrShipLength},
+		{"past the last row", 2, []string{"10A", "11A"}, ErrOutOfBounds},
+		{"not a column", 2, []string{"4A", "4AB"}, ErrOutOfBounds},
+	}
+	b := NewBoard(DefaultSize, DefaultFleet)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := b.ValidatePlacement(tt.length, shots(t, tt.coords...))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ValidatePlacement(%d, %v) = %v, want %v", tt.length, tt.coords, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrike(t *testing.T) {
+	b := newFleetBoard(t)
+	tests := []struct {
+		name  string
+		shot  string
+		want  string
+		ship  string
+		err   error
+		fleet bool
+	}{
+		{"miss", "5E", "miss", "", nil, false},
+		{"hit", "9J", "hit", "destroyer", nil, false},
+		{"already struck", "9J", "", "", ErrAlreadyStruck, false},
+		{"already struck, lowercase", "9j", "", "", ErrAlreadyStruck, false},
+		{"sunk, lowercase", "10j", "sunk destroyer", "destroyer", nil, false},
+		{"off the board", "11A", "", "", ErrOutOfBounds, false},
+		{"past the last column", "1K", "", "", ErrOutOfBounds, false},
+		{"carrier 1", "1A", "hit", "carrier", nil, false},
+		{"carrier 2", "1B", "hit", "carrier", nil, false},
+		{"carrier 3", "1c", "hit", "carrier", nil, false},
+		{"carrier 4", "1D", "hit", "carrier", nil, false},
+		{"last ship", "1E", "fleet destroyed", "carrier", nil, true},
+	}
+	for _, tt := range tests {
+		o, err := b.Strike(shots(t, tt.shot)[0])
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("%s: Strike(%s) error = %v, want %v", tt.name, tt.shot, err, tt.err)
+		}
+		if err != nil {
+			var se *ShotError
+			if !errors.As(err, &se) {
+				t.Errorf("%s: Strike(%s) error %v is not a *ShotError", tt.name, tt.shot, err)
+			}
+			continue
+		}
+		if o.String() != tt.want || o.Ship != tt.ship || o.FleetDestroyed != tt.fleet {
+			t.Errorf("%s: Strike(%s) = %q (ship %q, fleet destroyed %v), want %q (ship %q, fleet destroyed %v)",
+				tt.name, tt.shot, o, o.Ship, o.FleetDestroyed, tt.want, tt.ship, tt.fleet)
+		}
+		if o.Shot.CoordY != shots(t, tt.shot)[0].normalize().CoordY {
+			t.Errorf("%s: Strike(%s) reported column %q", tt.name, tt.shot, o.Shot.CoordY)
+		}
+	}
+	if !b.FleetDestroyed() || b.SunkShips() != 2 || b.SurvivingShips() != 0 {
+		t.Errorf("after sinking everything: destroyed %v, sunk %d, surviving %d",
+			b.FleetDestroyed(), b.SunkShips(), b.SurvivingShips())
+	}
+}
+
+func TestStrikeEmptyBoard(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultFleet)
+	o, err := b.Strike(Shot{CoordX: 1, CoordY: "A"})
+	if err != nil || o.Result != Miss {
+		t.Fatalf("Strike on an empty board = %v, %v; want a miss", o, err)
+	}
+	if b.FleetDestroyed() {
+		t.Error("a board with no ships on it counts as destroyed")
+	}
+}
+
+func TestVolley(t *testing.T) {
+	tests := []struct {
+		name  string
+		shots []string
+		want  []string
+		err   error
+	}{
+		{"all land", []string{"9J", "10J", "5E"}, []string{"hit", "sunk destroyer", "miss"}, nil},
+		{"lowercase", []string{"9j", "10j"}, []string{"hit", "sunk destroyer"}, nil},
+		{"one off the board", []string{"9J", "10J", "11J"}, nil, ErrOutOfBounds},
+		{"one already struck", []string{"9J", "10J", "3C"}, nil, ErrAlreadyStruck},
+		{"the same square twice", []string{"9J", "10J", "9J"}, nil, ErrAlreadyStruck},
+		{"the same square twice, mixed case", []string{"9J", "10J", "9j"}, nil, ErrAlreadyStruck},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFleetBoard(t)
+			if _, err := b.Strike(Shot{CoordX: 3, CoordY: "C"}); err != nil {
+				t.Fatal(err)
+			}
+			outcomes, err := b.Volley(shots(t, tt.shots...))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Volley(%v) error = %v, want %v", tt.shots, err, tt.err)
+			}
+			if err != nil {
+				// All or nothing: none of the good shots in a bad volley land
+				for _, s := range shots(t, tt.shots...) {
+					if b.InBounds(s) && b.Struck(s) && s.normalize() != (Shot{CoordX: 3, CoordY: "C"}) {
+						t.Errorf("%s was struck by a volley that failed", s)
+					}
+				}
+				if b.SunkShips() != 0 {
+					t.Errorf("a volley that failed sank %d ships", b.SunkShips())
+				}
+				return
+			}
+			if len(outcomes) != len(tt.want) {
+				t.Fatalf("Volley(%v) = %d outcomes, want %d", tt.shots, len(outcomes), len(tt.want))
+			}
+			for i, o := range outcomes {
+				if o.String() != tt.want[i] {
+					t.Errorf("shot %d (%s) = %q, want %q", i, tt.shots[i], o, tt.want[i])
+				}
+			}
+		})
+	}
+}
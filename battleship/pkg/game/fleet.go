@@ -0,0 +1,106 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Ship - a type of ship and how many squares it occupies
+// - Abbreviation is the letter a player types on the board to place it
+type Ship struct {
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+	Length       int    `json:"length"`
+}
+
+// Fleet - the ships each player must place
+type Fleet []Ship
+
+// DefaultFleet - the standard five ship fleet
+var DefaultFleet = Fleet{
+	{Type: "carrier", Name: "Carrier", Abbreviation: "C", Length: 5},
+	{Type: "battleship", Name: "Battleship", Abbreviation: "B", Length: 4},
+	{Type: "cruiser", Name: "Cruiser", Abbreviation: "R", Length: 3},
+	{Type: "submarine", Name: "Submarine", Abbreviation: "S", Length: 3},
+	{Type: "destroyer", Name: "Destroyer", Abbreviation: "D", Length: 2},
+}
+
+// LoadFleet - read a fleet definition (a JSON list of ships)
+func LoadFleet(r io.Reader) (Fleet, error) {
+	var f Fleet
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("game: unable to read fleet: %w", err)
+	}
+	for i := range f {
+		f[i].Abbreviation = strings.ToUpper(f[i].Abbreviation)
+		if f[i].Name == "" {
+			f[i].Name = f[i].Type
+		}
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// LoadFleetFile - LoadFleet from a file on disk
+func LoadFleetFile(path string) (Fleet, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return LoadFleet(fh)
+}
+
+// Validate - every ship needs a unique type, a unique one letter abbreviation
+// and a length that fits on the smallest board
+func (f Fleet) Validate() error {
+	if len(f) == 0 {
+		return fmt.Errorf("game: a fleet needs at least one ship")
+	}
+	types := map[string]bool{}
+	letters := map[string]bool{}
+	for _, s := range f {
+		switch {
+		case s.Type == "":
+			return fmt.Errorf("game: every ship needs a type")
+		case types[s.Type]:
+			return fmt.Errorf("game: ship type %q appears more than once", s.Type)
+		case len(s.Abbreviation) != 1 || !strings.Contains(alphabet, s.Abbreviation):
+			return fmt.Errorf("game: %s needs a single letter abbreviation", s.Type)
+		case letters[s.Abbreviation]:
+			return fmt.Errorf("game: abbreviation %q is used by more than one ship", s.Abbreviation)
+		case s.Length < 1 || s.Length > MinSize:
+			return fmt.Errorf("game: %s has an impossible length (%d)", s.Type, s.Length)
+		}
+		types[s.Type] = true
+		letters[s.Abbreviation] = true
+	}
+	return nil
+}
+
+// Lookup - find a ship in the fleet by its type
+func (f Fleet) Lookup(shipType string) (Ship, bool) {
+	for _, s := range f {
+		if s.Type == shipType {
+			return s, true
+		}
+	}
+	return Ship{}, false
+}
+
+// ByAbbreviation - find a ship in the fleet by the letter used to place it
+func (f Fleet) ByAbbreviation(letter string) (Ship, bool) {
+	letter = strings.ToUpper(letter)
+	for _, s := range f {
+		if s.Abbreviation == letter {
+			return s, true
+		}
+	}
+	return Ship{}, false
+}
@@ -0,0 +1,157 @@
+// Package game holds the rules of Battleship
+// - Ship placement, strike resolution, sinking and winner detection
+// - Nothing in here knows about HTTP or the database
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrAlreadyStruck = errors.New("game: coordinate has already been struck")
+	ErrOutOfBounds   = errors.New("game: coordinate is off the board")
+	ErrOverlap       = errors.New("game: ships may not overlap")
+	ErrShipLength    = errors.New("game: wrong number of coordinates for ship")
+	ErrShipShape     = errors.New("game: ship must be a single unbroken row or column")
+	ErrUnknownShip   = errors.New("game: ship is not part of the fleet")
+)
+
+// Columns are lettered, rows are numbered (starting at 1)
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Board dimensions
+// - DefaultSize is the classic 10x10 grid (rows 1-10, columns A-J)
+// - Boards are always square; a 26x26 board runs out of letters at Z
+const (
+	DefaultSize = 10
+	MinSize     = 8
+	MaxSize     = 26
+)
+
+// ValidSize - can we play on a board this big?
+func ValidSize(size int) bool {
+	return size >= MinSize && size <= MaxSize
+}
+
+// Columns - the column letters of a board of the given size
+func Columns(size int) []string {
+	if size > MaxSize {
+		size = MaxSize
+	}
+	var cols []string
+	for i := 0; i < size; i++ {
+		cols = append(cols, alphabet[i:i+1])
+	}
+	return cols
+}
+
+// Shot - a single coordinate on the board
+// - CoordX is the row, CoordY is the column letter (same as models.Position)
+type Shot struct {
+	CoordX int
+	CoordY string
+}
+
+// ParseCoordinate - turn "row,col" (the format used by the board forms) into a Shot
+func ParseCoordinate(rc string) (Shot, error) {
+	s := strings.Split(rc, ",")
+	if len(s) != 2 {
+		return Shot{}, fmt.Errorf("game: malformed coordinate %q", rc)
+	}
+	row, err := strconv.Atoi(s[0])
+	if err != nil {
+		return Shot{}, fmt.Errorf("game: malformed coordinate %q", rc)
+	}
+	return Shot{CoordX: row, CoordY: strings.ToUpper(s[1])}, nil
+}
+
+// ShotError - a shot that can't be fired, and why (ErrOutOfBounds or ErrAlreadyStruck)
+// - errors.Is sees through it to the reason
+type ShotError struct {
+	Shot Shot
+	Err  error
+}
+
+func (e *ShotError) Error() string {
+	return fmt.Sprintf("%s (%d,%s)", e.Err.Error(), e.Shot.CoordX, e.Shot.CoordY)
+}
+
+func (e *ShotError) Unwrap() error {
+	return e.Err
+}
+
+// Column index (0-based) of the shot, -1 if it isn't a single letter
+func (s Shot) col() int {
+	if len(s.CoordY) != 1 {
+		return -1
+	}
+	return strings.Index(alphabet, strings.ToUpper(s.CoordY))
+}
+
+// Normalize the column so "b" and "B" are the same square
+func (s Shot) normalize() Shot {
+	return Shot{CoordX: s.CoordX, CoordY: strings.ToUpper(s.CoordY)}
+}
+
+func (s Shot) String() string {
+	return fmt.Sprintf("%d%s", s.CoordX, s.CoordY)
+}
+
+// Result - what a strike did
+type Result int
+
+const (
+	Miss Result = iota
+	Hit
+	Sunk
+)
+
+func (r Result) String() string {
+	switch r {
+	case Hit:
+		return "hit"
+	case Sunk:
+		return "sunk"
+	}
+	return "miss"
+}
+
+// Outcome - the full answer to a strike
+type Outcome struct {
+	Shot           Shot
+	Result         Result
+	Ship           string
+	FleetDestroyed bool
+}
+
+// PinColor - the pin the board stores for this outcome
+func (o Outcome) PinColor() string {
+	if o.Result == Miss {
+		return "gray"
+	}
+	return "red"
+}
+
+// SunkShip - name of the ship that went down with this strike, if any
+func (o Outcome) SunkShip() string {
+	if o.Result == Sunk {
+		return o.Ship
+	}
+	return ""
+}
+
+// Human readable report: "miss", "hit", "sunk <ship>" or "fleet destroyed"
+func (o Outcome) String() string {
+	switch {
+	case o.FleetDestroyed:
+		return "fleet destroyed"
+	case o.Result == Sunk:
+		return "sunk " + o.Ship
+	case o.Result == Hit:
+		return "hit"
+	}
+	return "miss"
+}
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+var ErrNoRoom = errors.New("game: unable to fit the fleet on the board")
+
+// How hard RandomBoard tries before giving up
+const (
+	placementAttempts = 200
+	placementRestarts = 50
+)
+
+// PlacementError - RandomBoard gave up, and the ship it last couldn't find room for
+// - errors.Is sees ErrNoRoom through it
+type PlacementError struct {
+	Ship       Ship
+	Size       int
+	NoTouching bool
+}
+
+func (e *PlacementError) Error() string {
+	gap := ""
+	if e.NoTouching {
+		gap = " with a gap around every ship"
+	}
+	return fmt.Sprintf("%s (no room for the %s on %dx%d%s)", ErrNoRoom.Error(), e.Ship.Name, e.Size, e.Size, gap)
+}
+
+func (e *PlacementError) Unwrap() error {
+	return ErrNoRoom
+}
+
+// Placement - constraints on where RandomBoard may put ships
+// - NoTouching keeps a one square gap around every ship, diagonals included
+type Placement struct {
+	NoTouching bool
+}
+
+// RandomBoard - a board with every ship in the fleet placed at random
+// - Longest ships go first since they are the hardest to fit
+// - A *PlacementError if it can't be done
+func RandomBoard(size int, fleet Fleet, rng *rand.Rand, p Placement) (*Board, error) {
+	ships := make(Fleet, len(fleet))
+	copy(ships, fleet)
+	sort.SliceStable(ships, func(i, j int) bool { return ships[i].Length > ships[j].Length })
+
+	var stuck Ship
+	for restart := 0; restart < placementRestarts; restart++ {
+		b := NewBoard(size, fleet)
+		placed := true
+		for _, ship := range ships {
+			if !b.placeRandomly(ship, rng, p) {
+				placed, stuck = false, ship
+				break
+			}
+		}
+		if placed {
+			return b, nil
+		}
+	}
+	return nil, &PlacementError{Ship: stuck, Size: size, NoTouching: p.NoTouching}
+}
+
+func (b *Board) placeRandomly(ship Ship, rng *rand.Rand, p Placement) bool {
+	if ship.Length > b.Size {
+		return false
+	}
+	for attempt := 0; attempt < placementAttempts; attempt++ {
+		dRow, dCol := 0, 1
+		if rng.Intn(2) == 0 {
+			dRow, dCol = 1, 0
+		}
+		row := 1 + rng.Intn(b.Size-dRow*(ship.Length-1))
+		col := rng.Intn(b.Size - dCol*(ship.Length-1))
+		coords := line(row, col, dRow, dCol, ship.Length)
+		if p.NoTouching && b.touches(coords) {
+			continue
+		}
+		if b.Place(ship.Type, coords) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Would a ship on these squares touch one already on the board?
+func (b *Board) touches(coords []Shot) bool {
+	for _, s := range coords {
+		row, col := s.CoordX, s.col()
+		for dRow := -1; dRow <= 1; dRow++ {
+			for dCol := -1; dCol <= 1; dCol++ {
+				if _, ok := b.squares[square(row+dRow, col+dCol)]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ShipSquares - where a ship sits on this board
+func (b *Board) ShipSquares(shipType string) []Shot {
+	return b.ships[shipType]
+}
+
+// length squares starting at row/col (1-based row, 0-based column) in one direction
+func line(row, col, dRow, dCol, length int) []Shot {
+	var coords []Shot
+	for i := 0; i < length; i++ {
+		coords = append(coords, square(row+i*dRow, col+i*dCol))
+	}
+	return coords
+}
+
+// The square at row (1-based) and column index (0-based)
+func square(row, col int) Shot {
+	if col < 0 || col >= len(alphabet) {
+		return Shot{CoordX: row}
+	}
+	return Shot{CoordX: row, CoordY: alphabet[col : col+1]}
+}
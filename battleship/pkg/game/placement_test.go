@@ -0,0 +1,96 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// A random fleet on every size of board, with and without gaps between the ships
+func TestRandomBoard(t *testing.T) {
+	for size := MinSize; size <= MaxSize; size++ {
+		for _, p := range []Placement{{}, {NoTouching: true}} {
+			rng := rand.New(rand.NewSource(int64(size)))
+			b, err := RandomBoard(size, DefaultFleet, rng, p)
+			if err != nil {
+				t.Fatalf("RandomBoard(%d, %+v) = %v", size, p, err)
+			}
+			owner := map[Shot]string{}
+			for _, ship := range DefaultFleet {
+				squares := b.ShipSquares(ship.Type)
+				if err = NewBoard(size, DefaultFleet).ValidatePlacement(ship.Length, squares); err != nil {
+					t.Errorf("%dx%d %+v: the %s on %v: %v", size, size, p, ship.Type, squares, err)
+				}
+				for _, s := range squares {
+					if other, ok := owner[s]; ok {
+						t.Errorf("%dx%d %+v: the %s and the %s both on %s", size, size, p, other, ship.Type, s)
+					}
+					owner[s] = ship.Type
+				}
+			}
+			if !p.NoTouching {
+				continue
+			}
+			for s, shipType := range owner {
+				for dRow := -1; dRow <= 1; dRow++ {
+					for dCol := -1; dCol <= 1; dCol++ {
+						if other, ok := owner[square(s.CoordX+dRow, s.col()+dCol)]; ok && other != shipType {
+							t.Errorf("%dx%d: the %s on %s touches the %s", size, size, shipType, s, other)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// The same seed places the same fleet
+func TestRandomBoardIsSeeded(t *testing.T) {
+	place := func() string {
+		b, err := RandomBoard(DefaultSize, DefaultFleet, rand.New(rand.NewSource(42)), Placement{NoTouching: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var all []Shot
+		for _, ship := range DefaultFleet {
+			all = append(all, b.ShipSquares(ship.Type)...)
+		}
+		return fmt.Sprint(all)
+	}
+	if first, second := place(), place(); first != second {
+		t.Errorf("seed 42 placed %s, then %s", first, second)
+	}
+}
+
+func TestRandomBoardNoRoom(t *testing.T) {
+	// Eight ships as long as the board is wide: they fit side by side, but not with gaps
+	var crowded Fleet
+	for i := 0; i < MinSize; i++ {
+		letter := alphabet[i : i+1]
+		crowded = append(crowded, Ship{Type: "ship" + letter, Name: "Ship " + letter, Abbreviation: letter, Length: MinSize})
+	}
+	tests := []struct {
+		name  string
+		fleet Fleet
+		p     Placement
+		ok    bool
+	}{
+		{"gaps around every ship", crowded, Placement{NoTouching: true}, false},
+		{"longer than the board", Fleet{{Type: "ark", Name: "Ark", Abbreviation: "A", Length: MinSize + 1}}, Placement{}, false},
+		{"the default fleet", DefaultFleet, Placement{NoTouching: true}, true},
+	}
+	for _, tt := range tests {
+		_, err := RandomBoard(MinSize, tt.fleet, rand.New(rand.NewSource(1)), tt.p)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		var pe *PlacementError
+		if !errors.Is(err, ErrNoRoom) || !errors.As(err, &pe) || pe.Size != MinSize || pe.NoTouching != tt.p.NoTouching || pe.Ship.Name == "" {
+			t.Errorf("%s: %v, want a PlacementError saying which ship didn't fit", tt.name, err)
+		}
+	}
+}
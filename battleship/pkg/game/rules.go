@@ -0,0 +1,104 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrWrongShotCount = errors.New("game: wrong number of shots for this turn")
+
+// Rule variants
+// - Classic: one shot per turn
+// - Salvo: a volley of shots per turn, either a fixed number or one for
+//   every ship the shooter still has afloat
+const (
+	Classic = "classic"
+	Salvo   = "salvo"
+)
+
+// Rules - how a battle is played
+// - SalvoShots of 0 means "one shot per surviving ship"
+// - TurnTime is how long a player has to answer a challenge or take a turn; 0 is no limit
+type Rules struct {
+	Mode       string
+	SalvoShots int
+	TurnTime   time.Duration
+}
+
+// Turn clock limits
+// - A minute is about as fast as a live game goes; two weeks is a slow correspondence game
+const (
+	MinTurnTime = time.Minute
+	MaxTurnTime = 14 * 24 * time.Hour
+)
+
+// ParseRules - build Rules from what a player picked when issuing a challenge
+// - A salvo is at most one shot for every ship in the fleet
+func ParseRules(mode string, salvoShots int, fleet Fleet) (Rules, error) {
+	switch mode {
+	case "", Classic:
+		return Rules{Mode: Classic}, nil
+	case Salvo:
+		if salvoShots < 0 || salvoShots > len(fleet) {
+			return Rules{}, fmt.Errorf("game: a salvo is 0 (one shot per ship) to %d shots, not %d", len(fleet), salvoShots)
+		}
+		return Rules{Mode: Salvo, SalvoShots: salvoShots}, nil
+	}
+	return Rules{}, fmt.Errorf("game: unknown rules %q", mode)
+}
+
+// ParseTurnTime - the turn clock a challenger picked: a Go duration ("90s", "5m", "24h"),
+// a number of days ("3d"), or "" / "0" for no limit
+func ParseTurnTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	var d time.Duration
+	var err error
+	if strings.HasSuffix(s, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("game: %q is not a turn time", s)
+	}
+	if d < MinTurnTime || d > MaxTurnTime {
+		return 0, errors.New("game: a turn time has to be between a minute and two weeks")
+	}
+	return d, nil
+}
+
+// ShotsPerTurn - how many shots the shooter fires this turn
+// - Never more than the target board has squares left to fire on (see Board.Unstruck),
+//   or a late salvo could never be fired at all
+func (r Rules) ShotsPerTurn(survivingShips, unstruck int) int {
+	shots := 1
+	switch {
+	case r.Mode != Salvo:
+	case r.SalvoShots > 0:
+		shots = r.SalvoShots
+	default:
+		shots = survivingShips
+	}
+	if shots > unstruck {
+		shots = unstruck
+	}
+	return shots
+}
+
+func (r Rules) String() string {
+	if r.Mode != Salvo {
+		return "Classic"
+	}
+	if r.SalvoShots > 0 {
+		return fmt.Sprintf("Salvo (%d shots)", r.SalvoShots)
+	}
+	return "Salvo (one shot per ship)"
+}
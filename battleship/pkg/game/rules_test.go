@@ -0,0 +1,74 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		mode       string
+		salvoShots int
+		want       Rules
+		ok         bool
+	}{
+		{"", 0, Rules{Mode: Classic}, true},
+		{Classic, 3, Rules{Mode: Classic}, true},
+		{Salvo, 0, Rules{Mode: Salvo}, true},
+		{Salvo, 5, Rules{Mode: Salvo, SalvoShots: 5}, true},
+		{Salvo, 6, Rules{}, false},
+		{Salvo, -1, Rules{}, false},
+		{"blitz", 0, Rules{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRules(tt.mode, tt.salvoShots, DefaultFleet)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseRules(%q, %d) = %+v, %v; want %+v (ok %v)", tt.mode, tt.salvoShots, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestShotsPerTurn(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     Rules
+		surviving int
+		unstruck  int
+		want      int
+	}{
+		{"classic", Rules{Mode: Classic}, 5, 100, 1},
+		{"fixed salvo", Rules{Mode: Salvo, SalvoShots: 3}, 1, 100, 3},
+		{"one per ship", Rules{Mode: Salvo}, 4, 100, 4},
+		{"fixed salvo, few squares left", Rules{Mode: Salvo, SalvoShots: 5}, 5, 2, 2},
+		{"one per ship, few squares left", Rules{Mode: Salvo}, 5, 3, 3},
+		{"classic, last square", Rules{Mode: Classic}, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.rules.ShotsPerTurn(tt.surviving, tt.unstruck); got != tt.want {
+			t.Errorf("%s: ShotsPerTurn(%d, %d) = %d, want %d", tt.name, tt.surviving, tt.unstruck, got, tt.want)
+		}
+	}
+}
+
+// Late in a salvo the board runs out of squares before the shooter runs out of ships
+func TestShotsPerTurnFillsTheBoard(t *testing.T) {
+	b := NewBoard(MinSize, DefaultFleet)
+	for row := 1; row <= b.Size; row++ {
+		for _, col := range Columns(b.Size) {
+			if row == b.Size && col >= "F" {
+				continue
+			}
+			if _, err := b.Strike(Shot{CoordX: row, CoordY: col}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	n := Rules{Mode: Salvo}.ShotsPerTurn(len(DefaultFleet), b.Unstruck())
+	if n != 3 {
+		t.Fatalf("ShotsPerTurn with %d squares left = %d, want 3", b.Unstruck(), n)
+	}
+	shots := Aim(RandomFire{}, b.Knowledge(), n, rand.New(rand.NewSource(1)))
+	if _, err := b.Volley(shots); err != nil || len(shots) != n {
+		t.Errorf("the computer aimed %d shots (%v), want %d", len(shots), err, n)
+	}
+}
@@ -0,0 +1,161 @@
+// Package matchmaking - pair up players who are waiting for a battle
+// - A player joins the queue with a ticket: the board they'll play on and the rules they want
+// - Two tickets match when the board sizes and rules are the same and the ratings are close
+// - "Close" widens the longer a player waits, so nobody waits forever for a perfect match
+// - Nothing in here knows about the database; the queue lives as long as the process
+package matchmaking
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+)
+
+// Rating bands
+// - A new ticket only matches someone within InitialBand rating points
+// - The band grows by BandStep every BandEvery the ticket waits, up to MaxBand
+const (
+	InitialBand = 100
+	BandStep    = 50
+	BandEvery   = 15 * time.Second
+	MaxBand     = 800
+)
+
+// Ticket - one player waiting for an opponent
+type Ticket struct {
+	PlayerID   int
+	ScreenName string
+	BoardID    int
+	BoardSize  int
+	Rating     int
+	Rules      game.Rules
+	Joined     time.Time
+}
+
+// Band - how far apart two ratings may be for a ticket that has waited this long
+func Band(wait time.Duration) int {
+	band := InitialBand
+	if wait > 0 {
+		band += BandStep * int(wait/BandEvery)
+	}
+	if band > MaxBand {
+		band = MaxBand
+	}
+	return band
+}
+
+// Match - two tickets paired into a battle
+// - Challenger waited longer; they get to go second, just like a challenger would
+type Match struct {
+	Challenger Ticket
+	Opponent   Ticket
+}
+
+// Queue - the players waiting for an opponent, safe to share between goroutines
+type Queue struct {
+	mu      sync.Mutex
+	tickets map[int]Ticket
+}
+
+// NewQueue - an empty queue
+func NewQueue() *Queue {
+	return &Queue{tickets: map[int]Ticket{}}
+}
+
+// Join - put a ticket in the queue
+// - A player is only ever in the queue once; joining again replaces their ticket,
+//   but keeps their place (Joined) if they still want the same game
+func (q *Queue) Join(t Ticket) Ticket {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if old, ok := q.tickets[t.PlayerID]; ok && old.BoardSize == t.BoardSize && old.Rules == t.Rules {
+		t.Joined = old.Joined
+	}
+	q.tickets[t.PlayerID] = t
+	return t
+}
+
+// Leave - take a player out of the queue; false if they weren't in it
+func (q *Queue) Leave(playerID int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	_, ok := q.tickets[playerID]
+	delete(q.tickets, playerID)
+	return ok
+}
+
+// Waiting - a player's ticket, if they are in the queue
+func (q *Queue) Waiting(playerID int) (Ticket, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	t, ok := q.tickets[playerID]
+	return t, ok
+}
+
+// Len - how many players are waiting
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.tickets)
+}
+
+// Pair - take every match that can be made right now out of the queue
+// - Whoever has waited longest is matched first, with the closest rating that
+//   fits the band of the one who has waited longer
+func (q *Queue) Pair(now time.Time) []Match {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	waiting := make([]Ticket, 0, len(q.tickets))
+	for _, t := range q.tickets {
+		waiting = append(waiting, t)
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		if !waiting[i].Joined.Equal(waiting[j].Joined) {
+			return waiting[i].Joined.Before(waiting[j].Joined)
+		}
+		return waiting[i].PlayerID < waiting[j].PlayerID
+	})
+
+	var matches []Match
+	paired := map[int]bool{}
+	for i, a := range waiting {
+		if paired[a.PlayerID] {
+			continue
+		}
+		band := Band(now.Sub(a.Joined))
+		best := -1
+		for j := i + 1; j < len(waiting); j++ {
+			b := waiting[j]
+			if paired[b.PlayerID] || b.BoardSize != a.BoardSize || b.Rules != a.Rules {
+				continue
+			}
+			gap := abs(a.Rating - b.Rating)
+			if gap <= band && (best < 0 || gap < abs(a.Rating-waiting[best].Rating)) {
+				best = j
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		b := waiting[best]
+		paired[a.PlayerID], paired[b.PlayerID] = true, true
+		delete(q.tickets, a.PlayerID)
+		delete(q.tickets, b.PlayerID)
+		matches = append(matches, Match{Challenger: a, Opponent: b})
+	}
+	return matches
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+)
+
+var (
+	start   = time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	classic = game.Rules{Mode: game.Classic}
+)
+
+func ticket(playerID, rating int, joined time.Time) Ticket {
+	return Ticket{PlayerID: playerID, BoardSize: game.DefaultSize, Rating: rating, Rules: classic, Joined: joined}
+}
+
+func TestBand(t *testing.T) {
+	tests := []struct {
+		wait time.Duration
+		want int
+	}{
+		{0, InitialBand},
+		{-time.Second, InitialBand},
+		{BandEvery - time.Second, InitialBand},
+		{BandEvery, InitialBand + BandStep},
+		{4 * BandEvery, InitialBand + 4*BandStep},
+		{time.Hour, MaxBand},
+	}
+	for _, tt := range tests {
+		if got := Band(tt.wait); got != tt.want {
+			t.Errorf("Band(%v) = %d, want %d", tt.wait, got, tt.want)
+		}
+	}
+}
+
+// Two players 400 points apart only meet once the one who waited longer has
+// waited long enough for the band to reach 400
+func TestPairWidensTheBand(t *testing.T) {
+	tests := []struct {
+		waited  time.Duration
+		matched bool
+	}{
+		{0, false},
+		{5 * BandEvery, false},
+		{6 * BandEvery, true},
+	}
+	for _, tt := range tests {
+		q := NewQueue()
+		q.Join(ticket(1, 1500, start))
+		q.Join(ticket(2, 1900, start.Add(tt.waited)))
+		matches := q.Pair(start.Add(tt.waited))
+		if (len(matches) == 1) != tt.matched {
+			t.Errorf("after %v: %d matches, want matched %v", tt.waited, len(matches), tt.matched)
+			continue
+		}
+		if tt.matched && (matches[0].Challenger.PlayerID != 1 || matches[0].Opponent.PlayerID != 2 || q.Len() != 0) {
+			t.Errorf("after %v: %+v with %d left waiting, want 1 challenging 2 and nobody left", tt.waited, matches[0], q.Len())
+		}
+	}
+}
+
+func TestPairPicksTheClosestRating(t *testing.T) {
+	q := NewQueue()
+	q.Join(ticket(1, 1500, start))
+	q.Join(ticket(2, 1580, start.Add(time.Second)))
+	q.Join(ticket(3, 1520, start.Add(2*time.Second)))
+	q.Join(ticket(4, 1510, start.Add(3*time.Second)))
+	big := ticket(5, 1500, start)
+	big.BoardSize = 12
+	q.Join(big)
+	salvo := ticket(6, 1500, start)
+	salvo.Rules = game.Rules{Mode: game.Salvo}
+	q.Join(salvo)
+
+	matches := q.Pair(start.Add(3 * time.Second))
+	want := [][2]int{{1, 4}, {2, 3}}
+	if len(matches) != len(want) {
+		t.Fatalf("%d matches %+v, want %v", len(matches), matches, want)
+	}
+	for i, m := range matches {
+		if m.Challenger.PlayerID != want[i][0] || m.Opponent.PlayerID != want[i][1] {
+			t.Errorf("match %d = %d vs %d, want %d vs %d", i, m.Challenger.PlayerID, m.Opponent.PlayerID, want[i][0], want[i][1])
+		}
+	}
+	// a different board size or different rules is a different game
+	for _, playerID := range []int{5, 6} {
+		if _, waiting := q.Waiting(playerID); !waiting {
+			t.Errorf("player %d was taken out of the queue", playerID)
+		}
+	}
+}
+
+func TestNobodyPlaysThemselves(t *testing.T) {
+	q := NewQueue()
+	q.Join(ticket(1, 1500, start))
+	q.Join(ticket(1, 1500, start.Add(time.Minute)))
+	if q.Len() != 1 {
+		t.Fatalf("joining twice left %d tickets, want 1", q.Len())
+	}
+	if matches := q.Pair(start.Add(time.Hour)); len(matches) != 0 {
+		t.Errorf("Pair = %+v, want no matches for one player", matches)
+	}
+	if ticket, _ := q.Waiting(1); !ticket.Joined.Equal(start) {
+		t.Errorf("joining again for the same game moved the ticket to %v, want it kept at %v", ticket.Joined, start)
+	}
+}
+
+func TestLeave(t *testing.T) {
+	q := NewQueue()
+	q.Join(ticket(1, 1500, start))
+	q.Join(ticket(2, 1500, start))
+	if !q.Leave(1) || q.Leave(1) {
+		t.Error("Leave = false the first time or true the second, want the other way round")
+	}
+	if _, waiting := q.Waiting(1); waiting || q.Len() != 1 {
+		t.Errorf("after Leave: player 1 waiting %v, %d in the queue", waiting, q.Len())
+	}
+	if matches := q.Pair(start.Add(time.Hour)); len(matches) != 0 {
+		t.Errorf("Pair after Leave = %+v, want no matches", matches)
+	}
+}
@@ -0,0 +1,195 @@
+// Package migrate - numbered schema changes, applied in order and recorded
+// - Each database keeps the versions it has applied in schema_version
+// - A step is not all or nothing: MySQL commits every CREATE, ALTER and DROP
+//   the moment it runs, whatever transaction it is in.  So each statement runs
+//   in a transaction of its own along with a schema_progress row saying how far
+//   the step got, and a step that failed part way picks up after the last
+//   statement that worked the next time it is tried
+// - The SQL lives in the binary (see sqlite3.Migrations and mysql.Migrations)
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrUnknownVersion = errors.New("migrate: no such schema version")
+
+// Migration - one numbered change to the schema
+// - Up and Down are the SQL statements, one to each string, run in order
+//   (nothing splits them, so a string may hold a ";" of its own)
+// - Present is for databases made before schema_version existed: a query that
+//   counts more than 0 when this change is already there
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+	Present string
+}
+
+// Step - a migration that was just applied (or rolled back)
+type Step struct {
+	Migration
+	Down bool
+}
+
+func (s Step) String() string {
+	direction := "up"
+	if s.Down {
+		direction = "down"
+	}
+	return fmt.Sprintf("%s %d (%s)", direction, s.Version, s.Name)
+}
+
+// Latest - the version the last migration brings a database to
+func Latest(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Version - the schema version of a database
+// - A database from before schema_version is checked against each migration's
+//   Present query and recorded as being as far along as it is
+func Version(db *sql.DB, migrations []Migration) (int, error) {
+	stmt := `CREATE TABLE IF NOT EXISTS schema_version
+		(version INTEGER NOT NULL PRIMARY KEY, name VARCHAR(255), applied DATETIME)`
+	if _, err := db.Exec(stmt); err != nil {
+		return 0, err
+	}
+	stmt = `CREATE TABLE IF NOT EXISTS schema_progress
+		(version INTEGER NOT NULL, down INTEGER NOT NULL, statements INTEGER NOT NULL, PRIMARY KEY (version, down))`
+	if _, err := db.Exec(stmt); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT version FROM schema_version ORDER BY version DESC LIMIT 1`).Scan(&version)
+	if err == nil {
+		return version, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	for _, m := range migrations {
+		if m.Present == "" {
+			break
+		}
+		var n int
+		if err = db.QueryRow(m.Present).Scan(&n); err != nil {
+			return 0, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if n == 0 {
+			break
+		}
+		_, err = db.Exec(`INSERT INTO schema_version (version, name, applied) VALUES (?, ?, ?)`, m.Version, m.Name, time.Now())
+		if err != nil {
+			return 0, err
+		}
+		version = m.Version
+	}
+	return version, nil
+}
+
+// To - migrate a database up or down to the target version
+// - Returns the steps taken, even when a later step fails
+func To(db *sql.DB, migrations []Migration, target int) ([]Step, error) {
+	if target < 0 || target > Latest(migrations) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	current, err := Version(db, migrations)
+	if err != nil {
+		return nil, err
+	}
+	var steps []Step
+	if target >= current {
+		for _, m := range migrations {
+			if m.Version <= current || m.Version > target {
+				continue
+			}
+			if err = apply(db, m, false, m.Up, `INSERT INTO schema_version (version, name, applied) VALUES (?, ?, ?)`, m.Version, m.Name, time.Now()); err != nil {
+				return steps, err
+			}
+			steps = append(steps, Step{Migration: m})
+		}
+		return steps, nil
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		if err = apply(db, m, true, m.Down, `DELETE FROM schema_version WHERE version = ?`, m.Version); err != nil {
+			return steps, err
+		}
+		steps = append(steps, Step{Migration: m, Down: true})
+	}
+	return steps, nil
+}
+
+// apply - run one direction of a migration and record it
+// - Statements an earlier attempt got through (see schema_progress) aren't run again
+func apply(db *sql.DB, m Migration, down bool, statements []string, record string, args ...interface{}) error {
+	var done int
+	err := db.QueryRow(`SELECT statements FROM schema_progress WHERE version = ? AND down = ?`, m.Version, direction(down)).Scan(&done)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	for i, stmt := range statements {
+		n := i + 1
+		if n <= done {
+			continue
+		}
+		err = inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("migration %d (%s), statement %d: %w", m.Version, m.Name, n, err)
+			}
+			return setProgress(tx, m.Version, down, n)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// The step is done: record it and forget how far it got
+	return inTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(record, args...); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM schema_progress WHERE version = ? AND down = ?`, m.Version, direction(down))
+		return err
+	})
+}
+
+// setProgress - a step has got through its first n statements
+func setProgress(tx *sql.Tx, version int, down bool, n int) error {
+	_, err := tx.Exec(`DELETE FROM schema_progress WHERE version = ? AND down = ?`, version, direction(down))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO schema_progress (version, down, statements) VALUES (?, ?, ?)`, version, direction(down), n)
+	return err
+}
+
+// direction - how schema_progress tells up (0) from down (1)
+func direction(down bool) int {
+	if down {
+		return 1
+	}
+	return 0
+}
+
+func inTx(db *sql.DB, f func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
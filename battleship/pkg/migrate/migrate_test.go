@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openDB(t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+var testMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "players",
+		Up:      []string{`CREATE TABLE Players (name TEXT)`},
+		Down:    []string{`DROP TABLE Players`},
+	},
+	{
+		Version: 2,
+		Name:    "boards",
+		Up: []string{
+			`CREATE TABLE Boards (name TEXT)`,
+			`INSERT INTO Boards (name) VALUES ('first; and only')`,
+		},
+		Down: []string{`DROP TABLE Boards`},
+	},
+}
+
+// The same, except the second statement of step 2 fails until Ships exists
+var failingMigrations = []Migration{
+	testMigrations[0],
+	{
+		Version: 2,
+		Name:    "boards",
+		Up: []string{
+			`CREATE TABLE Boards (name TEXT)`,
+			`INSERT INTO Ships (name) VALUES ('carrier')`,
+			`INSERT INTO Boards (name) VALUES ('first; and only')`,
+		},
+		Down: []string{`DROP TABLE Boards`},
+	},
+}
+
+func TestTo(t *testing.T) {
+	db, cleanup := openDB(t)
+	defer cleanup()
+
+	tests := []struct {
+		target int
+		steps  int
+	}{
+		{2, 2},
+		{2, 0},
+		{0, 2},
+		{1, 1},
+	}
+	for _, tt := range tests {
+		steps, err := To(db, testMigrations, tt.target)
+		if err != nil || len(steps) != tt.steps {
+			t.Fatalf("To(%d) = %v, %v; want %d steps", tt.target, steps, err, tt.steps)
+		}
+		if v, err := Version(db, testMigrations); v != tt.target || err != nil {
+			t.Errorf("after To(%d), Version = %d, %v", tt.target, v, err)
+		}
+	}
+	if _, err := To(db, testMigrations, 3); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("To(a version that doesn't exist) = %v, want ErrUnknownVersion", err)
+	}
+}
+
+func TestToPicksUpWhereAStepFailed(t *testing.T) {
+	db, cleanup := openDB(t)
+	defer cleanup()
+
+	steps, err := To(db, failingMigrations, 2)
+	if err == nil || len(steps) != 1 {
+		t.Fatalf("To(2) = %v, %v; want the first step and an error from the second", steps, err)
+	}
+	if v, _ := Version(db, testMigrations); v != 1 {
+		t.Fatalf("Version after a step failed = %d, want 1", v)
+	}
+	var done int
+	err = db.QueryRow(`SELECT statements FROM schema_progress WHERE version = 2 AND down = 0`).Scan(&done)
+	if err != nil || done != 1 {
+		t.Fatalf("schema_progress = %d, %v; want 1 statement of step 2", done, err)
+	}
+
+	// Running it again doesn't create Boards a second time
+	if _, err = db.Exec(`CREATE TABLE Ships (name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if steps, err = To(db, failingMigrations, 2); err != nil || len(steps) != 1 {
+		t.Fatalf("To(2) again = %v, %v", steps, err)
+	}
+	var boards, progress int
+	var name string
+	db.QueryRow(`SELECT COUNT(*), MAX(name) FROM Boards`).Scan(&boards, &name)
+	db.QueryRow(`SELECT COUNT(*) FROM schema_progress`).Scan(&progress)
+	if boards != 1 || name != "first; and only" || progress != 0 {
+		t.Errorf("after the step finished: %d boards (%q) and %d schema_progress rows, want 1 and 0", boards, name, progress)
+	}
+	if v, _ := Version(db, testMigrations); v != 2 {
+		t.Errorf("Version = %d, want 2", v)
+	}
+}
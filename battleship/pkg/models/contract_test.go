@@ -0,0 +1,890 @@
+package models_test
+
+// The contract every implementation of the repositories keeps
+// - Each test runs against memory, sqlite3 (a new file in a temp directory)
+//   and, when BATTLESHIP_TEST_MYSQL_DSN is set, MySQL
+// - The MySQL database is migrated down to nothing and back up for every
+//   test, so never point it at one you want to keep
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/migrate"
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/models/memory"
+	"github.com/519seven/cs610/battleship/pkg/models/mysql"
+	"github.com/519seven/cs610/battleship/pkg/models/sqldb"
+	"github.com/519seven/cs610/battleship/pkg/models/sqlite3"
+)
+
+// repositories - one implementation of everything the web application uses
+type repositories struct {
+	battles       models.BattleRepository
+	boards        models.BoardRepository
+	messages      models.MessageRepository
+	notifications models.NotificationRepository
+	players       models.PlayerRepository
+	positions     models.PositionRepository
+	ratings       models.RatingRepository
+	ships         models.ShipRepository
+	strikes       models.StrikeRepository
+	tokens        models.TokenRepository
+	webhooks      models.WebhookRepository
+	// db - the database underneath, for the SQL implementations
+	db *sql.DB
+}
+
+type backend struct {
+	name string
+	// open - a fresh, empty set of repositories and how to tidy up after them
+	open func(t *testing.T) (*repositories, func())
+}
+
+func backends() []backend {
+	list := []backend{
+		{"memory", openMemory},
+		{"sqlite3", openSQLite},
+	}
+	if os.Getenv("BATTLESHIP_TEST_MYSQL_DSN") != "" {
+		list = append(list, backend{"mysql", openMySQL})
+	}
+	return list
+}
+
+func openMemory(t *testing.T) (*repositories, func()) {
+	store := memory.NewStore()
+	return &repositories{
+		battles:       &memory.BattleModel{Store: store},
+		boards:        &memory.BoardModel{Store: store},
+		messages:      &memory.MessageModel{Store: store},
+		notifications: &memory.NotificationModel{Store: store},
+		players:       &memory.PlayerModel{Store: store},
+		positions:     &memory.PositionModel{Store: store, Fleet: game.DefaultFleet},
+		ratings:       &memory.RatingModel{Store: store},
+		ships:         &memory.ShipModel{Store: store},
+		strikes:       &memory.StrikeModel{Store: store},
+		tokens:        &memory.TokenModel{Store: store},
+		webhooks:      &memory.WebhookModel{Store: store},
+	}, func() {}
+}
+
+func openSQLite(t *testing.T) (*repositories, func()) {
+	dir, err := ioutil.TempDir("", "battleship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sqlite3.Open(filepath.Join(dir, "battleship.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	if _, err = migrate.To(db, sqlite3.Migrations, migrate.Latest(sqlite3.Migrations)); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	r := sqlRepositories(sqldb.New(db, sqlite3.Dialect, game.DefaultFleet))
+	r.db = db
+	return r, cleanup
+}
+
+func openMySQL(t *testing.T) (*repositories, func()) {
+	db, err := mysql.Open(os.Getenv("BATTLESHIP_TEST_MYSQL_DSN"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []int{0, migrate.Latest(mysql.Migrations)} {
+		if _, err = migrate.To(db, mysql.Migrations, version); err != nil {
+			db.Close()
+			t.Fatal(err)
+		}
+	}
+	r := sqlRepositories(sqldb.New(db, mysql.Dialect, game.DefaultFleet))
+	r.db = db
+	return r, func() { db.Close() }
+}
+
+func sqlRepositories(m *sqldb.Models) *repositories {
+	return &repositories{
+		battles:       m.Battles,
+		boards:        m.Boards,
+		messages:      m.Messages,
+		notifications: m.Notifications,
+		players:       m.Players,
+		positions:     m.Positions,
+		ratings:       m.Ratings,
+		ships:         m.Ships,
+		strikes:       m.Strikes,
+		tokens:        m.Tokens,
+		webhooks:      m.Webhooks,
+	}
+}
+
+// forEachBackend - run a test against every implementation, each with its own fresh store
+func forEachBackend(t *testing.T, test func(t *testing.T, r *repositories)) {
+	for _, b := range backends() {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			r, cleanup := b.open(t)
+			defer cleanup()
+			test(t, r)
+		})
+	}
+}
+
+const password = "B0mbs4way:("
+
+// The same fleet as the classic one, with a longer destroyer
+var longDestroyer = game.Fleet{
+	{Type: "carrier", Name: "Carrier", Abbreviation: "C", Length: 5},
+	{Type: "battleship", Name: "Battleship", Abbreviation: "B", Length: 4},
+	{Type: "cruiser", Name: "Cruiser", Abbreviation: "R", Length: 3},
+	{Type: "submarine", Name: "Submarine", Abbreviation: "S", Length: 3},
+	{Type: "destroyer", Name: "Destroyer", Abbreviation: "D", Length: 3},
+}
+
+// Every board in these tests has its ships down the first rows of every other column
+var layout = map[string][]string{
+	"carrier":    {"1,A", "2,A", "3,A", "4,A", "5,A"},
+	"battleship": {"1,C", "2,C", "3,C", "4,C"},
+	"cruiser":    {"1,E", "2,E", "3,E"},
+	"submarine":  {"1,G", "2,G", "3,G"},
+	"destroyer":  {"1,I", "2,I"},
+}
+
+// layoutShots - every square of layout, in a fixed order
+func layoutShots(t *testing.T) []game.Shot {
+	t.Helper()
+	var types []string
+	for shipType := range layout {
+		types = append(types, shipType)
+	}
+	sort.Strings(types)
+	var shots []game.Shot
+	for _, shipType := range types {
+		for _, rc := range layout[shipType] {
+			s, err := game.ParseCoordinate(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shots = append(shots, s)
+		}
+	}
+	return shots
+}
+
+// Squares nothing in layout sits on (rows 6-10 of columns A-J)
+func openWater(n int) []game.Shot {
+	var shots []game.Shot
+	for _, col := range game.Columns(game.DefaultSize) {
+		for row := 6; row <= game.DefaultSize && len(shots) < n; row++ {
+			shots = append(shots, game.Shot{CoordX: row, CoordY: col})
+		}
+	}
+	return shots
+}
+
+func addPlayer(t *testing.T, r *repositories, screenName string) int {
+	t.Helper()
+	id, err := r.players.Insert(screenName, "", password)
+	if err != nil {
+		t.Fatalf("Insert player %s: %v", screenName, err)
+	}
+	return id
+}
+
+func addBoard(t *testing.T, r *repositories, playerID int, name string, size int) int {
+	t.Helper()
+	boardID, err := r.boards.Create(playerID, name, size)
+	if err != nil {
+		t.Fatalf("Create board %s: %v", name, err)
+	}
+	for shipType, coords := range layout {
+		if _, err = r.boards.Insert(playerID, boardID, shipType, coords); err != nil {
+			t.Fatalf("Insert %s on board %s: %v", shipType, name, err)
+		}
+	}
+	return boardID
+}
+
+// startBattle - two players, their boards and an accepted challenge between them
+// - The challenger is player1; the opponent (player2) fires first
+func startBattle(t *testing.T, r *repositories, rules game.Rules) (player1, player2, battleID int) {
+	t.Helper()
+	if err := r.ships.Sync(game.DefaultFleet); err != nil {
+		t.Fatal(err)
+	}
+	player1 = addPlayer(t, r, "amy")
+	player2 = addPlayer(t, r, "ben")
+	board1 := addBoard(t, r, player1, "amy's", game.DefaultSize)
+	board2 := addBoard(t, r, player2, "ben's", game.DefaultSize)
+	battleID, err := r.battles.Create(player1, board1, player2, "first", rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.battles.Accept(player2, board2, battleID); err != nil {
+		t.Fatal(err)
+	}
+	return player1, player2, battleID
+}
+
+func TestPlayers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		amy := addPlayer(t, r, "amy")
+		ben := addPlayer(t, r, "ben")
+		if amy == ben {
+			t.Fatalf("two players got the same ID %d", amy)
+		}
+		if _, err := r.players.Insert("amy", "", password); !errors.Is(err, models.ErrDuplicateScreenName) {
+			t.Errorf("Insert a second amy = %v, want ErrDuplicateScreenName", err)
+		}
+
+		tests := []struct {
+			screenName, password string
+			want                 int
+			err                  error
+		}{
+			{"amy", password, amy, nil},
+			{"ben", password, ben, nil},
+			{"amy", "wrong", 0, models.ErrInvalidCredentials},
+			{"nobody", password, 0, models.ErrInvalidCredentials},
+		}
+		for _, tt := range tests {
+			id, err := r.players.Authenticate(tt.screenName, tt.password)
+			if id != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("Authenticate(%s, %s) = %d, %v; want %d, %v", tt.screenName, tt.password, id, err, tt.want, tt.err)
+			}
+		}
+
+		p, err := r.players.Get(ben)
+		if err != nil || p.ScreenName != "ben" {
+			t.Errorf("Get(%d) = %+v, %v; want ben", ben, p, err)
+		}
+		if p, err = r.players.Get(ben + 100); !errors.Is(err, models.ErrNoRecord) || p == nil || p.ID != 0 {
+			t.Errorf("Get(no such player) = %+v, %v; want an empty player and ErrNoRecord", p, err)
+		}
+		// sqlite3 comes with sample players of its own
+		others, err := r.players.List(amy, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listed := map[int]bool{}
+		for _, p := range others {
+			listed[p.ID] = true
+		}
+		if listed[amy] || !listed[ben] {
+			t.Errorf("List(everyone but amy) = %v; want ben and not amy", listed)
+		}
+	})
+}
+
+func TestShipsSync(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		lengths := func() map[string]int {
+			ships, err := r.ships.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := map[string]int{}
+			for _, s := range ships {
+				m[s.Title] = s.Length
+			}
+			return m
+		}
+		steps := []struct {
+			name      string
+			fleet     game.Fleet
+			destroyer int
+		}{
+			{"empty", game.DefaultFleet, 2},
+			{"again", game.DefaultFleet, 2},
+			{"longer destroyer", longDestroyer, 3},
+			{"back to classic", game.DefaultFleet, 2},
+		}
+		for _, step := range steps {
+			if err := r.ships.Sync(step.fleet); err != nil {
+				t.Fatalf("%s: Sync = %v", step.name, err)
+			}
+			got := lengths()
+			if len(got) != len(step.fleet) || got["destroyer"] != step.destroyer {
+				t.Errorf("%s: ships are %v, want %d of them and a destroyer %d long", step.name, got, len(step.fleet), step.destroyer)
+			}
+		}
+
+		// Once a board is saved, only a fleet it fits will do
+		amy := addPlayer(t, r, "amy")
+		addBoard(t, r, amy, "amy's", game.DefaultSize)
+		mismatches := []struct {
+			name  string
+			fleet game.Fleet
+		}{
+			{"another length", longDestroyer},
+			{"a ship missing", game.DefaultFleet[:4]},
+		}
+		for _, m := range mismatches {
+			if err := r.ships.Sync(m.fleet); !errors.Is(err, models.ErrFleetMismatch) {
+				t.Errorf("Sync(%s) with a board saved = %v, want ErrFleetMismatch", m.name, err)
+			}
+		}
+		if got := lengths(); got["destroyer"] != 2 {
+			t.Errorf("a Sync that failed changed the destroyer to %d", got["destroyer"])
+		}
+		if err := r.ships.Sync(game.DefaultFleet); err != nil {
+			t.Errorf("Sync(the fleet the board was made with) = %v", err)
+		}
+	})
+}
+
+func TestBoards(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		if err := r.ships.Sync(game.DefaultFleet); err != nil {
+			t.Fatal(err)
+		}
+		amy := addPlayer(t, r, "amy")
+		ben := addPlayer(t, r, "ben")
+		boardID := addBoard(t, r, amy, "amy's", 12)
+
+		if _, err := r.boards.Create(amy, "amy's", game.DefaultSize); !errors.Is(err, models.ErrDuplicateBoardName) {
+			t.Errorf("Create a second board called amy's = %v, want ErrDuplicateBoardName", err)
+		}
+		if _, err := r.boards.Create(ben, "amy's", game.DefaultSize); err != nil {
+			t.Errorf("Create(ben's board called amy's) = %v; names only have to differ per player", err)
+		}
+		if _, err := r.boards.Insert(amy, boardID, "rowboat", []string{"9,J"}); !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("Insert a ship that isn't in Ships = %v, want ErrNoRecord", err)
+		}
+
+		b, err := r.boards.GetInfo(amy, boardID)
+		if err != nil || b.Title != "amy's" || b.BoardSize != 12 || b.PlayerID != amy || b.BattleID.Valid {
+			t.Errorf("GetInfo = %+v, %v", b, err)
+		}
+		if _, err = r.boards.GetInfo(ben, boardID); !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("GetInfo(someone else's board) = %v, want ErrNoRecord", err)
+		}
+
+		positions, err := r.boards.GetPositions(boardID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		squares := map[string]int{}
+		for _, p := range positions {
+			squares[p.ShipType.String]++
+		}
+		for _, ship := range game.DefaultFleet {
+			if squares[ship.Type] != ship.Length {
+				t.Errorf("GetPositions has %d squares of %s, want %d", squares[ship.Type], ship.Type, ship.Length)
+			}
+		}
+		if _, err = r.boards.GetPositions(0); !errors.Is(err, models.ErrMissingBoardID) {
+			t.Errorf("GetPositions(0) = %v, want ErrMissingBoardID", err)
+		}
+
+		if _, err = r.boards.Update(boardID, "renamed", amy); err != nil {
+			t.Errorf("Update = %v", err)
+		}
+		boards, err := r.boards.List(amy)
+		if err != nil || len(boards) != 1 || boards[0].Title != "renamed" {
+			t.Errorf("List after Update = %d boards, %v; want just renamed", len(boards), err)
+		}
+	})
+}
+
+func TestChallenges(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		if err := r.ships.Sync(game.DefaultFleet); err != nil {
+			t.Fatal(err)
+		}
+		amy := addPlayer(t, r, "amy")
+		ben := addPlayer(t, r, "ben")
+		amys := addBoard(t, r, amy, "amy's", game.DefaultSize)
+		bens := addBoard(t, r, ben, "ben's", game.DefaultSize)
+		big := addBoard(t, r, ben, "ben's big one", 12)
+		challenge := func() int {
+			t.Helper()
+			battleID, err := r.battles.Create(amy, amys, ben, "first", game.Rules{Mode: game.Classic})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return battleID
+		}
+
+		declined := challenge()
+		if err := r.battles.Decline(amy, declined); !errors.Is(err, models.ErrNotYourBattle) {
+			t.Errorf("the challenger declining = %v, want ErrNotYourBattle", err)
+		}
+		if err := r.battles.Decline(ben, declined); err != nil {
+			t.Errorf("Decline = %v", err)
+		}
+		if err := r.battles.Decline(ben, declined); !errors.Is(err, models.ErrBattleStatus) {
+			t.Errorf("Decline twice = %v, want ErrBattleStatus", err)
+		}
+		if _, err := r.battles.Accept(ben, bens, declined); !errors.Is(err, models.ErrBattleStatus) {
+			t.Errorf("Accept after Decline = %v, want ErrBattleStatus", err)
+		}
+
+		withdrawn := challenge()
+		if err := r.battles.Withdraw(ben, withdrawn); !errors.Is(err, models.ErrNotYourBattle) {
+			t.Errorf("the opponent withdrawing = %v, want ErrNotYourBattle", err)
+		}
+		if err := r.battles.Withdraw(amy, withdrawn); err != nil {
+			t.Errorf("Withdraw = %v", err)
+		}
+
+		accepted := challenge()
+		if _, err := r.battles.Accept(ben, big, accepted); !errors.Is(err, models.ErrBoardSizeMismatch) {
+			t.Errorf("Accept with a 12x12 board = %v, want ErrBoardSizeMismatch", err)
+		}
+		if _, err := r.battles.Accept(amy, amys, accepted); !errors.Is(err, models.ErrNotYourBattle) {
+			t.Errorf("the challenger accepting = %v, want ErrNotYourBattle", err)
+		}
+		if _, err := r.battles.Accept(ben, bens, accepted); err != nil {
+			t.Fatalf("Accept = %v", err)
+		}
+		if err := r.battles.Withdraw(amy, accepted); !errors.Is(err, models.ErrBattleStatus) {
+			t.Errorf("Withdraw after Accept = %v, want ErrBattleStatus", err)
+		}
+
+		want := map[int]models.BattleStatus{
+			declined:  models.StatusDeclined,
+			withdrawn: models.StatusCancelled,
+			accepted:  models.StatusSetup,
+		}
+		for battleID, status := range want {
+			b, err := r.battles.Get(amy, battleID)
+			if err != nil || b.Status != status {
+				t.Errorf("battle %d: Get = %+v, %v; want %s", battleID, b, err, status)
+			}
+		}
+		b, err := r.battles.Get(ben, accepted)
+		if err != nil || b.Player1BoardID == amys || b.Player2BoardID == bens || b.Player2BoardID == 0 {
+			t.Errorf("the battle should play on copies of the saved boards: %+v, %v", b, err)
+		}
+		if _, err = r.battles.Get(ben+100, accepted); !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("Get(someone else's battle) = %v, want ErrNoRecord", err)
+		}
+	})
+}
+
+func TestVolley(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		amy, ben, battleID := startBattle(t, r, game.Rules{Mode: game.Classic})
+		b, err := r.battles.Get(amy, battleID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Ben fires first (at amy's board); amy only ever misses
+		targets := map[int]int{ben: b.Player1BoardID, amy: b.Player2BoardID}
+		hits, misses := layoutShots(t), openWater(len(layoutShots(t)))
+		fire := func(playerID int, shots []game.Shot, secret, next string) ([]game.Outcome, bool, error) {
+			return r.positions.Volley(playerID, playerID, battleID, targets[playerID], shots, secret, next)
+		}
+
+		_, secret := r.battles.CheckTurn(battleID, ben)
+		if secret == "" {
+			t.Fatal("it isn't ben's turn")
+		}
+		if _, turn := r.battles.CheckTurn(battleID, amy); turn != "" {
+			t.Error("CheckTurn hands amy a secret on ben's turn")
+		}
+		if _, _, err = fire(ben, hits[:1], "stale", "next"); !errors.Is(err, models.ErrStaleTurn) {
+			t.Errorf("Volley with the wrong secret = %v, want ErrStaleTurn", err)
+		}
+		if _, _, err = fire(amy, misses[:1], secret, "next"); !errors.Is(err, models.ErrStaleTurn) {
+			t.Errorf("Volley out of turn = %v, want ErrStaleTurn", err)
+		}
+		if _, _, err = fire(ben, hits[:2], secret, "next"); !errors.Is(err, game.ErrWrongShotCount) {
+			t.Errorf("two shots in a classic battle = %v, want ErrWrongShotCount", err)
+		}
+		if _, _, err = r.positions.Volley(ben, ben, battleID, b.Player2BoardID, hits[:1], secret, "next"); !errors.Is(err, models.ErrNotYourBattle) {
+			t.Errorf("ben firing at their own board = %v, want ErrNotYourBattle", err)
+		}
+
+		var winner bool
+		for i, s := range hits {
+			outcomes, won, err := fire(ben, []game.Shot{s}, secret, "amy's turn")
+			if err != nil {
+				t.Fatalf("ben's shot %d (%s): %v", i, s, err)
+			}
+			if outcomes[0].Result == game.Miss {
+				t.Errorf("ben's shot %d (%s) missed", i, s)
+			}
+			if won {
+				winner = true
+				if i != len(hits)-1 {
+					t.Errorf("ben won with %d ships left to sink", len(hits)-1-i)
+				}
+				break
+			}
+			if _, _, err = fire(ben, []game.Shot{s}, secret, "amy's turn"); !errors.Is(err, models.ErrStaleTurn) {
+				t.Fatalf("the same turn twice = %v, want ErrStaleTurn", err)
+			}
+			outcomes, _, err = fire(amy, misses[i:i+1], "amy's turn", "ben's turn")
+			if err != nil || outcomes[0].Result != game.Miss {
+				t.Fatalf("amy's shot %d (%s) = %v, %v", i, misses[i], outcomes, err)
+			}
+			secret = "ben's turn"
+		}
+		if !winner {
+			t.Fatal("sinking every ship didn't win the battle")
+		}
+
+		b, err = r.battles.Get(amy, battleID)
+		if err != nil || b.Winner != ben || b.Status != models.StatusFinished {
+			t.Errorf("after the last ship went down: %+v, %v", b, err)
+		}
+		if won, err := r.positions.CheckWinner(amy, battleID); !won || err != nil {
+			t.Errorf("CheckWinner = %v, %v; want true", won, err)
+		}
+		if _, _, err = fire(amy, misses[len(misses)-1:], "ben's turn", "over"); !errors.Is(err, models.ErrStaleTurn) {
+			t.Errorf("a shot after the battle is over = %v, want ErrStaleTurn", err)
+		}
+
+		strikes, err := r.strikes.List(battleID)
+		if err != nil || len(strikes) != 2*len(hits)-1 {
+			t.Fatalf("Strikes = %d, %v; want %d", len(strikes), err, 2*len(hits)-1)
+		}
+		if s := strikes[0]; s.PlayerID != ben || s.Move != 1 || s.Result == "miss" {
+			t.Errorf("the first strike = %+v, want ben's hit in move 1", s)
+		}
+		pins, err := r.positions.List(b.Player1BoardID, amy)
+		if err != nil || len(pins) != len(hits) {
+			t.Errorf("pins on amy's board = %d, %v; want %d", len(pins), err, len(hits))
+		}
+
+		leaders, err := r.ratings.Leaderboard(10)
+		if err != nil || len(leaders) != 2 || leaders[0].ID != ben || leaders[0].Wins != 1 {
+			t.Errorf("Leaderboard = %d players, %v; want ben on top with a win", len(leaders), err)
+		}
+	})
+}
+
+// Many copies of the same turn at once (a double click, a retry racing the
+// original): exactly one is taken and the rest are stale
+// - On SQLite the losers wait on the write lock (busy_timeout) and then find the
+//   secret has moved on; "database is locked" would be a failure here
+// - On MySQL it's InnoDB's row lock: the losers' UPDATE ... WHERE secretTurn = ?
+//   waits for the winner's transaction and then matches nothing
+func TestConcurrentVolley(t *testing.T) {
+	const copies = 8
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		amy, ben, battleID := startBattle(t, r, game.Rules{Mode: game.Classic})
+		if strings.HasSuffix(t.Name(), "/mysql") && !locksRows(t, r.db, battleID) {
+			t.Skip("this MySQL server doesn't lock a row while a transaction updates it " +
+				"(InnoDB does), so every copy's UPDATE ... WHERE secretTurn = ? still sees the old secret")
+		}
+		b, err := r.battles.Get(amy, battleID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, secret := r.battles.CheckTurn(battleID, ben)
+		shot := openWater(1)
+
+		start := make(chan struct{})
+		errs := make(chan error, copies)
+		var wg sync.WaitGroup
+		for i := 0; i < copies; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				_, _, err := r.positions.Volley(ben, ben, battleID, b.Player1BoardID, shot, secret, fmt.Sprintf("next %d", i))
+				errs <- err
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		close(errs)
+
+		taken := 0
+		for err := range errs {
+			switch {
+			case err == nil:
+				taken++
+			case !errors.Is(err, models.ErrStaleTurn):
+				t.Errorf("a copy of the turn failed with %v, want ErrStaleTurn", err)
+			}
+		}
+		if taken != 1 {
+			t.Errorf("%d copies of the same turn were taken, want 1", taken)
+		}
+		if pins, err := r.positions.List(b.Player1BoardID, amy); err != nil || len(pins) != 1 {
+			t.Errorf("pins on amy's board = %d, %v; want 1", len(pins), err)
+		}
+		if strikes, err := r.strikes.List(battleID); err != nil || len(strikes) != 1 {
+			t.Errorf("Strikes = %d, %v; want 1", len(strikes), err)
+		}
+		if _, turn := r.battles.CheckTurn(battleID, amy); turn == "" {
+			t.Error("it isn't amy's turn after ben's")
+		}
+	})
+}
+
+// locksRows - does a second transaction's UPDATE of a Battles row wait for the
+// first one's to commit? (it gets a second to show that it does)
+func locksRows(t *testing.T, db *sql.DB, battleID int) bool {
+	t.Helper()
+	first, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = first.Exec(`UPDATE Battles SET secretTurn = secretTurn WHERE rowid = ?`, battleID); err != nil {
+		first.Rollback()
+		t.Fatal(err)
+	}
+	second, err := db.Begin()
+	if err != nil {
+		first.Rollback()
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := second.Exec(`UPDATE Battles SET secretTurn = secretTurn WHERE rowid = ?`, battleID)
+		done <- err
+	}()
+	waited := false
+	select {
+	case err = <-done:
+	case <-time.After(time.Second):
+		waited = true
+		first.Rollback()
+		err = <-done
+	}
+	first.Rollback()
+	second.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return waited
+}
+
+func TestWaiting(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		amy, ben, battleID := startBattle(t, r, game.Rules{Mode: game.Classic})
+		waiting := func(playerID int) []int {
+			t.Helper()
+			battles, err := r.battles.Waiting(playerID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, b := range battles {
+				ids = append(ids, b.ID)
+			}
+			return ids
+		}
+		if ids := waiting(ben); len(ids) != 1 || ids[0] != battleID {
+			t.Errorf("Waiting(ben) before the first shot = %v, want [%d]", ids, battleID)
+		}
+		if ids := waiting(amy); len(ids) != 0 {
+			t.Errorf("Waiting(amy) before the first shot = %v, want none", ids)
+		}
+
+		b, err := r.battles.Get(amy, battleID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, secret := r.battles.CheckTurn(battleID, ben)
+		if _, _, err = r.positions.Volley(ben, ben, battleID, b.Player1BoardID, openWater(1), secret, "amy's turn"); err != nil {
+			t.Fatal(err)
+		}
+		if ids := waiting(amy); len(ids) != 1 || ids[0] != battleID {
+			t.Errorf("Waiting(amy) after ben's shot = %v, want [%d]", ids, battleID)
+		}
+		if ids := waiting(ben); len(ids) != 0 {
+			t.Errorf("Waiting(ben) after ben's shot = %v, want none", ids)
+		}
+
+		// nobody is waited on in a battle that's over
+		if _, err = r.battles.Resign(amy, battleID); err != nil {
+			t.Fatal(err)
+		}
+		if ids := waiting(amy); len(ids) != 0 {
+			t.Errorf("Waiting(amy) after amy resigned = %v, want none", ids)
+		}
+	})
+}
+
+func TestSalvo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		amy, ben, battleID := startBattle(t, r, game.Rules{Mode: game.Salvo})
+		b, err := r.battles.Get(amy, battleID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := r.positions.ShotsPerTurn(battleID, ben)
+		if err != nil || n != len(game.DefaultFleet) {
+			t.Fatalf("ShotsPerTurn = %d, %v; want one per ship", n, err)
+		}
+		_, secret := r.battles.CheckTurn(battleID, ben)
+		if _, _, err = r.positions.Volley(ben, ben, battleID, b.Player1BoardID, openWater(n-1), secret, "next"); !errors.Is(err, game.ErrWrongShotCount) {
+			t.Errorf("a salvo a shot short = %v, want ErrWrongShotCount", err)
+		}
+
+		// A volley with one bad shot lands none of them
+		shots := append(openWater(n-1), game.Shot{CoordX: 11, CoordY: "A"})
+		if _, _, err = r.positions.Volley(ben, ben, battleID, b.Player1BoardID, shots, secret, "next"); !errors.Is(err, game.ErrOutOfBounds) {
+			t.Errorf("a salvo with a shot off the board = %v, want ErrOutOfBounds", err)
+		}
+		if pins, err := r.positions.List(b.Player1BoardID, amy); err != nil || len(pins) != 0 {
+			t.Errorf("a salvo that failed left %d pins, %v", len(pins), err)
+		}
+		if _, turn := r.battles.CheckTurn(battleID, ben); turn != secret {
+			t.Errorf("a salvo that failed took ben's turn")
+		}
+
+		outcomes, _, err := r.positions.Volley(ben, ben, battleID, b.Player1BoardID, openWater(n), secret, "next")
+		if err != nil || len(outcomes) != n {
+			t.Fatalf("a full salvo = %d outcomes, %v", len(outcomes), err)
+		}
+		strikes, err := r.strikes.List(battleID)
+		if err != nil || len(strikes) != n {
+			t.Fatalf("Strikes = %d, %v; want %d", len(strikes), err, n)
+		}
+		for _, s := range strikes {
+			if s.Move != 1 {
+				t.Errorf("strike %+v isn't part of move 1; a salvo is one move", s)
+			}
+		}
+	})
+}
+
+func TestWebhooks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		amy := addPlayer(t, r, "amy")
+		ben := addPlayer(t, r, "ben")
+		all, err := r.webhooks.Insert(amy, "https://example.com/all", "secret", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		strikes, err := r.webhooks.Insert(amy, "https://example.com/strikes", "secret", []string{"strike", "sunk"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h, err := r.webhooks.Get(amy, strikes)
+		if err != nil || h.URL != "https://example.com/strikes" || len(h.Events) != 2 || h.Secret != "secret" {
+			t.Errorf("Get = %+v, %v", h, err)
+		}
+		if _, err = r.webhooks.Get(ben, strikes); !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("Get(someone else's webhook) = %v, want ErrNoRecord", err)
+		}
+		listening := map[string]int{"strike": 2, "winner": 1}
+		for event, want := range listening {
+			hooks, err := r.webhooks.Listening(amy, event)
+			if err != nil || len(hooks) != want {
+				t.Errorf("Listening(%s) = %d webhooks, %v; want %d", event, len(hooks), err, want)
+			}
+		}
+
+		deliveryID, err := r.webhooks.LogDelivery(all, 0, "ping", `{"id":"ping"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = r.webhooks.UpdateDelivery(deliveryID, 2, 503, models.DeliveryPending, "The receiver answered 503"); err != nil {
+			t.Fatal(err)
+		}
+		deliveries, err := r.webhooks.Deliveries(all, 10)
+		if err != nil || len(deliveries) != 1 {
+			t.Fatalf("Deliveries = %d, %v", len(deliveries), err)
+		}
+		if d := deliveries[0]; d.Attempts != 2 || d.StatusCode != 503 || d.Status != models.DeliveryPending || d.Payload != `{"id":"ping"}` {
+			t.Errorf("the delivery = %+v", d)
+		}
+
+		if err = r.webhooks.Delete(ben, all); !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("Delete(someone else's webhook) = %v, want ErrNoRecord", err)
+		}
+		if err = r.webhooks.Delete(amy, all); err != nil {
+			t.Errorf("Delete = %v", err)
+		}
+		if err = r.webhooks.Delete(amy, all); !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("Delete twice = %v, want ErrNoRecord", err)
+		}
+		if hooks, err := r.webhooks.List(amy); err != nil || len(hooks) != 1 || hooks[0].ID != strikes {
+			t.Errorf("List after Delete = %d webhooks, %v", len(hooks), err)
+		}
+	})
+}
+
+func TestNotifications(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		amy := addPlayer(t, r, "amy")
+		for _, n := range []struct {
+			battleID int
+			kind     string
+		}{
+			{1, models.NoticeChallenge},
+			{1, models.NoticeTurn},
+			{1, models.NoticeTurn}, // replaces the unread one before it
+			{2, models.NoticeTurn},
+		} {
+			if _, err := r.notifications.Insert(amy, n.battleID, n.kind, "Something happened"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if unread, err := r.notifications.Unread(amy); err != nil || unread != 3 {
+			t.Errorf("Unread = %d, %v; want 3", unread, err)
+		}
+		if err := r.notifications.MarkBattleRead(amy, 1); err != nil {
+			t.Fatal(err)
+		}
+		if unread, err := r.notifications.Unread(amy); err != nil || unread != 1 {
+			t.Errorf("Unread after MarkBattleRead = %d, %v; want 1", unread, err)
+		}
+		list, err := r.notifications.List(amy, 10)
+		if err != nil || len(list) != 3 || list[0].BattleID != 2 || list[0].Read {
+			t.Errorf("List = %d notifications, %v; want 3 with the unread one first", len(list), err)
+		}
+		// a read one is replaced too
+		if _, err = r.notifications.Insert(amy, 1, models.NoticeTurn, "Your turn again"); err != nil {
+			t.Fatal(err)
+		}
+		list, err = r.notifications.List(amy, 10)
+		if err != nil || len(list) != 3 || list[0].BattleID != 1 || list[0].Message != "Your turn again" || list[0].Read {
+			t.Errorf("List after another turn = %d notifications, %v; want 3 with the new turn first", len(list), err)
+		}
+		if err = r.notifications.MarkAllRead(amy); err != nil {
+			t.Fatal(err)
+		}
+		if unread, err := r.notifications.Unread(amy); err != nil || unread != 0 {
+			t.Errorf("Unread after MarkAllRead = %d, %v", unread, err)
+		}
+	})
+}
+
+func TestTokens(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r *repositories) {
+		amy := addPlayer(t, r, "amy")
+		token, err := r.tokens.Insert(amy, "laptop", []string{models.ScopeRead})
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := r.tokens.Authenticate(token)
+		if err != nil || a.PlayerID != amy || a.ScreenName != "amy" || len(a.Scopes) != 1 {
+			t.Fatalf("Authenticate = %+v, %v", a, err)
+		}
+		if _, err = r.tokens.Authenticate(token + "x"); !errors.Is(err, models.ErrInvalidCredentials) && !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("Authenticate(a wrong token) = %v", err)
+		}
+		if err = r.tokens.Revoke(amy, a.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = r.tokens.Authenticate(token); err == nil {
+			t.Error("a revoked token still works")
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+)
+
+// FleetMismatch - can boards saved with these ships be played with this fleet? (nil if they can)
+// - squares is board ID -> ship type -> how many squares that ship has on the board
+// - A ship the fleet doesn't have, or one of a different length, would stop the
+//   board from loading in the middle of a battle, so it's ErrFleetMismatch
+func FleetMismatch(fleet game.Fleet, squares map[int]map[string]int) error {
+	var boardIDs []int
+	for boardID := range squares {
+		boardIDs = append(boardIDs, boardID)
+	}
+	sort.Ints(boardIDs)
+	for _, boardID := range boardIDs {
+		var shipTypes []string
+		for shipType := range squares[boardID] {
+			shipTypes = append(shipTypes, shipType)
+		}
+		sort.Strings(shipTypes)
+		for _, shipType := range shipTypes {
+			n := squares[boardID][shipType]
+			ship, ok := fleet.Lookup(shipType)
+			if !ok {
+				return fmt.Errorf("%w: board %d has a %s, which the fleet doesn't", ErrFleetMismatch, boardID, shipType)
+			}
+			if n != ship.Length {
+				return fmt.Errorf("%w: board %d has a %s %d squares long, the fleet's is %d",
+					ErrFleetMismatch, boardID, shipType, n, ship.Length)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type BattleModel struct {
+	Store *Store
+}
+
+// Accept a challenge (battle)
+// - Only player2 can accept, with a board of their own the size of the battle
+// - The battle plays on its own copy of that board (see copyBoard)
+// - challenged -> accepted -> setup
+func (m *BattleModel) Accept(player2ID, boardID, battleID int) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return 0, models.ErrNoRecord
+	}
+	if b.player2ID != player2ID {
+		return 0, models.ErrNotYourBattle
+	}
+	bo := m.Store.board(boardID)
+	if bo == nil || bo.playerID != player2ID {
+		return 0, models.ErrNoRecord
+	}
+	if bo.size != b.boardSize {
+		return 0, models.ErrBoardSizeMismatch
+	}
+	if !b.status.CanBecome(models.StatusAccepted) {
+		return 0, fmt.Errorf("%w: %s to %s", models.ErrBattleStatus, b.status, models.StatusAccepted)
+	}
+	copyID, err := m.Store.copyBoard(player2ID, boardID, battleID)
+	if err != nil {
+		return 0, err
+	}
+	b.status = models.StatusAccepted
+	b.player2Accepted = true
+	b.player2BoardID = copyID
+	// Both boards are in; the opponent fires first, and their turn clock starts now
+	if err = b.setStatus(models.StatusSetup); err != nil {
+		return 0, err
+	}
+	m.Store.startClock(b)
+	return battleID, nil
+}
+
+// CheckBoardOwner - Ensure the player is the owner of this board and this board is part of this battle
+func (m *BattleModel) CheckBoardOwner(playerID, battleID, boardID int) bool {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return false
+	}
+	return (b.player1ID == playerID && b.player1BoardID == boardID) ||
+		(b.player2ID == playerID && b.player2BoardID == boardID)
+}
+
+// CheckTurn - whose turn is it?
+// - The secret is only handed out to the player whose turn it is
+func (m *BattleModel) CheckTurn(battleID, playerID int) (int, string) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil || b.turn != playerID {
+		return 0, ""
+	}
+	return b.turn, b.secretTurn
+}
+
+// Create a new Battle - record the challenger (player1) and the challengee (player2)
+// - A pair of players may have any number of battles going at once
+// - The battle is played on the challenger's board size and the opponent goes first
+// - rules are picked by the challenger (classic or salvo, and the turn clock)
+// - The battle plays on its own copy of the challenger's board (see copyBoard)
+func (m *BattleModel) Create(player1ID, player1BoardID, player2ID int, secretTurn string, rules game.Rules) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	boardSize := 10
+	if bo := m.Store.board(player1BoardID); bo != nil {
+		boardSize = bo.size
+	}
+	copyID, err := m.Store.copyBoard(player1ID, player1BoardID, len(m.Store.battles)+1)
+	if err != nil {
+		return 0, err
+	}
+	b := &battle{
+		id:              len(m.Store.battles) + 1,
+		player1ID:       player1ID,
+		player1Accepted: true,
+		player1BoardID:  copyID,
+		player2ID:       player2ID,
+		challengeDate:   m.Store.now(),
+		turn:            player2ID,
+		secretTurn:      secretTurn,
+		boardSize:       boardSize,
+		rules:           rules.Mode,
+		salvoShots:      rules.SalvoShots,
+		status:          models.StatusChallenged,
+		turnTime:        rules.TurnTime,
+	}
+	// The opponent has until the turn clock runs out to answer
+	m.Store.startClock(b)
+	m.Store.battles = append(m.Store.battles, b)
+	return b.id, nil
+}
+
+// Decline - the opponent (player2) turns the challenge down
+// - challenged -> declined
+func (m *BattleModel) Decline(playerID, battleID int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return models.ErrNoRecord
+	}
+	if b.player2ID != playerID {
+		return models.ErrNotYourBattle
+	}
+	return b.setStatus(models.StatusDeclined)
+}
+
+// Withdraw - the challenger (player1) takes the challenge back before it's accepted
+// - challenged -> cancelled
+func (m *BattleModel) Withdraw(playerID, battleID int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return models.ErrNoRecord
+	}
+	if b.player1ID != playerID {
+		return models.ErrNotYourBattle
+	}
+	return b.setStatus(models.StatusCancelled)
+}
+
+// Resign - a player gives up a battle that has been accepted; the opponent wins
+// - accepted, setup or in_progress -> abandoned
+// - It counts as a loss (see rate)
+// - Returns the winner
+func (m *BattleModel) Resign(playerID, battleID int) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return 0, models.ErrNoRecord
+	}
+	winner := b.player1ID
+	switch playerID {
+	case b.player1ID:
+		winner = b.player2ID
+	case b.player2ID:
+	default:
+		return 0, models.ErrNotYourBattle
+	}
+	if err := b.setStatus(models.StatusAbandoned); err != nil {
+		return 0, err
+	}
+	if b.winner == 0 {
+		b.winner = winner
+		m.Store.rate(b, winner)
+	}
+	return winner, nil
+}
+
+// Expire - nobody answered the challenge before its deadline
+// - challenged -> expired
+// - ErrBattleStatus if it was answered in the meantime
+func (m *BattleModel) Expire(battleID int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return models.ErrNoRecord
+	}
+	if !m.Store.overdue(b) {
+		return models.ErrBattleStatus
+	}
+	return b.setStatus(models.StatusExpired)
+}
+
+// Forfeit - the player whose turn it is ran out of time; the other player wins
+// - accepted, setup or in_progress -> abandoned
+// - It counts as a loss (see rate)
+// - ErrBattleStatus if they moved (or the battle ended) in the meantime
+// - Returns the winner
+func (m *BattleModel) Forfeit(battleID int) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return 0, models.ErrNoRecord
+	}
+	if !m.Store.overdue(b) || b.winner != 0 {
+		return 0, models.ErrBattleStatus
+	}
+	winner := b.player1ID
+	if b.turn == b.player1ID {
+		winner = b.player2ID
+	}
+	if err := b.setStatus(models.StatusAbandoned); err != nil {
+		return 0, err
+	}
+	b.winner = winner
+	m.Store.rate(b, winner)
+	return winner, nil
+}
+
+// Get - return a single battle; this is for the battle board
+func (m *BattleModel) Get(playerID, battleID int) (*models.Battle, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil || (b.player1ID != playerID && b.player2ID != playerID) {
+		return nil, models.ErrNoRecord
+	}
+	return m.Store.detail(b), nil
+}
+
+// detail - everything the battle board needs (the caller holds the lock)
+func (s *Store) detail(b *battle) *models.Battle {
+	battle := s.summary(b)
+	battle.Title = battle.Player2ScreenName + " vs. " + battle.Player1ScreenName
+	battle.Player1BoardID = b.player1BoardID
+	battle.Player2BoardID = b.player2BoardID
+	battle.AIStrategy = b.aiStrategy
+	return battle
+}
+
+// Watch - a battle as a spectator sees it (see models.Battle.Watchable)
+// - ErrNoRecord if there's no such battle, it hasn't started or a player keeps it private
+func (m *BattleModel) Watch(battleID int) (*models.Battle, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return nil, models.ErrNoRecord
+	}
+	battle := m.Store.detail(b)
+	if !battle.Watchable() {
+		return nil, models.ErrNoRecord
+	}
+	return battle, nil
+}
+
+// ListWatchable - the battles being played right now that this player could watch, newest first
+// - Not their own, and not the ones a player keeps private
+func (m *BattleModel) ListWatchable(playerID int) ([]*models.Battle, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	battles := []*models.Battle{}
+	for i := len(m.Store.battles) - 1; i >= 0; i-- {
+		b := m.Store.battles[i]
+		if b.player1ID == playerID || b.player2ID == playerID || b.player1Private || b.player2Private {
+			continue
+		}
+		if b.status == models.StatusSetup || b.status == models.StatusInProgress {
+			battles = append(battles, m.Store.detail(b))
+		}
+	}
+	return battles, nil
+}
+
+// SetPrivate - a player keeps spectators out of a battle (or lets them back in)
+// - Each player has their own say; the battle is private if either wants it to be
+func (m *BattleModel) SetPrivate(playerID, battleID int, private bool) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return models.ErrNoRecord
+	}
+	switch playerID {
+	case b.player1ID:
+		b.player1Private = private
+	case b.player2ID:
+		b.player2Private = private
+	default:
+		return models.ErrNotYourBattle
+	}
+	return nil
+}
+
+// summary - the columns every battle listing has (the caller holds the lock)
+func (s *Store) summary(b *battle) *models.Battle {
+	return &models.Battle{
+		ID:                b.id,
+		Player1ID:         b.player1ID,
+		Player1ScreenName: s.screenName(b.player1ID),
+		Player1Accepted:   b.player1Accepted,
+		Player2ID:         b.player2ID,
+		Player2ScreenName: s.screenName(b.player2ID),
+		Player2Accepted:   b.player2Accepted,
+		ChallengeDate:     b.challengeDate,
+		Turn:              sql.NullInt64{Int64: int64(b.turn), Valid: true},
+		BoardSize:         b.boardSize,
+		Rules:             b.rules,
+		SalvoShots:        b.salvoShots,
+		Winner:            b.winner,
+		Status:            b.status,
+		TurnTime:          b.turnTime,
+		Deadline:          sql.NullTime{Time: b.deadline, Valid: !b.deadline.IsZero()},
+		Player1Private:    b.player1Private,
+		Player2Private:    b.player2Private,
+	}
+}
+
+// GetChallenger - See if there are any challengers out there
+func (m *BattleModel) GetChallenger(currentPlayerID int) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for _, b := range m.Store.battles {
+		if b.player2ID == currentPlayerID && b.status == models.StatusChallenged {
+			return b.player1ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// GetChallenges - every battle this player is in, either side
+// - ChallengerBoardName is the name of this player's own board
+func (m *BattleModel) GetChallenges(playerID int) ([]*models.Battle, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	battles := []*models.Battle{}
+	for _, b := range m.Store.battles {
+		var boardID int
+		switch playerID {
+		case b.player1ID:
+			boardID = b.player1BoardID
+		case b.player2ID:
+			boardID = b.player2BoardID
+		default:
+			continue
+		}
+		battle := m.Store.summary(b)
+		if bo := m.Store.board(boardID); bo != nil {
+			battle.ChallengerBoardName = sql.NullString{String: bo.name, Valid: true}
+		}
+		battles = append(battles, battle)
+	}
+	sort.Slice(battles, func(i, j int) bool { return battles[i].ID < battles[j].ID })
+	return battles, nil
+}
+
+// GetOpen - Get a list of challenges to this player
+func (m *BattleModel) GetOpen(playerID, battleID int) ([]*models.Battle, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	battles := []*models.Battle{}
+	for _, b := range m.Store.battles {
+		if b.player2ID == playerID {
+			battles = append(battles, m.Store.summary(b))
+		}
+	}
+	return battles, nil
+}
+
+// Overdue - battles waiting on a move whose deadline has passed (challenges too)
+func (m *BattleModel) Overdue() ([]*models.Battle, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	battles := []*models.Battle{}
+	for _, b := range m.Store.battles {
+		switch b.status {
+		case models.StatusChallenged, models.StatusSetup, models.StatusInProgress:
+			if m.Store.overdue(b) {
+				battles = append(battles, m.Store.summary(b))
+			}
+		}
+	}
+	return battles, nil
+}
+
+// Waiting - battles under way where it's this player's turn to fire
+func (m *BattleModel) Waiting(playerID int) ([]*models.Battle, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	battles := []*models.Battle{}
+	for _, b := range m.Store.battles {
+		switch b.status {
+		case models.StatusSetup, models.StatusInProgress:
+			if b.turn == playerID {
+				battles = append(battles, m.Store.summary(b))
+			}
+		}
+	}
+	return battles, nil
+}
+
+// SetStrategy - how the computer plays in this battle (see game.Strategies)
+func (m *BattleModel) SetStrategy(battleID int, strategy string) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if b := m.Store.battle(battleID); b != nil {
+		b.aiStrategy = strategy
+	}
+	return nil
+}
+
+// UpdateChallenge - opponent has accepted
+func (m *BattleModel) UpdateChallenge(player1 int, player2 int, player2Accepted bool, battleID int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if b := m.Store.battle(battleID); b != nil {
+		b.player2Accepted = player2Accepted
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type BoardModel struct {
+	Store *Store
+}
+
+// Create a board if one with the same name doesn't already exist (belonging to this user)
+// - ErrDuplicateBoardName if it does; battle copies of a board don't count
+func (m *BoardModel) Create(playerID int, boardName string, boardSize int) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for _, bo := range m.Store.boards {
+		if bo.playerID == playerID && bo.name == boardName && bo.battleID == 0 {
+			return 0, models.ErrDuplicateBoardName
+		}
+	}
+	bo := &board{
+		id:       len(m.Store.boards) + 1,
+		name:     boardName,
+		playerID: playerID,
+		created:  m.Store.now(),
+		size:     boardSize,
+	}
+	m.Store.boards = append(m.Store.boards, bo)
+	return bo.id, nil
+}
+
+// GetInfo - name of board (only for its owner)
+func (m *BoardModel) GetInfo(playerID, boardID int) (*models.Board, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	bo := m.Store.board(boardID)
+	if bo == nil || bo.playerID != playerID {
+		return nil, models.ErrNoRecord
+	}
+	b := bo.model()
+	for _, c := range m.Store.boards {
+		if c.templateID == bo.id {
+			b.Battles++
+		}
+	}
+	return b, nil
+}
+
+func (bo *board) model() *models.Board {
+	return &models.Board{
+		ID:         bo.id,
+		Title:      bo.name,
+		PlayerID:   bo.playerID,
+		BattleID:   sql.NullInt64{Int64: int64(bo.battleID), Valid: bo.battleID != 0},
+		Created:    bo.created,
+		BoardSize:  bo.size,
+		TemplateID: bo.templateID,
+	}
+}
+
+// copyBoard - give a battle its own copy of a player's saved board (the caller holds the lock)
+// - Only the ships are copied; pins land on the copy, never on the saved board
+// - ErrNoRecord unless the player owns the saved board (and it isn't itself a copy)
+func (s *Store) copyBoard(playerID, templateID, battleID int) (int, error) {
+	template := s.board(templateID)
+	if template == nil || template.playerID != playerID || template.battleID != 0 {
+		return 0, models.ErrNoRecord
+	}
+	bo := &board{
+		id:         len(s.boards) + 1,
+		name:       template.name,
+		playerID:   playerID,
+		created:    s.now(),
+		size:       template.size,
+		templateID: templateID,
+		battleID:   battleID,
+	}
+	s.boards = append(s.boards, bo)
+	for _, p := range s.positions {
+		if p.boardID != templateID || p.shipType == "" {
+			continue
+		}
+		s.positions = append(s.positions, &position{
+			id:       len(s.positions) + 1,
+			boardID:  bo.id,
+			shipType: p.shipType,
+			playerID: p.playerID,
+			coordX:   p.coordX,
+			coordY:   p.coordY,
+			pinColor: "0",
+		})
+	}
+	return bo.id, nil
+}
+
+// GetPositions - ship squares and pins on a board
+// - ID is the board's ID; PositionID is the square's own
+func (m *BoardModel) GetPositions(boardID int) ([]*models.Position, error) {
+	if boardID == 0 {
+		return nil, models.ErrMissingBoardID
+	}
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	positions := []*models.Position{}
+	for _, p := range m.Store.positions {
+		if p.boardID != boardID {
+			continue
+		}
+		positions = append(positions, &models.Position{
+			ID:         boardID,
+			PlayerID:   p.playerID,
+			PositionID: p.id,
+			ShipType:   sql.NullString{String: p.shipType, Valid: p.shipType != ""},
+			CoordX:     p.coordX,
+			CoordY:     p.coordY,
+			PinColor:   p.pinColor,
+		})
+	}
+	return positions, nil
+}
+
+// Insert coordinates ("row,col") of a ship on a board
+// - ErrNoRecord if the ship isn't known (see ShipModel.Sync)
+func (m *BoardModel) Insert(playerID int, boardID int, shipName string, arrayOfCoords []string) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	known := false
+	for _, ship := range m.Store.ships {
+		known = known || ship.Title == shipName
+	}
+	if !known {
+		return 0, models.ErrNoRecord
+	}
+	for _, rc := range arrayOfCoords {
+		s := strings.Split(rc, ",")
+		if len(s) != 2 {
+			return 0, fmt.Errorf("memory: malformed coordinate %q", rc)
+		}
+		row, err := strconv.Atoi(s[0])
+		if err != nil {
+			return 0, fmt.Errorf("memory: malformed coordinate %q", rc)
+		}
+		m.Store.positions = append(m.Store.positions, &position{
+			id:       len(m.Store.positions) + 1,
+			boardID:  boardID,
+			shipType: shipName,
+			playerID: playerID,
+			coordX:   row,
+			coordY:   s[1],
+			pinColor: "0",
+		})
+	}
+	return 0, nil
+}
+
+// List - a player's ten newest saved boards (not the copies battles are played on)
+func (m *BoardModel) List(playerID int) ([]*models.Board, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	battles := map[int]int{}
+	for _, bo := range m.Store.boards {
+		battles[bo.templateID]++
+	}
+	boards := []*models.Board{}
+	for _, bo := range m.Store.boards {
+		if bo.playerID == playerID && bo.battleID == 0 {
+			b := bo.model()
+			b.Battles = battles[bo.id]
+			boards = append(boards, b)
+		}
+	}
+	sort.SliceStable(boards, func(i, j int) bool {
+		if boards[i].Created.Equal(boards[j].Created) {
+			return boards[i].ID > boards[j].ID
+		}
+		return boards[i].Created.After(boards[j].Created)
+	})
+	if len(boards) > 10 {
+		boards = boards[:10]
+	}
+	return boards, nil
+}
+
+// Update - rename a board
+func (m *BoardModel) Update(boardID int, boardName string, playerID int) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	bo := m.Store.board(boardID)
+	if bo == nil {
+		return 0, models.ErrNoRecord
+	}
+	bo.name = boardName
+	bo.playerID = playerID
+	return boardID, nil
+}
@@ -0,0 +1,223 @@
+// Package memory - the models without a database (see models.BattleRepository and friends)
+// - Everything lives in one Store guarded by one mutex, so a volley can touch
+//   battles, pins and strikes at once, just like a transaction would
+// - IDs count up from 1 in each "table", so the same calls always get the same IDs
+// - Nothing is saved; this is for trying things out and for tests
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// Store - the "tables"; a row's ID is its index + 1
+type Store struct {
+	// Now is the clock used for created/lastLogin/etc. (time.Now if nil)
+	Now func() time.Time
+
+	mu        sync.Mutex
+	battles   []*battle
+	boards    []*board
+	messages  []*models.Message
+	players   []*player
+	positions []*position
+	ratings   []*models.Rating
+	ships     []*models.Ship
+	strikes   []*models.Strike
+	tokens    []*token
+
+	// Notifications are deleted when they're replaced, so they count their own IDs
+	notifications  []*models.Notification
+	notificationID int
+
+	// Deleted webhooks stay (like revoked tokens), so their delivery log still has an owner
+	webhooks   []*webhook
+	deliveries []*models.WebhookDelivery
+}
+
+// NewStore - an empty store
+func NewStore() *Store {
+	return &Store{}
+}
+
+func (s *Store) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+type battle struct {
+	id              int
+	player1ID       int
+	player1Accepted bool
+	player1BoardID  int
+	player2ID       int
+	player2Accepted bool
+	player2BoardID  int
+	player1Sunk     int
+	player2Sunk     int
+	challengeDate   time.Time
+	turn            int
+	secretTurn      string
+	winner          int
+	boardSize       int
+	rules           string
+	salvoShots      int
+	aiStrategy      string
+	status          models.BattleStatus
+	turnTime        time.Duration
+	deadline        time.Time
+	player1Private  bool
+	player2Private  bool
+}
+
+// setStatus - move a battle one step along its lifecycle (the caller holds the lock)
+func (b *battle) setStatus(status models.BattleStatus) error {
+	if !b.status.CanBecome(status) {
+		return fmt.Errorf("%w: %s to %s", models.ErrBattleStatus, b.status, status)
+	}
+	b.status = status
+	return nil
+}
+
+// startClock - whoever has to move next has until turnTime from now (the caller holds the lock)
+// - A battle without a turn clock has no deadline
+func (s *Store) startClock(b *battle) {
+	b.deadline = time.Time{}
+	if b.turnTime > 0 {
+		b.deadline = s.now().Add(b.turnTime)
+	}
+}
+
+// overdue - has whoever has to move next run out of time? (the caller holds the lock)
+func (s *Store) overdue(b *battle) bool {
+	return !b.deadline.IsZero() && !s.now().Before(b.deadline)
+}
+
+// board - a saved board, or a battle's copy of one (templateID and battleID set)
+type board struct {
+	id         int
+	name       string
+	playerID   int
+	created    time.Time
+	size       int
+	templateID int
+	battleID   int
+}
+
+type player struct {
+	id             int
+	screenName     string
+	emailAddress   string
+	hashedPassword []byte
+	created        time.Time
+	loggedIn       bool
+	lastLogin      time.Time
+	isComputer     bool
+	rating         int
+	wins           int
+	losses         int
+	streak         int
+}
+
+// position - a ship square (shipType set) or a miss (shipType empty)
+// - pinColor is "0" until the square is shot at
+type position struct {
+	id       int
+	boardID  int
+	shipType string
+	playerID int
+	coordX   int
+	coordY   string
+	pinColor string
+}
+
+type token struct {
+	id        int
+	playerID  int
+	name      string
+	prefix    string
+	tokenHash string
+	scopes    []string
+	created   time.Time
+	lastUsed  time.Time
+	revoked   bool
+}
+
+type webhook struct {
+	models.Webhook
+	deleted bool
+}
+
+// Lookups by ID; the caller holds the lock
+
+func (s *Store) battle(id int) *battle {
+	if id < 1 || id > len(s.battles) {
+		return nil
+	}
+	return s.battles[id-1]
+}
+
+func (s *Store) board(id int) *board {
+	if id < 1 || id > len(s.boards) {
+		return nil
+	}
+	return s.boards[id-1]
+}
+
+func (s *Store) player(id int) *player {
+	if id < 1 || id > len(s.players) {
+		return nil
+	}
+	return s.players[id-1]
+}
+
+func (s *Store) screenName(playerID int) string {
+	if p := s.player(playerID); p != nil {
+		return p.screenName
+	}
+	return ""
+}
+
+// Load a board into the game engine - ship squares first, then every pin
+// that has already been dropped on it (the caller holds the lock)
+func (s *Store) loadBoard(fleet game.Fleet, boardID int) (*game.Board, error) {
+	if boardID == 0 {
+		return nil, models.ErrMissingBoardID
+	}
+	bo := s.board(boardID)
+	if bo == nil {
+		return nil, models.ErrNoRecord
+	}
+	ships := map[string][]game.Shot{}
+	var pins []game.Shot
+	for _, p := range s.positions {
+		if p.boardID != boardID {
+			continue
+		}
+		shot := game.Shot{CoordX: p.coordX, CoordY: p.coordY}
+		if p.shipType != "" {
+			ships[p.shipType] = append(ships[p.shipType], shot)
+		}
+		if p.pinColor == "red" || p.pinColor == "gray" {
+			pins = append(pins, shot)
+		}
+	}
+	b := game.NewBoard(bo.size, fleet)
+	for shipType, coords := range ships {
+		if err := b.Place(shipType, coords); err != nil {
+			return nil, fmt.Errorf("board %d, %s: %w", boardID, shipType, err)
+		}
+	}
+	for _, shot := range pins {
+		if _, err := b.Strike(shot); err != nil {
+			return nil, fmt.Errorf("board %d, pin %s: %w", boardID, shot, err)
+		}
+	}
+	return b, nil
+}
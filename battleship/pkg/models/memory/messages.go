@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// MessageModel - the chat between the two players in a battle
+type MessageModel struct {
+	Store *Store
+}
+
+// Insert - a player says something in their battle
+// - ErrNotYourBattle if they aren't playing in it
+func (m *MessageModel) Insert(playerID, battleID int, body string) (*models.Message, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.battle(battleID)
+	if b == nil {
+		return nil, models.ErrNoRecord
+	}
+	if playerID != b.player1ID && playerID != b.player2ID {
+		return nil, models.ErrNotYourBattle
+	}
+	msg := &models.Message{
+		ID:         len(m.Store.messages) + 1,
+		BattleID:   battleID,
+		PlayerID:   playerID,
+		ScreenName: m.Store.screenName(playerID),
+		Body:       body,
+		Created:    m.Store.now(),
+	}
+	m.Store.messages = append(m.Store.messages, msg)
+	saved := *msg
+	return &saved, nil
+}
+
+// List - everything said in a battle, oldest first
+func (m *MessageModel) List(battleID int) ([]*models.Message, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	messages := []*models.Message{}
+	for _, msg := range m.Store.messages {
+		if msg.BattleID == battleID {
+			saved := *msg
+			messages = append(messages, &saved)
+		}
+	}
+	return messages, nil
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// NotificationModel - each player's inbox
+type NotificationModel struct {
+	Store *Store
+}
+
+// Insert - put a notification in a player's inbox
+// - An earlier notification of the same kind about the same battle is replaced,
+//   read or not, so a long battle leaves one "your turn" rather than dozens
+func (m *NotificationModel) Insert(playerID, battleID int, kind, message string) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	kept := m.Store.notifications[:0]
+	for _, n := range m.Store.notifications {
+		if n.PlayerID == playerID && n.BattleID == battleID && n.Kind == kind {
+			continue
+		}
+		kept = append(kept, n)
+	}
+	m.Store.notifications = kept
+	m.Store.notificationID++
+	m.Store.notifications = append(m.Store.notifications, &models.Notification{
+		ID:       m.Store.notificationID,
+		PlayerID: playerID,
+		BattleID: battleID,
+		Kind:     kind,
+		Message:  message,
+		Created:  m.Store.now(),
+	})
+	return m.Store.notificationID, nil
+}
+
+// List - a player's most recent notifications, newest first
+func (m *NotificationModel) List(playerID, limit int) ([]*models.Notification, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	notifications := []*models.Notification{}
+	for i := len(m.Store.notifications) - 1; i >= 0 && len(notifications) < limit; i-- {
+		if n := m.Store.notifications[i]; n.PlayerID == playerID {
+			saved := *n
+			notifications = append(notifications, &saved)
+		}
+	}
+	return notifications, nil
+}
+
+// MarkAllRead - empty a player's unread count
+func (m *NotificationModel) MarkAllRead(playerID int) error {
+	return m.MarkBattleRead(playerID, 0)
+}
+
+// MarkBattleRead - the player has looked at a battle; everything about it has been seen
+// - Battle 0 is every battle
+func (m *NotificationModel) MarkBattleRead(playerID, battleID int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for _, n := range m.Store.notifications {
+		if n.PlayerID == playerID && (battleID == 0 || n.BattleID == battleID) {
+			n.Read = true
+		}
+	}
+	return nil
+}
+
+// MarkRead - one notification has been seen; ErrNoRecord if it isn't the player's
+func (m *NotificationModel) MarkRead(playerID, notificationID int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for _, n := range m.Store.notifications {
+		if n.ID == notificationID && n.PlayerID == playerID {
+			n.Read = true
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
+
+// Unread - how many notifications a player hasn't seen
+func (m *NotificationModel) Unread(playerID int) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	unread := 0
+	for _, n := range m.Store.notifications {
+		if n.PlayerID == playerID && !n.Read {
+			unread++
+		}
+	}
+	return unread, nil
+}
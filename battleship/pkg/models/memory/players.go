@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/rating"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Passwords are hashed at bcrypt's lowest cost; nothing here outlives the process
+const passwordCost = bcrypt.MinCost
+
+type PlayerModel struct {
+	Store *Store
+}
+
+// Authenticate - the player's ID if the password is right
+func (m *PlayerModel) Authenticate(screenName, password string) (int, error) {
+	m.Store.mu.Lock()
+	var found *player
+	for _, p := range m.Store.players {
+		if p.screenName == screenName {
+			found = p
+			break
+		}
+	}
+	m.Store.mu.Unlock()
+
+	if found == nil {
+		return 0, models.ErrInvalidCredentials
+	}
+	err := bcrypt.CompareHashAndPassword(found.hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+	return found.id, nil
+}
+
+// EnsureComputer - make sure the built-in computer player exists and return its ID
+func (m *PlayerModel) EnsureComputer(screenName string) (int, error) {
+	m.Store.mu.Lock()
+	for _, p := range m.Store.players {
+		if p.isComputer {
+			m.Store.mu.Unlock()
+			return p.id, nil
+		}
+	}
+	m.Store.mu.Unlock()
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return 0, err
+	}
+	id, err := m.insert(screenName, "", secret)
+	if err != nil {
+		return 0, err
+	}
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+	p := m.Store.player(id)
+	p.isComputer = true
+	p.loggedIn = true
+	return id, nil
+}
+
+// Get - player information
+// - An unknown player comes back empty (ID 0) along with ErrNoRecord; the
+//   authenticate middleware looks at the ID
+func (m *PlayerModel) Get(playerID int) (*models.Player, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	p := m.Store.player(playerID)
+	if p == nil {
+		return &models.Player{}, models.ErrNoRecord
+	}
+	player := p.model()
+	player.EmailAddress = p.emailAddress
+	return player, nil
+}
+
+func (p *player) model() *models.Player {
+	loggedIn := "0"
+	if p.loggedIn {
+		loggedIn = "1"
+	}
+	return &models.Player{
+		ID:         p.id,
+		ScreenName: p.screenName,
+		LoggedIn:   sql.NullString{String: loggedIn, Valid: true},
+		Created:    sql.NullString{String: p.created.Format(time.RFC3339), Valid: true},
+		LastLogin:  sql.NullString{String: p.lastLogin.Format(time.RFC3339), Valid: true},
+		IsComputer: p.isComputer,
+		Rating:     p.rating,
+		Wins:       p.wins,
+		Losses:     p.losses,
+		Streak:     p.streak,
+	}
+}
+
+// Insert - a new player; screen names are unique
+func (m *PlayerModel) Insert(screenName string, emailAddress string, password string) (int, error) {
+	return m.insert(screenName, emailAddress, []byte(password))
+}
+
+func (m *PlayerModel) insert(screenName string, emailAddress string, password []byte) (int, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword(password, passwordCost)
+	if err != nil {
+		return 0, err
+	}
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for _, p := range m.Store.players {
+		if p.screenName == screenName {
+			return 0, models.ErrDuplicateScreenName
+		}
+	}
+	now := m.Store.now()
+	p := &player{
+		id:             len(m.Store.players) + 1,
+		screenName:     screenName,
+		emailAddress:   emailAddress,
+		hashedPassword: hashedPassword,
+		created:        now,
+		lastLogin:      now,
+		rating:         rating.Initial,
+	}
+	m.Store.players = append(m.Store.players, p)
+	return p.id, nil
+}
+
+// List - everyone but this player ("loggedIn" for just the ones logged in)
+func (m *PlayerModel) List(playerID int, status string) ([]*models.Player, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	players := []*models.Player{}
+	for _, p := range m.Store.players {
+		if p.id == playerID || (status == "loggedIn" && !p.loggedIn) {
+			continue
+		}
+		players = append(players, p.model())
+	}
+	if playerID == 0 && status == "" && len(players) == 0 {
+		return nil, models.ErrNoRecord
+	}
+	return players, nil
+}
+
+// Update - a player's email address
+func (m *PlayerModel) Update(playerID int, emailAddress string) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	p := m.Store.player(playerID)
+	if p == nil {
+		return playerID, models.ErrNoRecord
+	}
+	p.emailAddress = emailAddress
+	p.lastLogin = m.Store.now()
+	return playerID, nil
+}
+
+// UpdateLogin - logged in or out
+func (m *PlayerModel) UpdateLogin(playerID int, loggedIn bool) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	p := m.Store.player(playerID)
+	if p == nil {
+		return playerID, models.ErrNoRecord
+	}
+	p.loggedIn = loggedIn
+	p.lastLogin = m.Store.now()
+	return playerID, nil
+}
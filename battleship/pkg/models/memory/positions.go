@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type PositionModel struct {
+	Store *Store
+	Fleet game.Fleet
+}
+
+// participant - the battle, if this player is in it (the caller holds the lock)
+func (s *Store) participant(playerID, battleID int) *battle {
+	b := s.battle(battleID)
+	if b == nil || (b.player1ID != playerID && b.player2ID != playerID) {
+		return nil
+	}
+	return b
+}
+
+// CheckWinner - has one of the players lost their whole fleet?
+// - The game engine decides; we record the first winner and report it
+func (m *PositionModel) CheckWinner(playerID, battleID int) (bool, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.participant(playerID, battleID)
+	if b == nil {
+		return false, nil
+	}
+	if b.winner != 0 {
+		return true, nil
+	}
+	for _, side := range []struct{ boardID, victor int }{{b.player1BoardID, b.player2ID}, {b.player2BoardID, b.player1ID}} {
+		if side.boardID == 0 {
+			continue
+		}
+		board, err := m.Store.loadBoard(m.Fleet, side.boardID)
+		if err != nil {
+			return false, err
+		}
+		if board.FleetDestroyed() {
+			if err := b.setStatus(models.StatusFinished); err != nil {
+				return false, err
+			}
+			b.winner = side.victor
+			m.Store.rate(b, side.victor)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Knowledge - what an opponent can see of this board (see game.Board.Knowledge)
+func (m *PositionModel) Knowledge(boardID int) (game.Knowledge, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	board, err := m.Store.loadBoard(m.Fleet, boardID)
+	if err != nil {
+		return game.Knowledge{}, err
+	}
+	return board.Knowledge(), nil
+}
+
+// List - the pins (gray or red) dropped on a board
+func (m *PositionModel) List(boardID, playerID int) ([]*models.Position, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	positions := []*models.Position{}
+	for _, p := range m.Store.positions {
+		if p.boardID == boardID && (p.pinColor == "gray" || p.pinColor == "red") {
+			positions = append(positions, &models.Position{CoordX: p.coordX, CoordY: p.coordY, PinColor: p.pinColor})
+		}
+	}
+	return positions, nil
+}
+
+// ShotsPerTurn - how many shots this player fires on their turn
+func (m *PositionModel) ShotsPerTurn(battleID, playerID int) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.participant(playerID, battleID)
+	if b == nil {
+		return 0, models.ErrNoRecord
+	}
+	ownBoardID, targetBoardID := b.player2BoardID, b.player1BoardID
+	if b.player1ID == playerID {
+		ownBoardID, targetBoardID = b.player1BoardID, b.player2BoardID
+	}
+	own, err := m.Store.loadBoard(m.Fleet, ownBoardID)
+	if err != nil {
+		return 0, err
+	}
+	// Never more than the squares left on the other board (once it's there)
+	unstruck := own.Size * own.Size
+	if targetBoardID != 0 {
+		target, err := m.Store.loadBoard(m.Fleet, targetBoardID)
+		if err != nil {
+			return 0, err
+		}
+		unstruck = target.Unstruck()
+	}
+	return game.Rules{Mode: b.rules, SalvoShots: b.salvoShots}.ShotsPerTurn(own.SurvivingShips(), unstruck), nil
+}
+
+// Update - a single shot; this is a one shot Volley
+// - Return the pinColor and shipType (if sunk)
+func (m *PositionModel) Update(playerID int, playerTakingTheirTurn int, battleID int, boardID int, coordX int, coordY, secretTurn, newSecretTurn string) (string, string, bool, error) {
+	outcomes, winner, err := m.Volley(playerID, playerTakingTheirTurn, battleID, boardID, []game.Shot{{CoordX: coordX, CoordY: coordY}}, secretTurn, newSecretTurn)
+	if err != nil {
+		return "", "", false, err
+	}
+	return outcomes[0].PinColor(), outcomes[0].SunkShip(), winner, nil
+}
+
+// Volley - record every shot of a turn and pass the turn, all or nothing
+// - secretTurn has to be the one this player was handed for this turn
+// - Everything is checked and resolved before anything is written
+func (m *PositionModel) Volley(playerID int, playerTakingTheirTurn int, battleID int, boardID int, shots []game.Shot, secretTurn, newSecretTurn string) ([]game.Outcome, bool, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	b := m.Store.participant(playerID, battleID)
+	if b == nil {
+		return nil, false, models.ErrNoRecord
+	}
+	if secretTurn == "" || b.turn != playerTakingTheirTurn || b.secretTurn != secretTurn || b.winner != 0 ||
+		(b.status != models.StatusSetup && b.status != models.StatusInProgress) {
+		return nil, false, models.ErrStaleTurn
+	}
+	turn, ownBoardID, sunk := b.player2ID, b.player1BoardID, &b.player2Sunk
+	if b.player1ID != playerTakingTheirTurn {
+		turn, ownBoardID, sunk = b.player1ID, b.player2BoardID, &b.player1Sunk
+	}
+	// Shots only ever land on the other player's board in this battle
+	if boardID == ownBoardID || (boardID != b.player1BoardID && boardID != b.player2BoardID) {
+		return nil, false, models.ErrNotYourBattle
+	}
+
+	// The rules decide how many shots make up a turn
+	own, err := m.Store.loadBoard(m.Fleet, ownBoardID)
+	if err != nil {
+		return nil, false, err
+	}
+	board, err := m.Store.loadBoard(m.Fleet, boardID)
+	if err != nil {
+		return nil, false, err
+	}
+	required := game.Rules{Mode: b.rules, SalvoShots: b.salvoShots}.ShotsPerTurn(own.SurvivingShips(), board.Unstruck())
+	if len(shots) != required {
+		return nil, false, fmt.Errorf("%w: need %d, got %d", game.ErrWrongShotCount, required, len(shots))
+	}
+
+	// Let the game engine resolve the volley against the target board
+	outcomes, err := board.Volley(shots)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Record the pins: red on a ship square, a new gray one for a miss
+	for _, o := range outcomes {
+		if o.Result == game.Miss {
+			m.Store.positions = append(m.Store.positions, &position{
+				id:       len(m.Store.positions) + 1,
+				boardID:  boardID,
+				playerID: playerID,
+				coordX:   o.Shot.CoordX,
+				coordY:   o.Shot.CoordY,
+				pinColor: o.PinColor(),
+			})
+			continue
+		}
+		for _, p := range m.Store.positions {
+			if p.boardID == boardID && p.coordX == o.Shot.CoordX && p.coordY == o.Shot.CoordY {
+				p.pinColor = o.PinColor()
+			}
+		}
+		if o.Result == game.Sunk {
+			*sunk++
+		}
+	}
+	m.Store.recordStrikes(battleID, playerTakingTheirTurn, boardID, outcomes)
+
+	// The whole volley is in; now it's the other player's turn
+	// - The first shot of the battle moves it from setup to in_progress
+	// - The other player's turn clock starts now
+	b.turn = turn
+	b.secretTurn = newSecretTurn
+	b.status = models.StatusInProgress
+	m.Store.startClock(b)
+	winner := false
+	if board.FleetDestroyed() && b.winner == 0 {
+		b.winner = playerID
+		b.status = models.StatusFinished
+		m.Store.rate(b, playerID)
+		winner = true
+	}
+	return outcomes, winner, nil
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/rating"
+)
+
+// RatingModel - players' ratings, records and rating history (see pkg/rating)
+type RatingModel struct {
+	Store *Store
+}
+
+// rate - the winner of a battle takes rating points from the loser (the caller holds the lock)
+// - Both players' win/loss records and streaks move, and each gets a history row
+func (s *Store) rate(b *battle, winnerID int) {
+	loserID := b.player1ID
+	if winnerID == b.player1ID {
+		loserID = b.player2ID
+	}
+	winner, loser := s.player(winnerID), s.player(loserID)
+	if winner == nil || loser == nil {
+		return
+	}
+	oldWinner, oldLoser := winner.rating, loser.rating
+	winner.rating, loser.rating = rating.Update(oldWinner, oldLoser)
+
+	winner.wins++
+	if winner.streak > 0 {
+		winner.streak++
+	} else {
+		winner.streak = 1
+	}
+	loser.losses++
+	if loser.streak < 0 {
+		loser.streak--
+	} else {
+		loser.streak = -1
+	}
+
+	now := s.now()
+	record := func(p, opponent *player, old int) {
+		s.ratings = append(s.ratings, &models.Rating{
+			ID:         len(s.ratings) + 1,
+			PlayerID:   p.id,
+			BattleID:   b.id,
+			OpponentID: opponent.id,
+			Rating:     p.rating,
+			Delta:      p.rating - old,
+			Created:    now,
+		})
+	}
+	record(winner, loser, oldWinner)
+	record(loser, winner, oldLoser)
+}
+
+// History - every change to a player's rating, newest first
+func (m *RatingModel) History(playerID int) ([]*models.Rating, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ratings := []*models.Rating{}
+	for i := len(m.Store.ratings) - 1; i >= 0; i-- {
+		if m.Store.ratings[i].PlayerID != playerID {
+			continue
+		}
+		r := *m.Store.ratings[i]
+		r.OpponentScreenName = m.Store.screenName(r.OpponentID)
+		ratings = append(ratings, &r)
+	}
+	return ratings, nil
+}
+
+// Leaderboard - the best rated players who have finished a battle, best first
+func (m *RatingModel) Leaderboard(limit int) ([]*models.Player, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	players := []*models.Player{}
+	for _, p := range m.Store.players {
+		if p.wins+p.losses > 0 {
+			players = append(players, p.model())
+		}
+	}
+	sort.SliceStable(players, func(i, j int) bool {
+		a, b := players[i], players[j]
+		if a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.ScreenName < b.ScreenName
+	})
+	if len(players) > limit {
+		players = players[:limit]
+	}
+	return players, nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type ShipModel struct {
+	Store *Store
+}
+
+func (m *ShipModel) List() ([]*models.Ship, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	ships := []*models.Ship{}
+	for _, s := range m.Store.ships {
+		ship := *s
+		ships = append(ships, &ship)
+	}
+	return ships, nil
+}
+
+// Sync - make sure every ship in the fleet is known, with the fleet's length
+// - ErrFleetMismatch (and nothing changes) if boards already placed have ships
+//   the fleet doesn't, or ships of another length
+func (m *ShipModel) Sync(fleet game.Fleet) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	squares := map[int]map[string]int{}
+	for _, p := range m.Store.positions {
+		if p.shipType == "" {
+			continue
+		}
+		if squares[p.boardID] == nil {
+			squares[p.boardID] = map[string]int{}
+		}
+		squares[p.boardID][p.shipType]++
+	}
+	if err := models.FleetMismatch(fleet, squares); err != nil {
+		return err
+	}
+	known := map[string]*models.Ship{}
+	for _, s := range m.Store.ships {
+		known[s.Title] = s
+	}
+	for _, s := range fleet {
+		if k, ok := known[s.Type]; ok {
+			k.Length = s.Length
+			continue
+		}
+		m.Store.ships = append(m.Store.ships, &models.Ship{ID: len(m.Store.ships) + 1, Title: s.Type, Length: s.Length})
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// StrikeModel - the history of every battle, one entry per shot
+type StrikeModel struct {
+	Store *Store
+}
+
+// recordStrikes - add a turn's shots to the battle's history (the caller holds the lock)
+func (s *Store) recordStrikes(battleID, playerID, boardID int, outcomes []game.Outcome) {
+	move := 1
+	for _, st := range s.strikes {
+		if st.BattleID == battleID && st.Move >= move {
+			move = st.Move + 1
+		}
+	}
+	now := s.now()
+	for _, o := range outcomes {
+		s.strikes = append(s.strikes, &models.Strike{
+			ID:       len(s.strikes) + 1,
+			BattleID: battleID,
+			Move:     move,
+			PlayerID: playerID,
+			BoardID:  boardID,
+			CoordX:   o.Shot.CoordX,
+			CoordY:   o.Shot.CoordY,
+			Result:   o.Result.String(),
+			ShipType: o.Ship,
+			Created:  now,
+		})
+	}
+}
+
+// List - every shot fired in a battle, in the order they were fired
+func (m *StrikeModel) List(battleID int) ([]*models.Strike, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	strikes := []*models.Strike{}
+	for _, st := range m.Store.strikes {
+		if st.BattleID == battleID {
+			strike := *st
+			strikes = append(strikes, &strike)
+		}
+	}
+	return strikes, nil
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"sort"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type TokenModel struct {
+	Store *Store
+}
+
+// Tokens look like "bst_<43 random characters>"; only a hash is kept
+const tokenPrefix = "bst_"
+
+func hashToken(t string) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *token) model(screenName string) *models.APIToken {
+	return &models.APIToken{
+		ID:         t.id,
+		PlayerID:   t.playerID,
+		ScreenName: screenName,
+		Name:       t.name,
+		Prefix:     t.prefix,
+		Scopes:     append([]string(nil), t.scopes...),
+		Created:    t.created,
+		LastUsed:   sql.NullTime{Time: t.lastUsed, Valid: !t.lastUsed.IsZero()},
+	}
+}
+
+// Insert - create a token for a player
+// - Returns the token itself; this is the only time it is ever available
+func (m *TokenModel) Insert(playerID int, name string, scopes []string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+	m.Store.tokens = append(m.Store.tokens, &token{
+		id:        len(m.Store.tokens) + 1,
+		playerID:  playerID,
+		name:      name,
+		prefix:    secret[:len(tokenPrefix)+6],
+		tokenHash: hashToken(secret),
+		scopes:    append([]string(nil), scopes...),
+		created:   m.Store.now(),
+	})
+	return secret, nil
+}
+
+// List - a player's tokens that haven't been revoked, newest first
+func (m *TokenModel) List(playerID int) ([]*models.APIToken, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	tokens := []*models.APIToken{}
+	for _, t := range m.Store.tokens {
+		if t.playerID == playerID && !t.revoked {
+			tokens = append(tokens, t.model(m.Store.screenName(playerID)))
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].ID > tokens[j].ID })
+	return tokens, nil
+}
+
+// Revoke - a player can only revoke their own tokens
+func (m *TokenModel) Revoke(playerID, tokenID int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	for _, t := range m.Store.tokens {
+		if t.id == tokenID && t.playerID == playerID && !t.revoked {
+			t.revoked = true
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
+
+// Authenticate - who does this bearer token belong to?
+// - Unknown and revoked tokens are both ErrInvalidCredentials
+func (m *TokenModel) Authenticate(secret string) (*models.APIToken, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	hash := hashToken(secret)
+	for _, t := range m.Store.tokens {
+		if t.tokenHash == hash && !t.revoked {
+			t.lastUsed = m.Store.now()
+			return t.model(m.Store.screenName(t.playerID)), nil
+		}
+	}
+	return nil, models.ErrInvalidCredentials
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// WebhookModel - players' webhooks and the log of what was sent to them
+type WebhookModel struct {
+	Store *Store
+}
+
+// copy - a webhook the caller can keep (the caller holds the lock)
+func (h *webhook) copy() *models.Webhook {
+	saved := h.Webhook
+	saved.Events = append([]string(nil), h.Events...)
+	return &saved
+}
+
+// Delete - a player can only delete their own webhooks
+// - It stays in the store so its delivery log still has something to point at
+func (m *WebhookModel) Delete(playerID, webhookID int) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if webhookID < 1 || webhookID > len(m.Store.webhooks) {
+		return models.ErrNoRecord
+	}
+	h := m.Store.webhooks[webhookID-1]
+	if h.PlayerID != playerID || h.deleted {
+		return models.ErrNoRecord
+	}
+	h.deleted = true
+	return nil
+}
+
+// Deliveries - the most recent deliveries to a webhook, newest first
+func (m *WebhookModel) Deliveries(webhookID, limit int) ([]*models.WebhookDelivery, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	deliveries := []*models.WebhookDelivery{}
+	for i := len(m.Store.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if d := m.Store.deliveries[i]; d.WebhookID == webhookID {
+			saved := *d
+			deliveries = append(deliveries, &saved)
+		}
+	}
+	return deliveries, nil
+}
+
+// Get - one of a player's webhooks; ErrNoRecord if it isn't theirs (or was deleted)
+func (m *WebhookModel) Get(playerID, webhookID int) (*models.Webhook, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if webhookID < 1 || webhookID > len(m.Store.webhooks) {
+		return nil, models.ErrNoRecord
+	}
+	h := m.Store.webhooks[webhookID-1]
+	if h.PlayerID != playerID || h.deleted {
+		return nil, models.ErrNoRecord
+	}
+	return h.copy(), nil
+}
+
+// Insert - register a webhook for a player
+func (m *WebhookModel) Insert(playerID int, url, secret string, events []string) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	id := len(m.Store.webhooks) + 1
+	m.Store.webhooks = append(m.Store.webhooks, &webhook{Webhook: models.Webhook{
+		ID:       id,
+		PlayerID: playerID,
+		URL:      url,
+		Secret:   secret,
+		Events:   append([]string(nil), events...),
+		Created:  m.Store.now(),
+	}})
+	return id, nil
+}
+
+// List - a player's webhooks, oldest first
+func (m *WebhookModel) List(playerID int) ([]*models.Webhook, error) {
+	return m.Listening(playerID, "")
+}
+
+// Listening - a player's webhooks that want an event ("" for all of them)
+func (m *WebhookModel) Listening(playerID int, event string) ([]*models.Webhook, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	webhooks := []*models.Webhook{}
+	for _, h := range m.Store.webhooks {
+		if h.PlayerID == playerID && !h.deleted && (event == "" || h.Wants(event)) {
+			webhooks = append(webhooks, h.copy())
+		}
+	}
+	return webhooks, nil
+}
+
+// LogDelivery - start the log entry for an event on its way to a webhook (pending, no attempts yet)
+func (m *WebhookModel) LogDelivery(webhookID, battleID int, event, payload string) (int, error) {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	now := m.Store.now()
+	id := len(m.Store.deliveries) + 1
+	m.Store.deliveries = append(m.Store.deliveries, &models.WebhookDelivery{
+		ID:        id,
+		WebhookID: webhookID,
+		BattleID:  battleID,
+		Event:     event,
+		Payload:   payload,
+		Status:    models.DeliveryPending,
+		Created:   now,
+		Updated:   now,
+	})
+	return id, nil
+}
+
+// UpdateDelivery - record how the latest attempt went
+func (m *WebhookModel) UpdateDelivery(deliveryID, attempts, statusCode int, status, errorText string) error {
+	m.Store.mu.Lock()
+	defer m.Store.mu.Unlock()
+
+	if deliveryID < 1 || deliveryID > len(m.Store.deliveries) {
+		return models.ErrNoRecord
+	}
+	d := m.Store.deliveries[deliveryID-1]
+	d.Attempts = attempts
+	d.StatusCode = statusCode
+	d.Status = status
+	d.Error = errorText
+	d.Updated = m.Store.now()
+	return nil
+}
@@ -7,10 +7,16 @@ import (
 )
 
 var (
+	ErrBoardSizeMismatch = errors.New("models: board size does not match the battle")
 	ErrMissingBoardID = errors.New("Missing BoardID")
 	ErrNoRecord = errors.New("models: no matching record found")
 	ErrInvalidCredentials = errors.New("models: invalid credentials")
+	ErrNotYourBattle = errors.New("models: player is not part of this battle")
 	ErrDuplicateScreenName = errors.New("models: duplicate screen name")
+	ErrDuplicateBoardName = errors.New("models: duplicate board name")
+	ErrStaleTurn = errors.New("models: not this player's turn (or the turn has already been taken)")
+	ErrBattleStatus = errors.New("models: the battle can't get there from where it is")
+	ErrFleetMismatch = errors.New("models: saved boards were made with a different fleet")
 )
 
 type About struct {
@@ -32,6 +38,65 @@ type Battle struct {
 	ChallengerBoardName		sql.NullString
 	ChallengeDate			time.Time
 	Turn      				sql.NullInt64
+	BoardSize				int
+	Rules					string
+	SalvoShots				int
+	Winner					int
+	AIStrategy				string
+	Status					BattleStatus
+	TurnTime				time.Duration			// 0 is no turn clock
+	Deadline				sql.NullTime			// when the player whose move it is runs out of time
+	Player1Private			bool					// the challenger keeps spectators out
+	Player2Private			bool					// the opponent keeps spectators out
+}
+
+// Private - does either player keep spectators out?
+func (b *Battle) Private() bool {
+	return b.Player1Private || b.Player2Private
+}
+
+// Watchable - may other players watch this battle?
+// - Once it has been accepted (the pins stay on view after it's over), unless a player keeps it private
+func (b *Battle) Watchable() bool {
+	if b.Private() {
+		return false
+	}
+	switch b.Status {
+	case StatusSetup, StatusInProgress, StatusFinished, StatusAbandoned:
+		return true
+	}
+	return false
+}
+
+// API token scopes
+// - read: every GET under /api/v1
+// - play: create boards, challenge, accept, decline, withdraw, join the matchmaking queue, strike and resign
+const (
+	ScopeRead = "read"
+	ScopePlay = "play"
+)
+
+// APIToken - a bearer token a player created for a non-browser client
+// - Only a hash of the token is stored; Prefix is enough to recognize it
+type APIToken struct {
+	ID						int
+	PlayerID				int
+	ScreenName				string
+	Name					string
+	Prefix					string
+	Scopes					[]string
+	Created					time.Time
+	LastUsed				sql.NullTime
+}
+
+// HasScope - may this token be used for that?
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 type Board struct {
@@ -40,6 +105,9 @@ type Board struct {
 	PlayerID 				int
 	BattleID  				sql.NullInt64
 	Created 				time.Time
+	BoardSize				int
+	TemplateID				int						// the saved board a battle's copy was made from
+	Battles					int						// how many battles a saved board has been copied into
 }
 
 type Login struct {
@@ -58,8 +126,34 @@ type Player struct {
 	InBattle   				sql.NullString
 	Created  				sql.NullString
 	LastLogin  				sql.NullString
+	IsComputer				bool
+	Rating					int						// see pkg/rating
+	Wins					int
+	Losses					int
+	Streak					int						// wins (positive) or losses (negative) in a row
+}
+
+// Notification - something that happened in one of a player's battles, kept in their inbox
+// - Message is plain text; pages escape it when they show it
+type Notification struct {
+	ID						int
+	PlayerID				int
+	BattleID				int
+	Kind					string
+	Message					string
+	Read					bool
+	Created					time.Time
 }
 
+// Kinds of notification
+const (
+	NoticeChallenge = "challenge"		// someone challenged you
+	NoticeAccept    = "accept"			// a challenge you issued was accepted (or matchmaking found you a battle)
+	NoticeTurn      = "turn"			// it's your move
+	NoticeGameOver  = "game_over"		// one of your battles has a winner
+	NoticeExpired   = "expired"			// a challenge ran out of time before it was answered
+)
+
 type Position struct {
 	ID      				int
 	PlayerID 				int
@@ -71,6 +165,46 @@ type Position struct {
 	PinColor				string
 }
 
+// Strike - one shot in a battle's history
+// - Every shot fired in the same turn (a salvo) shares a Move number
+// - ShipType is the ship that was hit, if any
+type Strike struct {
+	ID						int
+	BattleID				int
+	Move					int
+	PlayerID				int
+	BoardID					int
+	CoordX					int
+	CoordY					string
+	Result					string
+	ShipType				string
+	Created					time.Time
+}
+
+// Message - something one player in a battle said to the other
+// - Body is kept exactly as it was typed; pages escape it when they show it
+type Message struct {
+	ID						int
+	BattleID				int
+	PlayerID				int
+	ScreenName				string
+	Body					string
+	Created					time.Time
+}
+
+// Rating - one change to a player's rating, made when a battle they were in got a winner
+// - Rating is where it ended up; Delta is how far it moved (negative for a loss)
+type Rating struct {
+	ID						int
+	PlayerID				int
+	BattleID				int
+	OpponentID				int
+	OpponentScreenName		string
+	Rating					int
+	Delta					int
+	Created					time.Time
+}
+
 type Signup struct {
 	ID       	  			int
 	ScreenName 				string
@@ -83,3 +217,54 @@ type Ship struct {
 	Length 					int
 	Title  					string
 }
+
+// Webhook - a URL a player wants the events of their battles sent to (see pkg/webhook)
+// - Secret signs every payload, so the receiver can tell it came from this server
+// - Events is what it asked for; empty means everything
+type Webhook struct {
+	ID						int
+	PlayerID				int
+	URL						string
+	Secret					string
+	Events					[]string
+	Created					time.Time
+}
+
+// Wants - should this webhook get that event?
+func (h *Webhook) Wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Where a webhook delivery stands
+// - pending: still being tried
+// - delivered: the receiver answered 2xx
+// - failed: it gave up (see webhook.Result.Retry)
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery - one event sent to a webhook, and how the last attempt went
+// - StatusCode is 0 when the receiver never answered; Error says why
+type WebhookDelivery struct {
+	ID						int
+	WebhookID				int
+	BattleID				int
+	Event					string
+	Payload					string
+	Status					string
+	Attempts				int
+	StatusCode				int
+	Error					string
+	Created					time.Time
+	Updated					time.Time
+}
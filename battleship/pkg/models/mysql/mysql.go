@@ -0,0 +1,215 @@
+// Package mysql - what the models need to run on MySQL (see pkg/models/sqldb)
+// - The SQL itself is shared with SQLite; this is the Dialect, Open and the schema
+// - Every table has a "rowid" primary key, just like SQLite's built-in one, so
+//   the shared queries work on both
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	driver "github.com/go-sql-driver/mysql"
+
+	"github.com/519seven/cs610/battleship/pkg/migrate"
+	"github.com/519seven/cs610/battleship/pkg/models/sqldb"
+)
+
+// MySQL's error number for a row that breaks a UNIQUE key
+const errDuplicateEntry = 1062
+
+// Dialect - how MySQL differs from SQLite
+var Dialect = sqldb.Dialect{
+	Name: "mysql",
+	IsDuplicate: func(err error) bool {
+		var mysqlErr *driver.MySQLError
+		return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
+	},
+}
+
+// Open - connect to MySQL
+// - DATETIME columns are scanned into time.Time, so parseTime is always on
+func Open(dsn string) (*sql.DB, error) {
+	cfg, err := driver.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParseTime = true
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func hasTable(name string) string {
+	return `SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = '` + name + `'`
+}
+
+// Migrations - the MySQL schema, oldest first (see sqlite3.Migrations)
+// - Never edit one that has shipped; add another
+var Migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: []string{
+			`CREATE TABLE Battles (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				player1ID INTEGER, player1Accepted BOOLEAN, player1BoardID INTEGER,
+				player2ID INTEGER, player2Accepted BOOLEAN, player2BoardID INTEGER,
+				player1SunkenShips INTEGER DEFAULT 0, player2SunkenShips INTEGER DEFAULT 0,
+				challengeDate DATETIME DEFAULT CURRENT_TIMESTAMP,
+				turn INTEGER, secretTurn VARCHAR(64), winner INTEGER DEFAULT 0,
+				boardSize INTEGER DEFAULT 10, rules VARCHAR(16) DEFAULT 'classic',
+				salvoShots INTEGER DEFAULT 0, aiStrategy VARCHAR(16) DEFAULT '')`,
+			`CREATE TABLE Boards (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				boardName VARCHAR(255), playerID INTEGER,
+				created DATETIME DEFAULT CURRENT_TIMESTAMP, isChosen BOOLEAN,
+				boardSize INTEGER DEFAULT 10)`,
+			`CREATE TABLE Players (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				screenName VARCHAR(64) NOT NULL UNIQUE, emailAddress VARCHAR(255),
+				hashedPassword VARCHAR(255), created DATETIME, loggedIn BOOLEAN,
+				inBattle BOOLEAN, lastLogin DATETIME, isComputer BOOLEAN DEFAULT 0)`,
+			`CREATE TABLE Positions (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				boardID INTEGER, shipID INTEGER, playerID INTEGER,
+				coordX INTEGER, coordY VARCHAR(2), pinColor VARCHAR(8))`,
+			`CREATE TABLE Ships (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				shipType VARCHAR(64), shipLength INTEGER)`,
+			`CREATE TABLE ApiTokens (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				playerID INTEGER, name VARCHAR(255), prefix VARCHAR(16), tokenHash VARCHAR(64) UNIQUE,
+				scopes VARCHAR(255), created DATETIME, lastUsed DATETIME, revoked BOOLEAN DEFAULT 0)`,
+			`CREATE TABLE Strikes (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				battleID INTEGER, move INTEGER, playerID INTEGER, boardID INTEGER,
+				coordX INTEGER, coordY VARCHAR(2), result VARCHAR(8), shipType VARCHAR(64), created DATETIME)`,
+		},
+		Down: []string{
+			`DROP TABLE Strikes`,
+			`DROP TABLE ApiTokens`,
+			`DROP TABLE Ships`,
+			`DROP TABLE Positions`,
+			`DROP TABLE Players`,
+			`DROP TABLE Boards`,
+			`DROP TABLE Battles`,
+		},
+		Present: hasTable("Battles"),
+	},
+	{
+		// A battle plays on copies of the players' saved boards (templateID is the
+		// saved board, battleID the battle); the saved boards themselves never get pins
+		Version: 2,
+		Name:    "board instances",
+		Up: []string{
+			`ALTER TABLE Boards ADD COLUMN templateID INTEGER DEFAULT 0`,
+			`ALTER TABLE Boards ADD COLUMN battleID INTEGER DEFAULT 0`,
+		},
+		Down: []string{
+			`ALTER TABLE Boards DROP COLUMN battleID`,
+			`ALTER TABLE Boards DROP COLUMN templateID`,
+		},
+	},
+	{
+		// Battles from before this get the status their columns add up to
+		Version: 3,
+		Name:    "battle status",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN status VARCHAR(16) DEFAULT 'challenged'`,
+			`UPDATE Battles SET status = 'setup' WHERE IFNULL(player2Accepted, 0) != 0`,
+			`UPDATE Battles SET status = 'in_progress' WHERE status = 'setup'
+				AND (rowid IN (SELECT battleID FROM Strikes)
+				OR player1BoardID IN (SELECT boardID FROM Positions WHERE pinColor IN ('red', 'gray'))
+				OR player2BoardID IN (SELECT boardID FROM Positions WHERE pinColor IN ('red', 'gray')))`,
+			`UPDATE Battles SET status = 'finished' WHERE IFNULL(winner, 0) != 0`,
+		},
+		Down: []string{`ALTER TABLE Battles DROP COLUMN status`},
+	},
+	{
+		// turnTime is the turn clock in seconds (0 is none); deadline is when
+		// whoever has to move next runs out of time
+		Version: 4,
+		Name:    "turn clock",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN turnTime INTEGER DEFAULT 0`,
+			`ALTER TABLE Battles ADD COLUMN deadline DATETIME(6)`,
+		},
+		Down: []string{
+			`ALTER TABLE Battles DROP COLUMN deadline`,
+			`ALTER TABLE Battles DROP COLUMN turnTime`,
+		},
+	},
+	{
+		// Every player starts at 1500 (see pkg/rating); battles won before this don't count
+		// - Ratings is the history: one row per player per rated battle
+		Version: 5,
+		Name:    "ratings",
+		Up: []string{
+			`ALTER TABLE Players ADD COLUMN rating INTEGER DEFAULT 1500`,
+			`ALTER TABLE Players ADD COLUMN wins INTEGER DEFAULT 0`,
+			`ALTER TABLE Players ADD COLUMN losses INTEGER DEFAULT 0`,
+			`ALTER TABLE Players ADD COLUMN streak INTEGER DEFAULT 0`,
+			`CREATE TABLE Ratings (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				playerID INTEGER, battleID INTEGER, opponentID INTEGER,
+				rating INTEGER, delta INTEGER, created DATETIME)`,
+		},
+		Down: []string{
+			`DROP TABLE Ratings`,
+			`ALTER TABLE Players DROP COLUMN streak`,
+			`ALTER TABLE Players DROP COLUMN losses`,
+			`ALTER TABLE Players DROP COLUMN wins`,
+			`ALTER TABLE Players DROP COLUMN rating`,
+		},
+	},
+	{
+		// Either player can keep spectators out of their battle
+		Version: 6,
+		Name:    "spectators",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN player1Private BOOLEAN DEFAULT 0`,
+			`ALTER TABLE Battles ADD COLUMN player2Private BOOLEAN DEFAULT 0`,
+		},
+		Down: []string{
+			`ALTER TABLE Battles DROP COLUMN player2Private`,
+			`ALTER TABLE Battles DROP COLUMN player1Private`,
+		},
+	},
+	{
+		// What the two players in a battle say to each other
+		Version: 7,
+		Name:    "chat",
+		Up: []string{
+			`CREATE TABLE Messages (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				battleID INTEGER, playerID INTEGER, body TEXT, created DATETIME)`,
+		},
+		Down: []string{`DROP TABLE Messages`},
+	},
+	{
+		// Each player's inbox: challenges, accepted challenges, turns and results
+		Version: 8,
+		Name:    "notifications",
+		Up: []string{
+			`CREATE TABLE Notifications (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				playerID INTEGER, battleID INTEGER, kind TEXT, message TEXT,
+				isRead BOOLEAN DEFAULT 0, created DATETIME)`,
+		},
+		Down: []string{`DROP TABLE Notifications`},
+	},
+	{
+		// Webhooks players register, and the log of every event sent to them
+		Version: 9,
+		Name:    "webhooks",
+		Up: []string{
+			`CREATE TABLE Webhooks (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				playerID INTEGER, url TEXT, secret VARCHAR(64), events VARCHAR(255),
+				created DATETIME, deleted BOOLEAN DEFAULT 0)`,
+			`CREATE TABLE WebhookDeliveries (rowid INTEGER AUTO_INCREMENT PRIMARY KEY,
+				webhookID INTEGER, battleID INTEGER, event VARCHAR(16), payload TEXT,
+				status VARCHAR(16), attempts INTEGER DEFAULT 0, statusCode INTEGER DEFAULT 0, error TEXT,
+				created DATETIME, updated DATETIME)`,
+		},
+		Down: []string{
+			`DROP TABLE WebhookDeliveries`,
+			`DROP TABLE Webhooks`,
+		},
+	},
+}
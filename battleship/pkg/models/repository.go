@@ -0,0 +1,107 @@
+package models
+
+import (
+	"github.com/519seven/cs610/battleship/pkg/game"
+)
+
+// Repositories - everything the web application asks of a database
+// - pkg/models/sqldb (on SQLite or MySQL) and pkg/models/memory are the
+//   implementations; main picks one with the -driver flag
+// - Only what the handlers use is here; the implementations may do more
+
+type BattleRepository interface {
+	Accept(player2ID, boardID, battleID int) (int, error)
+	CheckBoardOwner(playerID, battleID, boardID int) bool
+	CheckTurn(battleID, playerID int) (int, string)
+	Create(player1ID, player1BoardID, player2ID int, secretTurn string, rules game.Rules) (int, error)
+	Decline(playerID, battleID int) error
+	Expire(battleID int) error
+	Forfeit(battleID int) (int, error)
+	Get(playerID, battleID int) (*Battle, error)
+	GetChallenger(currentPlayerID int) (int, error)
+	GetChallenges(playerID int) ([]*Battle, error)
+	GetOpen(playerID, battleID int) ([]*Battle, error)
+	ListWatchable(playerID int) ([]*Battle, error)
+	Overdue() ([]*Battle, error)
+	Resign(playerID, battleID int) (int, error)
+	SetPrivate(playerID, battleID int, private bool) error
+	SetStrategy(battleID int, strategy string) error
+	UpdateChallenge(player1 int, player2 int, player2Accepted bool, battleID int) error
+	Waiting(playerID int) ([]*Battle, error)
+	Watch(battleID int) (*Battle, error)
+	Withdraw(playerID, battleID int) error
+}
+
+type BoardRepository interface {
+	Create(playerID int, boardName string, boardSize int) (int, error)
+	GetInfo(playerID, boardID int) (*Board, error)
+	GetPositions(boardID int) ([]*Position, error)
+	Insert(playerID int, boardID int, shipName string, arrayOfCoords []string) (int, error)
+	List(playerID int) ([]*Board, error)
+	Update(boardID int, boardName string, playerID int) (int, error)
+}
+
+type MessageRepository interface {
+	Insert(playerID, battleID int, body string) (*Message, error)
+	List(battleID int) ([]*Message, error)
+}
+
+type NotificationRepository interface {
+	Insert(playerID, battleID int, kind, message string) (int, error)
+	List(playerID, limit int) ([]*Notification, error)
+	MarkAllRead(playerID int) error
+	MarkBattleRead(playerID, battleID int) error
+	MarkRead(playerID, notificationID int) error
+	Unread(playerID int) (int, error)
+}
+
+type PlayerRepository interface {
+	Authenticate(screenName, password string) (int, error)
+	EnsureComputer(screenName string) (int, error)
+	Get(playerID int) (*Player, error)
+	Insert(screenName string, emailAddress string, password string) (int, error)
+	List(playerID int, status string) ([]*Player, error)
+	Update(playerID int, emailAddress string) (int, error)
+	UpdateLogin(playerID int, loggedIn bool) (int, error)
+}
+
+type PositionRepository interface {
+	CheckWinner(playerID, battleID int) (bool, error)
+	Knowledge(boardID int) (game.Knowledge, error)
+	List(boardID, playerID int) ([]*Position, error)
+	ShotsPerTurn(battleID, playerID int) (int, error)
+	Update(playerID int, playerTakingTheirTurn int, battleID int, boardID int, coordX int, coordY, secretTurn, newSecretTurn string) (string, string, bool, error)
+	Volley(playerID int, playerTakingTheirTurn int, battleID int, boardID int, shots []game.Shot, secretTurn, newSecretTurn string) ([]game.Outcome, bool, error)
+}
+
+type RatingRepository interface {
+	History(playerID int) ([]*Rating, error)
+	Leaderboard(limit int) ([]*Player, error)
+}
+
+type ShipRepository interface {
+	List() ([]*Ship, error)
+	Sync(fleet game.Fleet) error
+}
+
+type StrikeRepository interface {
+	List(battleID int) ([]*Strike, error)
+}
+
+type TokenRepository interface {
+	Authenticate(token string) (*APIToken, error)
+	Insert(playerID int, name string, scopes []string) (string, error)
+	List(playerID int) ([]*APIToken, error)
+	Revoke(playerID, tokenID int) error
+}
+
+type WebhookRepository interface {
+	Delete(playerID, webhookID int) error
+	Deliveries(webhookID, limit int) ([]*WebhookDelivery, error)
+	Get(playerID, webhookID int) (*Webhook, error)
+	Insert(playerID int, url, secret string, events []string) (int, error)
+	List(playerID int) ([]*Webhook, error)
+	Listening(playerID int, event string) ([]*Webhook, error)
+	LogDelivery(webhookID, battleID int, event, payload string) (int, error)
+	UpdateDelivery(deliveryID, attempts, statusCode int, status, errorText string) error
+}
@@ -0,0 +1,677 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type BattleModel struct {
+	DB *sql.DB
+}
+
+// statusIn - "status IN (?, ...)" for every status that can become this one
+func statusIn(status models.BattleStatus) (string, []interface{}) {
+	var marks []string
+	var args []interface{}
+	for _, from := range status.From() {
+		marks = append(marks, "?")
+		args = append(args, string(from))
+	}
+	return `status IN (` + strings.Join(marks, ", ") + `)`, args
+}
+
+// setStatus - move a battle one step along its lifecycle (see models.BattleStatus)
+// - ErrBattleStatus if the battle isn't somewhere it can go from
+func setStatus(db querier, battleID int, status models.BattleStatus) error {
+	in, args := statusIn(status)
+	stmt := `UPDATE Battles SET status = ? WHERE rowid = ? AND ` + in
+	result, err := db.Exec(stmt, append([]interface{}{string(status), battleID}, args...)...)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 1 {
+		return nil
+	}
+	var current string
+	err = db.QueryRow(`SELECT IFNULL(status, '') FROM Battles WHERE rowid = ?`, battleID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrNoRecord
+	} else if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s to %s", models.ErrBattleStatus, current, status)
+}
+
+// startClock - whoever has to move next has until turnTime from now
+// - A battle without a turn clock has no deadline
+func startClock(db querier, battleID int) error {
+	var turnTime int
+	err := db.QueryRow(`SELECT IFNULL(turnTime, 0) FROM Battles WHERE rowid = ?`, battleID).Scan(&turnTime)
+	if err != nil {
+		return err
+	}
+	var deadline interface{}
+	if turnTime > 0 {
+		deadline = time.Now().UTC().Add(time.Duration(turnTime) * time.Second)
+	}
+	_, err = db.Exec(`UPDATE Battles SET deadline = ? WHERE rowid = ?`, deadline, battleID)
+	return err
+}
+
+// Accept a challenge (battle)
+// - The battle gets its own copy of the board player2 picked (see copyBoard)
+// - challenged -> accepted -> setup
+// - The opponent's turn clock starts now
+func (m *BattleModel) Accept(player2ID, boardID, battleID int) (int, error) {
+	var player2IDFromDB int = 0
+	// Check to be sure that the person accepting this battle is matches the "player2ID"
+	stmt := `SELECT player2ID FROM Battles WHERE rowid = ?`
+	err := m.DB.QueryRow(stmt, battleID).Scan(&player2IDFromDB)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, models.ErrNoRecord
+		}
+		return 0, err
+	}
+
+	if player2ID == player2IDFromDB {
+		// The opponent's board has to be the same size as the challenger's
+		var battleSize, boardSize int
+		stmt = `SELECT IFNULL(b.boardSize, 10), IFNULL(bo.boardSize, 10)
+					FROM Battles b, Boards bo
+					WHERE b.rowid = ? AND bo.rowid = ? AND bo.playerID = ?`
+		err = m.DB.QueryRow(stmt, battleID, boardID, player2ID).Scan(&battleSize, &boardSize)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, models.ErrNoRecord
+			}
+			return 0, err
+		}
+		if battleSize != boardSize {
+			return 0, models.ErrBoardSizeMismatch
+		}
+		tx, err := m.DB.Begin()
+		if err != nil {
+			return 0, err
+		}
+		defer tx.Rollback()
+		if err = setStatus(tx, battleID, models.StatusAccepted); err != nil {
+			return 0, err
+		}
+		copyID, err := copyBoard(tx, player2ID, boardID, battleID)
+		if err != nil {
+			return 0, err
+		}
+		// Only player2 can accept a challenge
+		stmt = `UPDATE Battles SET player2Accepted = true, player2BoardID = ? WHERE player2ID = ? AND rowid = ?`
+		_, err = tx.Exec(stmt, copyID, player2ID, battleID)
+		if err != nil {
+			return 0, err
+		}
+		// Both boards are in; the opponent fires first
+		if err = setStatus(tx, battleID, models.StatusSetup); err != nil {
+			return 0, err
+		}
+		if err = startClock(tx, battleID); err != nil {
+			return 0, err
+		}
+		if err = tx.Commit(); err != nil {
+			return 0, err
+		}
+		return battleID, nil
+	}
+	return 0, models.ErrNotYourBattle
+}
+
+
+// GetBoardOwner - Ensure the player is the owner of this board and this board is part of this battle
+func (m *BattleModel) CheckBoardOwner(playerID, battleID, boardID int) bool {
+	var battleIDEntry int = 0
+	stmt := `SELECT rowid
+				FROM Battles
+				WHERE
+				((player1ID = ? AND player1BoardID = ?)
+				OR
+				(player2ID = ? AND player2BoardID = ?))
+				AND rowid = ?`
+	err := m.DB.QueryRow(stmt, playerID, boardID, playerID, boardID, battleID).Scan(&battleIDEntry)
+	if err != nil {
+		return false
+	}
+	return battleIDEntry != 0
+}
+
+
+// Check that the person logged in should be updating this battle
+func (m *BattleModel) CheckChallenger(player1ID, battleID, player2BoardID int) bool {
+	return true	// Return true
+}
+
+
+// Create a new Battle - record the challenger (player1) and the challengee (player2)
+// - rules are picked by the challenger (classic or salvo, and the turn clock)
+// - The battle gets its own copy of the board player1 picked (see copyBoard)
+func (m *BattleModel) Create(player1ID, player1BoardID, player2ID int, secretTurn string, rules game.Rules) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// A pair of players may have any number of battles going at once
+	// The battle is played on the challenger's board size
+	stmt := `INSERT INTO Battles (player1ID, player1Accepted, player1BoardID, player2ID, player2Accepted, turn, secretTurn, rules, salvoShots, boardSize, status, turnTime) 
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT IFNULL(boardSize, 10) FROM Boards WHERE rowid = ?), ?, ?)`
+	// The opponent will always go first
+	result, err := tx.Exec(stmt, player1ID, 1, 0, player2ID, 0, player2ID, secretTurn, rules.Mode, rules.SalvoShots, player1BoardID, models.StatusChallenged,
+		int(rules.TurnTime/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	battleID := int(id)
+	copyID, err := copyBoard(tx, player1ID, player1BoardID, battleID)
+	if err != nil {
+		return 0, err
+	}
+	stmt = `UPDATE Battles SET player1BoardID = ? WHERE rowid = ?`
+	_, err = tx.Exec(stmt, copyID, battleID)
+	if err != nil {
+		return 0, err
+	}
+	// The opponent has until the turn clock runs out to answer
+	if err = startClock(tx, battleID); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return battleID, nil
+}
+
+// players - who is in this battle
+func players(db querier, battleID int) (int, int, error) {
+	var player1ID, player2ID int
+	stmt := `SELECT player1ID, player2ID FROM Battles WHERE rowid = ?`
+	err := db.QueryRow(stmt, battleID).Scan(&player1ID, &player2ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, models.ErrNoRecord
+	}
+	return player1ID, player2ID, err
+}
+
+// Decline - the opponent (player2) turns the challenge down
+// - challenged -> declined
+func (m *BattleModel) Decline(playerID, battleID int) error {
+	_, player2ID, err := players(m.DB, battleID)
+	if err != nil {
+		return err
+	}
+	if playerID != player2ID {
+		return models.ErrNotYourBattle
+	}
+	return setStatus(m.DB, battleID, models.StatusDeclined)
+}
+
+// Withdraw - the challenger (player1) takes the challenge back before it's accepted
+// - challenged -> cancelled
+func (m *BattleModel) Withdraw(playerID, battleID int) error {
+	player1ID, _, err := players(m.DB, battleID)
+	if err != nil {
+		return err
+	}
+	if playerID != player1ID {
+		return models.ErrNotYourBattle
+	}
+	return setStatus(m.DB, battleID, models.StatusCancelled)
+}
+
+// Resign - a player gives up a battle that has been accepted; the opponent wins
+// - accepted, setup or in_progress -> abandoned
+// - It counts as a loss (see rate)
+// - Returns the winner
+func (m *BattleModel) Resign(playerID, battleID int) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	player1ID, player2ID, err := players(tx, battleID)
+	if err != nil {
+		return 0, err
+	}
+	winner := player1ID
+	switch playerID {
+	case player1ID:
+		winner = player2ID
+	case player2ID:
+	default:
+		return 0, models.ErrNotYourBattle
+	}
+	if err = setStatus(tx, battleID, models.StatusAbandoned); err != nil {
+		return 0, err
+	}
+	stmt := `UPDATE Battles SET winner = ? WHERE rowid = ? AND IFNULL(winner, 0) = 0`
+	if _, err = tx.Exec(stmt, winner, battleID); err != nil {
+		return 0, err
+	}
+	if err = rate(tx, battleID, winner); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return winner, nil
+}
+
+// Expire - nobody answered the challenge before its deadline
+// - challenged -> expired
+// - ErrBattleStatus if it was answered (or the deadline moved) in the meantime
+func (m *BattleModel) Expire(battleID int) error {
+	in, args := statusIn(models.StatusExpired)
+	stmt := `UPDATE Battles SET status = ? WHERE rowid = ? AND deadline <= ? AND ` + in
+	result, err := m.DB.Exec(stmt, append([]interface{}{models.StatusExpired, battleID, time.Now().UTC()}, args...)...)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return models.ErrBattleStatus
+	}
+	return nil
+}
+
+// Forfeit - the player whose turn it is ran out of time; the other player wins
+// - accepted, setup or in_progress -> abandoned
+// - It counts as a loss (see rate)
+// - ErrBattleStatus if they moved (or the battle ended) in the meantime
+// - Returns the winner
+func (m *BattleModel) Forfeit(battleID int) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var player1ID, player2ID, turn int
+	stmt := `SELECT player1ID, player2ID, IFNULL(turn, 0) FROM Battles WHERE rowid = ? AND deadline <= ?`
+	err = tx.QueryRow(stmt, battleID, now).Scan(&player1ID, &player2ID, &turn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, models.ErrBattleStatus
+	} else if err != nil {
+		return 0, err
+	}
+	winner := player1ID
+	if turn == player1ID {
+		winner = player2ID
+	}
+	// The turn is part of the condition so a strike that just got in wins the race
+	in, args := statusIn(models.StatusAbandoned)
+	stmt = `UPDATE Battles SET status = ?, winner = ?
+				WHERE rowid = ? AND turn = ? AND deadline <= ? AND IFNULL(winner, 0) = 0 AND ` + in
+	result, err := tx.Exec(stmt, append([]interface{}{models.StatusAbandoned, winner, battleID, turn, now}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, models.ErrBattleStatus
+	}
+	if err = rate(tx, battleID, winner); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return winner, nil
+}
+
+// A battle with both players' names, for Get, Watch and ListWatchable (see scanBattle)
+const battleSelect = `SELECT b.rowid,
+				p1.rowid as Player1ID, p1.screenName as Player1ScreenName, IFNULL(b.player1BoardID, 0),
+				p2.rowid as Player2ID, p2.screenName as Player2ScreenName, IFNULL(b.player2BoardID, 0),
+				IFNULL(b.boardSize, 10), IFNULL(b.rules, 'classic'), IFNULL(b.salvoShots, 0),
+				IFNULL(b.player1Accepted, 0), IFNULL(b.player2Accepted, 0), b.challengeDate, b.turn, IFNULL(b.winner, 0),
+				IFNULL(b.aiStrategy, ''), IFNULL(b.status, 'challenged'), IFNULL(b.turnTime, 0), b.deadline,
+				IFNULL(b.player1Private, 0), IFNULL(b.player2Private, 0)
+				FROM Battles as b
+				JOIN Players as p1 ON p1.rowid = b.player1ID
+				JOIN Players as p2 ON p2.rowid = b.player2ID`
+
+// scanBattle - one row of battleSelect
+func scanBattle(row interface{ Scan(...interface{}) error }) (*models.Battle, error) {
+	b := &models.Battle{}
+	var turnTime int
+	err := row.Scan(
+		&b.ID,
+		&b.Player1ID, &b.Player1ScreenName, &b.Player1BoardID, 
+		&b.Player2ID, &b.Player2ScreenName, &b.Player2BoardID,
+		&b.BoardSize, &b.Rules, &b.SalvoShots,
+		&b.Player1Accepted, &b.Player2Accepted, &b.ChallengeDate, &b.Turn, &b.Winner,
+		&b.AIStrategy, &b.Status, &turnTime, &b.Deadline,
+		&b.Player1Private, &b.Player2Private)
+	if err != nil {
+		return nil, err
+	}
+	b.Title = b.Player2ScreenName + " vs. " + b.Player1ScreenName
+	b.TurnTime = time.Duration(turnTime) * time.Second
+	return b, nil
+}
+
+// Get - return a single battle; this is for the battle board
+func (m *BattleModel) Get(playerID, battleID int) (*models.Battle, error) {
+	// Get a single battle that is available for this user
+	stmt := battleSelect + ` WHERE (b.player1ID = ? OR b.player2ID = ?) AND b.rowid = ?`
+	b, err := scanBattle(m.DB.QueryRow(stmt, playerID, playerID, battleID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// Watch - a battle as a spectator sees it (see models.Battle.Watchable)
+// - ErrNoRecord if there's no such battle, it hasn't started or a player keeps it private
+func (m *BattleModel) Watch(battleID int) (*models.Battle, error) {
+	b, err := scanBattle(m.DB.QueryRow(battleSelect+` WHERE b.rowid = ?`, battleID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	if !b.Watchable() {
+		return nil, models.ErrNoRecord
+	}
+	return b, nil
+}
+
+// ListWatchable - the battles being played right now that this player could watch, newest first
+// - Not their own, and not the ones a player keeps private
+func (m *BattleModel) ListWatchable(playerID int) ([]*models.Battle, error) {
+	stmt := battleSelect + ` WHERE b.player1ID != ? AND b.player2ID != ?
+				AND IFNULL(b.player1Private, 0) = 0 AND IFNULL(b.player2Private, 0) = 0
+				AND b.status IN (?, ?)
+				ORDER BY b.rowid DESC`
+	rows, err := m.DB.Query(stmt, playerID, playerID, models.StatusSetup, models.StatusInProgress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	battles := []*models.Battle{}
+	for rows.Next() {
+		b, err := scanBattle(rows)
+		if err != nil {
+			return nil, err
+		}
+		battles = append(battles, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return battles, nil
+}
+
+// SetPrivate - a player keeps spectators out of a battle (or lets them back in)
+// - Each player has their own say; the battle is private if either wants it to be
+func (m *BattleModel) SetPrivate(playerID, battleID int, private bool) error {
+	player1ID, player2ID, err := players(m.DB, battleID)
+	if err != nil {
+		return err
+	}
+	column := "player1Private"
+	switch playerID {
+	case player1ID:
+	case player2ID:
+		column = "player2Private"
+	default:
+		return models.ErrNotYourBattle
+	}
+	_, err = m.DB.Exec(`UPDATE Battles SET `+column+` = ? WHERE rowid = ?`, private, battleID)
+	return err
+}
+
+// GetChallenger - See if there are any challengers out there
+func (m *BattleModel) GetChallenger(currentPlayerID int) (int, error) {
+	var challenger int
+	stmt := `SELECT player1ID 
+				FROM Battles 
+				WHERE player1Accepted = true 
+				AND player2ID = ? 
+				AND player2Accepted = false 
+				AND IFNULL(status, 'challenged') = 'challenged' 
+				LIMIT 0, 1`
+	rows, err := m.DB.Query(stmt, currentPlayerID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err := rows.Scan(&challenger)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return challenger, err
+}
+
+
+// GetAll - Get all active battles or challenges (this could be combined with GetOpen below)
+func (m *BattleModel) GetChallenges(rowid int) ([]*models.Battle, error) {
+
+	// I think I want to swap player1 and player2 things in the second SELECT ***
+	
+	stmt := `
+	SELECT 
+	b1.rowid, player1ID, p1.screenName as challenger, boardName, 
+	player1Accepted, player2ID, p2.screenName as opponent, 
+	player2Accepted, challengeDate as dateAsked, turn, IFNULL(b1.boardSize, 10), 
+	IFNULL(b1.rules, 'classic'), IFNULL(b1.salvoShots, 0), IFNULL(b1.winner, 0), 
+	IFNULL(b1.status, 'challenged'), IFNULL(b1.turnTime, 0), b1.deadline 
+	FROM Battles b1 
+	LEFT OUTER JOIN Boards bo1 ON bo1.rowid = b1.player1BoardID 
+	LEFT OUTER JOIN Players p1 ON p1.rowid = b1.player1ID 
+	LEFT OUTER JOIN Players p2 ON p2.rowid = b1.player2ID 
+	WHERE b1.player1ID = ? 
+	UNION 
+	SELECT
+	b2.rowid, player1ID, p4.screenName as challenger, boardName,
+	player1Accepted, player2ID, p3.screenName as opponent, 
+	player2Accepted, challengeDate as dateAsked, turn, IFNULL(b2.boardSize, 10), 
+	IFNULL(b2.rules, 'classic'), IFNULL(b2.salvoShots, 0), IFNULL(b2.winner, 0), 
+	IFNULL(b2.status, 'challenged'), IFNULL(b2.turnTime, 0), b2.deadline 
+	FROM Battles b2 
+	LEFT OUTER JOIN Boards bo2 ON bo2.rowid = b2.player2BoardID 
+	LEFT OUTER JOIN Players p3 ON p3.rowid = b2.player2ID 
+	LEFT OUTER JOIN Players p4 ON p4.rowid = b2.player1ID 
+	WHERE b2.player2ID = ?;
+	`
+	rows, err := m.DB.Query(stmt, rowid, rowid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	battles := []*models.Battle{}
+
+	for rows.Next() {
+		b := &models.Battle{}
+		var turnTime int
+		err = rows.Scan(
+			&b.ID, 
+			&b.Player1ID, &b.Player1ScreenName, &b.ChallengerBoardName, 
+			&b.Player1Accepted, &b.Player2ID, &b.Player2ScreenName, 
+			&b.Player2Accepted, &b.ChallengeDate, &b.Turn, &b.BoardSize, &b.Rules, &b.SalvoShots, &b.Winner,
+			&b.Status, &turnTime, &b.Deadline)
+		if err != nil {
+			return nil, err
+		}
+		b.TurnTime = time.Duration(turnTime) * time.Second
+		battles = append(battles, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return battles, nil
+}
+
+// GetOpen - Get a list of open challenges
+func (m *BattleModel) GetOpen(rowid, battleID int) ([]*models.Battle, error) {
+	// Get a list of battles that are available for this user
+	stmt := `SELECT b.rowid,
+				p1.rowid as Player1ID, p1.screenName as Player1ScreenName, 
+				p2.rowid as Player2ID, p2.screenName as Player2ScreenName 
+				FROM Battles as b
+				JOIN Players as p1 ON p1.rowid = b.player1ID
+				JOIN Players as p2 ON p2.rowid = b.player2ID
+				WHERE b.player2ID = ?`
+	rows, err := m.DB.Query(stmt, rowid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	battles := []*models.Battle{}
+
+	for rows.Next() {
+		b := &models.Battle{}
+		err = rows.Scan(&b.ID, &b.Player1ID, &b.Player1ScreenName, &b.Player2ID, &b.Player2ScreenName)
+		if err != nil {
+			return nil, err
+		}
+		battles = append(battles, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return battles, nil
+}
+
+
+// Overdue - battles waiting on a move whose deadline has passed (challenges too)
+// - Just the ids, the players, whose turn it is and the status
+func (m *BattleModel) Overdue() ([]*models.Battle, error) {
+	stmt := `SELECT rowid, player1ID, player2ID, turn, IFNULL(status, 'challenged')
+				FROM Battles
+				WHERE deadline IS NOT NULL AND deadline <= ? AND status IN (?, ?, ?)`
+	rows, err := m.DB.Query(stmt, time.Now().UTC(), models.StatusChallenged, models.StatusSetup, models.StatusInProgress)
+	if err != nil {
+		return nil, err
+	}
+	return scanSummaries(rows)
+}
+
+// Waiting - battles under way where it's this player's turn to fire
+// - Just the ids, the players, whose turn it is and the status
+func (m *BattleModel) Waiting(playerID int) ([]*models.Battle, error) {
+	stmt := `SELECT rowid, player1ID, player2ID, turn, IFNULL(status, 'challenged')
+				FROM Battles
+				WHERE turn = ? AND status IN (?, ?)`
+	rows, err := m.DB.Query(stmt, playerID, models.StatusSetup, models.StatusInProgress)
+	if err != nil {
+		return nil, err
+	}
+	return scanSummaries(rows)
+}
+
+// scanSummaries - the rows of Overdue or Waiting
+func scanSummaries(rows *sql.Rows) ([]*models.Battle, error) {
+	defer rows.Close()
+
+	battles := []*models.Battle{}
+	for rows.Next() {
+		b := &models.Battle{}
+		if err := rows.Scan(&b.ID, &b.Player1ID, &b.Player2ID, &b.Turn, &b.Status); err != nil {
+			return nil, err
+		}
+		battles = append(battles, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return battles, nil
+}
+
+
+// Just ask the database whose turn it is
+// - The database will return a secret key representing player1 or player2
+func (m *BattleModel) CheckTurn(battleID, playerID int) (int, string) {
+	var turn int = 0
+	var secretTurn string = ""
+
+	stmt := `SELECT turn, secretTurn FROM Battles WHERE rowid = ? AND turn = ?`
+	_ = m.DB.QueryRow(stmt, battleID, playerID).Scan(&turn, &secretTurn)
+	return turn, secretTurn
+}
+
+
+// Just ask the database whose turn it is
+// - The database will return a secret key representing player1 or player2
+func (m *BattleModel) GetTurn(battleID int) (int, error) {
+	var turn int = 0
+
+	stmt := `SELECT turn FROM Battles WHERE rowid = ?`
+	err := m.DB.QueryRow(stmt, battleID).Scan(&turn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, models.ErrNoRecord
+	}
+	return turn, err
+}
+
+
+// SetStrategy - how the computer plays in this battle (see game.Strategies)
+func (m *BattleModel) SetStrategy(battleID int, strategy string) error {
+	_, err := m.DB.Exec(`UPDATE Battles SET aiStrategy = ? WHERE rowid = ?`, strategy, battleID)
+	return err
+}
+
+
+// Update challenge - opponent has accepted
+func (m *BattleModel) UpdateChallenge(player1 int, player2 int, player2Accepted bool, battleID int) (error) {
+	stmt := `UPDATE Battles SET player2Accepted = ? WHERE rowid = ?`
+	_, err := m.DB.Exec(stmt, player2Accepted, battleID)
+	if err != nil {
+		return err
+	}
+	return err
+}
+
+
+// Update turn - other player's turn
+func (m *BattleModel) UpdateTurn(player1 int, player2 int, nextTurn int, battleID int, secretTurn []byte) error {
+	// Swap the value of nextTurn
+	if nextTurn == player1 {
+		nextTurn = player2
+	} else {
+		nextTurn = player1
+	}
+
+	stmt := `UPDATE Battles SET secretTurn = ?, turn = ? WHERE rowid = ?`
+	_, err := m.DB.Exec(stmt, secretTurn, nextTurn, battleID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type BoardModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// Choose Board - I can't store the boardID in my session so...
+// 				  update the database and keep one "selected" board at any given point in time
+func (m *BoardModel) ChooseBoard(rowid, boardID int, action string) (int, error) {
+	if action == "update" {
+		stmt := `UPDATE Boards SET isChosen = 0;`
+		_, err := m.DB.Exec(stmt)
+		if err != nil {
+			return 0, err
+		}
+		stmt = `UPDATE Boards SET isChosen = 1 WHERE rowid = ?`
+		_, err = m.DB.Exec(stmt, boardID)
+		if err != nil {
+			return 0, err
+		}
+		return boardID, nil
+	} else if action == "select" {
+		var boardID int
+		stmt := `SELECT rowid FROM Boards WHERE isChosen = 1;`
+		err := m.DB.QueryRow(stmt).Scan(&boardID)
+		if err != nil {
+			return 0, err
+		}
+		return boardID, nil
+	}
+	return 0, m.Dialect.errorf("unknown ChooseBoard action %q", action)
+}
+
+// Create a board if one with the same name doesn't already exist (belonging to this user)
+// - ErrDuplicateBoardName if it does; battle copies of a board don't count
+func (m *BoardModel) Create(rowid int, boardName string, boardSize int) (int, error) {
+	var boardID int64
+	stmt := `SELECT rowid FROM Boards WHERE boardName = ? AND playerID = ? AND IFNULL(battleID, 0) = 0`
+	err := m.DB.QueryRow(stmt, boardName, rowid).Scan(&boardID)
+	if err == nil {
+		return 0, models.ErrDuplicateBoardName
+	}
+	if !xerrors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	stmt = `INSERT INTO Boards (boardName, playerID, boardSize) VALUES (?, ?, ?)`
+	result, err := m.DB.Exec(stmt, boardName, rowid, boardSize)
+	if err != nil {
+		return 0, err
+	}
+	boardID, err = result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(boardID), nil
+}
+
+// Get board info - name of board
+func (m *BoardModel) GetInfo(playerID, boardID int) (*models.Board, error) {
+	stmt := `SELECT 
+		b.rowid as ID, b.boardName as Title, b.playerID as playerID, b.created,
+		IFNULL(b.boardSize, 10), IFNULL(b.templateID, 0), IFNULL(b.battleID, 0),
+		(SELECT COUNT(*) FROM Boards c WHERE c.templateID = b.rowid)
+		FROM Boards b
+		WHERE b.rowid = ? AND b.playerID = ?`
+	b := &models.Board{}
+
+	err := m.DB.QueryRow(stmt, boardID, playerID).Scan(
+			&b.ID, &b.Title, &b.PlayerID, &b.Created, &b.BoardSize, &b.TemplateID, &b.BattleID.Int64, &b.Battles)
+	if err != nil {
+		if xerrors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+	b.BattleID.Valid = b.BattleID.Int64 != 0
+	return b, nil
+}
+
+// Get positions on board
+func (m *BoardModel) GetPositions(rowid int) ([]*models.Position, error) {
+	if rowid == 0 {
+		return nil, models.ErrMissingBoardID
+	}
+	positions := []*models.Position{}
+	stmt := `SELECT 
+		b.rowid as boardID, p.playerID as playerID, 
+		p.rowid as positionID, s.shipType, p.coordX, p.coordY, p.pinColor
+		FROM Boards b
+		LEFT OUTER JOIN Positions p ON
+		p.boardID = b.rowid 
+		LEFT OUTER JOIN Ships s ON
+		s.rowid = p.shipID
+		WHERE b.rowid = ?`
+	rows, err := m.DB.Query(stmt, rowid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := &models.Position{}
+		err = rows.Scan(&p.ID, &p.PlayerID, &p.PositionID, &p.ShipType, &p.CoordX, &p.CoordY, &p.PinColor)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// copyBoard - give a battle its own copy of a player's saved board
+// - Only the ships are copied; pins land on the copy, never on the saved board,
+//   so a saved board can be picked for any number of battles
+// - ErrNoRecord unless the player owns the saved board (and it isn't itself a copy)
+func copyBoard(db querier, playerID, templateID, battleID int) (int, error) {
+	stmt := `INSERT INTO Boards (boardName, playerID, boardSize, templateID, battleID)
+		SELECT boardName, playerID, IFNULL(boardSize, 10), rowid, ? FROM Boards
+		WHERE rowid = ? AND playerID = ? AND IFNULL(battleID, 0) = 0`
+	result, err := db.Exec(stmt, battleID, templateID, playerID)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, models.ErrNoRecord
+	}
+	boardID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	stmt = `INSERT INTO Positions (boardID, shipID, playerID, coordX, coordY, pinColor)
+		SELECT ?, shipID, playerID, coordX, coordY, 0 FROM Positions
+		WHERE boardID = ? AND IFNULL(shipID, 0) != 0`
+	_, err = db.Exec(stmt, boardID, templateID)
+	if err != nil {
+		return 0, err
+	}
+	return int(boardID), nil
+}
+
+// querier - what *sql.DB and *sql.Tx have in common, so helpers can run
+// inside or outside of a transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Load a board into the game engine - ship squares first, then every pin
+// that has already been dropped on it
+func loadBoard(db querier, fleet game.Fleet, boardID int) (*game.Board, error) {
+	if boardID == 0 {
+		return nil, models.ErrMissingBoardID
+	}
+	var boardSize int
+	stmt := `SELECT IFNULL(boardSize, 10) FROM Boards WHERE rowid = ?`
+	err := db.QueryRow(stmt, boardID).Scan(&boardSize)
+	if err != nil {
+		if xerrors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	stmt = `SELECT IFNULL(s.shipType, ''), p.coordX, p.coordY, IFNULL(p.pinColor, '')
+		FROM Positions p
+		LEFT OUTER JOIN Ships s ON s.rowid = p.shipID
+		WHERE p.boardID = ?`
+	rows, err := db.Query(stmt, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ships := map[string][]game.Shot{}
+	var pins []game.Shot
+	for rows.Next() {
+		var shipType, pinColor string
+		var s game.Shot
+		err = rows.Scan(&shipType, &s.CoordX, &s.CoordY, &pinColor)
+		if err != nil {
+			return nil, err
+		}
+		if shipType != "" {
+			ships[shipType] = append(ships[shipType], s)
+		}
+		if pinColor == "red" || pinColor == "gray" {
+			pins = append(pins, s)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	b := game.NewBoard(boardSize, fleet)
+	for shipType, coords := range ships {
+		err = b.Place(shipType, coords)
+		if err != nil {
+			return nil, fmt.Errorf("board %d, %s: %w", boardID, shipType, err)
+		}
+	}
+	for _, s := range pins {
+		_, err = b.Strike(s)
+		if err != nil {
+			return nil, fmt.Errorf("board %d, pin %s: %w", boardID, s, err)
+		}
+	}
+	return b, nil
+}
+
+// Insert coordinates for a board
+// - arrayOfCoords are "row,col" strings, one per square of the ship
+// - ErrNoRecord if the ship isn't in Ships (see ShipModel.Sync)
+func (m *BoardModel) Insert(playerID int, boardID int, shipName string, arrayOfCoords []string) (int, error) {
+	var shipID int
+	stmt := `SELECT rowid FROM Ships WHERE shipType = ? LIMIT 1`
+	err := m.DB.QueryRow(stmt, shipName).Scan(&shipID)
+	if err != nil {
+		if xerrors.Is(err, sql.ErrNoRows) {
+			return 0, models.ErrNoRecord
+		}
+		return 0, err
+	}
+	stmt = `INSERT INTO Positions (boardID, shipID, playerID, coordX, coordY, pinColor) VALUES (?, ?, ?, ?, ?, ?)`
+	for _, rc := range arrayOfCoords {
+		s := strings.Split(rc, ",")
+		if len(s) != 2 {
+			return 0, m.Dialect.errorf("malformed coordinate %q", rc)
+		}
+		_, err = m.DB.Exec(stmt, boardID, shipID, playerID, s[0], s[1], 0)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+func (m *BoardModel) List(rowid int) ([]*models.Board, error) {
+	// Just the saved boards; the copies battles are played on stay out of the list
+	stmt := `SELECT b.rowid, b.boardName, b.playerID, b.created, IFNULL(b.boardSize, 10),
+	(SELECT COUNT(*) FROM Boards c WHERE c.templateID = b.rowid)
+	FROM Boards b
+	WHERE b.playerID = ? AND IFNULL(b.battleID, 0) = 0
+	ORDER BY b.created DESC LIMIT 10`
+
+	rows, err := m.DB.Query(stmt, rowid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boards := []*models.Board{}
+
+	for rows.Next() {
+		s := &models.Board{}
+		// Assign fields in rowset to Board model's "properties"
+		err = rows.Scan(&s.ID, &s.Title, &s.PlayerID, &s.Created, &s.BoardSize, &s.Battles)
+		if err != nil {
+			return nil, err
+		}
+		boards = append(boards, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return boards, nil
+}
+
+func (m *BoardModel) Update(rowid int, boardName string, playerID int) (int, error) {
+	stmt := `UPDATE Boards SET boardName = ?, playerID = ? WHERE rowid = ?`
+	_, err := m.DB.Exec(stmt, boardName, playerID, rowid)
+	if err != nil {
+		return 0, err
+	}
+	return rowid, nil
+}
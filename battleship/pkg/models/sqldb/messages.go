@@ -0,0 +1,69 @@
+package sqldb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// MessageModel - the chat between the two players in a battle
+type MessageModel struct {
+	DB *sql.DB
+}
+
+// Insert - a player says something in their battle
+// - ErrNotYourBattle if they aren't playing in it
+func (m *MessageModel) Insert(playerID, battleID int, body string) (*models.Message, error) {
+	player1ID, player2ID, err := players(m.DB, battleID)
+	if err != nil {
+		return nil, err
+	}
+	if playerID != player1ID && playerID != player2ID {
+		return nil, models.ErrNotYourBattle
+	}
+	msg := &models.Message{BattleID: battleID, PlayerID: playerID, Body: body, Created: time.Now()}
+	stmt := `INSERT INTO Messages (battleID, playerID, body, created) VALUES (?, ?, ?, ?)`
+	result, err := m.DB.Exec(stmt, battleID, playerID, body, msg.Created)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	msg.ID = int(id)
+	stmt = `SELECT screenName FROM Players WHERE rowid = ?`
+	if err = m.DB.QueryRow(stmt, playerID).Scan(&msg.ScreenName); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// List - everything said in a battle, oldest first
+func (m *MessageModel) List(battleID int) ([]*models.Message, error) {
+	stmt := `SELECT m.rowid, m.battleID, m.playerID, IFNULL(p.screenName, ''), m.body, m.created
+				FROM Messages m
+				LEFT OUTER JOIN Players p ON p.rowid = m.playerID
+				WHERE m.battleID = ?
+				ORDER BY m.rowid`
+	rows, err := m.DB.Query(stmt, battleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*models.Message{}
+	for rows.Next() {
+		msg := &models.Message{}
+		err = rows.Scan(&msg.ID, &msg.BattleID, &msg.PlayerID, &msg.ScreenName, &msg.Body, &msg.Created)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
@@ -0,0 +1,105 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// NotificationModel - each player's inbox
+type NotificationModel struct {
+	DB *sql.DB
+}
+
+// Insert - put a notification in a player's inbox
+// - An earlier notification of the same kind about the same battle is replaced,
+//   read or not, so a long battle leaves one "your turn" rather than dozens
+func (m *NotificationModel) Insert(playerID, battleID int, kind, message string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `DELETE FROM Notifications WHERE playerID = ? AND battleID = ? AND kind = ?`
+	if _, err = tx.Exec(stmt, playerID, battleID, kind); err != nil {
+		return 0, err
+	}
+	stmt = `INSERT INTO Notifications (playerID, battleID, kind, message, isRead, created) VALUES (?, ?, ?, ?, 0, ?)`
+	result, err := tx.Exec(stmt, playerID, battleID, kind, message, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), tx.Commit()
+}
+
+// List - a player's most recent notifications, newest first
+func (m *NotificationModel) List(playerID, limit int) ([]*models.Notification, error) {
+	stmt := `SELECT rowid, playerID, battleID, kind, message, isRead, created
+				FROM Notifications
+				WHERE playerID = ?
+				ORDER BY rowid DESC
+				LIMIT ?`
+	rows, err := m.DB.Query(stmt, playerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*models.Notification{}
+	for rows.Next() {
+		n := &models.Notification{}
+		err = rows.Scan(&n.ID, &n.PlayerID, &n.BattleID, &n.Kind, &n.Message, &n.Read, &n.Created)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkAllRead - empty a player's unread count
+func (m *NotificationModel) MarkAllRead(playerID int) error {
+	_, err := m.DB.Exec(`UPDATE Notifications SET isRead = 1 WHERE playerID = ? AND isRead = 0`, playerID)
+	return err
+}
+
+// MarkBattleRead - the player has looked at a battle; everything about it has been seen
+func (m *NotificationModel) MarkBattleRead(playerID, battleID int) error {
+	stmt := `UPDATE Notifications SET isRead = 1 WHERE playerID = ? AND battleID = ? AND isRead = 0`
+	_, err := m.DB.Exec(stmt, playerID, battleID)
+	return err
+}
+
+// MarkRead - one notification has been seen; ErrNoRecord if it isn't the player's
+func (m *NotificationModel) MarkRead(playerID, notificationID int) error {
+	var owner int
+	err := m.DB.QueryRow(`SELECT playerID FROM Notifications WHERE rowid = ?`, notificationID).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrNoRecord
+	}
+	if err != nil {
+		return err
+	}
+	if owner != playerID {
+		return models.ErrNoRecord
+	}
+	_, err = m.DB.Exec(`UPDATE Notifications SET isRead = 1 WHERE rowid = ?`, notificationID)
+	return err
+}
+
+// Unread - how many notifications a player hasn't seen
+func (m *NotificationModel) Unread(playerID int) (int, error) {
+	var n int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM Notifications WHERE playerID = ? AND isRead = 0`, playerID).Scan(&n)
+	return n, err
+}
@@ -1,19 +1,19 @@
-package sqlite3
+package sqldb
 
 import (
+	"crypto/rand"
 	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/rating"
 	"database/sql"
 	"errors"
-	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/xerrors"
-	"strings"
 	"time"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 type PlayerModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Dialect Dialect
 }
 
 // authenticate player
@@ -46,50 +46,34 @@ func (m *PlayerModel) Authenticate(screenName, password string) (int, error) {
 func (m *PlayerModel) Get(rowid int) (*models.Player, error) {
 	p := &models.Player{}
 
-	stmt := `SELECT rowid, screenName, emailAddress, lastLogin FROM Players WHERE rowid = ?`
-	err := m.DB.QueryRow(stmt, rowid).Scan(&p.ID, &p.ScreenName, &p.EmailAddress, &p.LastLogin)
-	if err != nil {
-		fmt.Println("ERROR [players.Get 1] - ", err.Error())
-		// if this result set is empty, there could be two reasons
-		// a.) somebody is trying to hack me by submitting a bogus rowid
-		// b.) the database has been deleted
-		// so, let's check the database for users.  If there are none, delete session and return to login screen
-		//f := PlayerModel{}
-		//_, err = f.List(0, "")
-		if err != nil {
-			fmt.Println("ERROR [players.Get 3] - empty result set")
-			// then our result set is empty
-			return p, errors.New("empty")
-		} else {
-			// somebody is trying to hack us
-			fmt.Println("ERROR [players.Get 2] - ", err.Error())
-			return nil, err
-		}
+	stmt := `SELECT rowid, screenName, IFNULL(emailAddress, ''), lastLogin, loggedIn, IFNULL(isComputer, 0),
+				IFNULL(rating, ?), IFNULL(wins, 0), IFNULL(losses, 0), IFNULL(streak, 0) FROM Players WHERE rowid = ?`
+	err := m.DB.QueryRow(stmt, rating.Initial, rowid).Scan(&p.ID, &p.ScreenName, &p.EmailAddress, &p.LastLogin, &p.LoggedIn, &p.IsComputer,
+		&p.Rating, &p.Wins, &p.Losses, &p.Streak)
+	// The (empty) player comes back with an error too; the authenticate middleware looks at its ID
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, models.ErrNoRecord
 	}
-	//fmt.Println("INFO - Returning player information to authenticate middleware")	// debug
-	return p, nil
+	return p, err
 }
 
 // insert new player
 func (m *PlayerModel) Insert(screenName string, emailAddress string, password string) (int, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 13)
 	if err != nil {
-		fmt.Println("ERROR - Error generating hashed password")
 		return 0, err
 	}
 
 	stmt := `INSERT INTO Players (screenName, emailAddress, hashedPassword, created, loggedIn, lastLogin) VALUES (?, ?, ?, ?, ?, ?)`
 	result, err := m.DB.Exec(stmt, screenName, emailAddress, hashedPassword, time.Now(), 0, time.Now())
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed:") {
+		if m.Dialect.IsDuplicate(err) {
 			// Our unique requirement for screen name has been violated
-			fmt.Println(err.Error())
 			return 0, models.ErrDuplicateScreenName
-		} else {
-			return 0, err
 		}
+		return 0, err
 	}
-	rowid, err := result.LastInsertId() // confirmed! sqlite3 driver has this functionality!
+	rowid, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
@@ -99,17 +83,20 @@ func (m *PlayerModel) Insert(screenName string, emailAddress string, password st
 
 // list players
 func (m *PlayerModel) List(rowid int, status string) ([]*models.Player, error) {
-	stmt := `SELECT rowid, screenName, loggedIn, inBattle, created, lastLogin FROM Players`
+	stmt := `SELECT rowid, screenName, loggedIn, inBattle, created, lastLogin, IFNULL(isComputer, 0),
+				IFNULL(rating, ?), IFNULL(wins, 0), IFNULL(losses, 0), IFNULL(streak, 0) FROM Players`
+	args := []interface{}{rating.Initial}
 	if status == "loggedIn" {
 		stmt += " WHERE loggedIn = 1"
 		if rowid != 0 {
 			stmt += " AND rowid != ?"
+			args = append(args, rowid)
 		}
 	} else if rowid != 0 {
 		stmt += " WHERE rowid != ?"
+		args = append(args, rowid)
 	}
-	//fmt.Println("[players.List 1]", stmt)
-	rows, err := m.DB.Query(stmt, rowid)
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -119,15 +106,14 @@ func (m *PlayerModel) List(rowid int, status string) ([]*models.Player, error) {
 
 	for rows.Next() {
 		s := &models.Player{}
-		err = rows.Scan(&s.ID, &s.ScreenName, &s.LoggedIn, &s.InBattle, &s.Created, &s.LastLogin)
+		err = rows.Scan(&s.ID, &s.ScreenName, &s.LoggedIn, &s.InBattle, &s.Created, &s.LastLogin, &s.IsComputer,
+			&s.Rating, &s.Wins, &s.Losses, &s.Streak)
 		if err != nil {
-			//fmt.Println("[players.List 2] Error:", err.Error())
 			return nil, err
 		}
 		players = append(players, s)
 	}
 	if err = rows.Err(); err != nil {
-		//fmt.Println("[ERROR] Error:", err.Error())
 		return nil, err
 	}
 	if rowid == 0 && status == "" && len(players) == 0 {
@@ -141,7 +127,6 @@ func (m *PlayerModel) Update(id int, emailAddress string) (int, error) {
 	stmt := `UPDATE Players SET emailAddress = ?, lastLogin = ? WHERE rowid = ?`
 	_, err := m.DB.Exec(stmt, emailAddress, time.Now(), id)
 	if err != nil {
-		//fmt.Println("[ERROR] Error:", err.Error())
 		if xerrors.Is(err, sql.ErrNoRows) {
 			return id, models.ErrNoRecord
 		}
@@ -154,10 +139,42 @@ func (m *PlayerModel) UpdateLogin(id int, loggedIn bool) (int, error) {
 	stmt := `UPDATE Players SET lastLogin = ?, loggedIn = ? WHERE rowid = ?`
 	_, err := m.DB.Exec(stmt, time.Now(), loggedIn, id)
 	if err != nil {
-		//fmt.Println("[ERROR] Error encountered:", err.Error())
 		if xerrors.Is(err, sql.ErrNoRows) {
 			return id, models.ErrNoRecord
 		}
 	}
 	return id, err
-}
\ No newline at end of file
+}
+
+
+// EnsureComputer - make sure the built-in computer player exists and return its ID
+// - Its screen name has a space in it so no one can sign up with it
+// - Nobody knows its password; it is always "logged in" so it can be challenged
+func (m *PlayerModel) EnsureComputer(screenName string) (int, error) {
+	var rowid int
+	err := m.DB.QueryRow(`SELECT rowid FROM Players WHERE isComputer = 1 LIMIT 0, 1`).Scan(&rowid)
+	if err == nil {
+		return rowid, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return 0, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword(secret, 13)
+	if err != nil {
+		return 0, err
+	}
+	stmt := `INSERT INTO Players (screenName, hashedPassword, created, loggedIn, lastLogin, isComputer) VALUES (?, ?, ?, 1, ?, 1)`
+	result, err := m.DB.Exec(stmt, screenName, hashedPassword, time.Now(), time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
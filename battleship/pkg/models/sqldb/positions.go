@@ -0,0 +1,309 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type PositionModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+	Fleet   game.Fleet
+}
+
+
+// Check the Battles board to see if one of the players has lost their whole fleet
+// - The game engine decides; we record the first winner and report it
+func (m *PositionModel) CheckWinner(playerID, battleID int) (bool, error) {
+	var pOne, pTwo, boardOne, boardTwo, winner int
+
+	stmt := `SELECT player1ID, player2ID, IFNULL(player1BoardID, 0), IFNULL(player2BoardID, 0), winner
+				FROM Battles WHERE rowid = ? AND (player1ID = ? OR player2ID = ?)`
+	err := m.DB.QueryRow(stmt, battleID, playerID, playerID).Scan(&pOne, &pTwo, &boardOne, &boardTwo, &winner)
+	if errors.Is(err, sql.ErrNoRows) {
+		// not their battle (or no battle at all), so nobody has won it
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// There can be only one winner
+	if winner != 0 {
+		return true, nil
+	}
+	for _, side := range []struct{ boardID, victor int }{{boardOne, pTwo}, {boardTwo, pOne}} {
+		if side.boardID == 0 {
+			continue
+		}
+		b, err := loadBoard(m.DB, m.Fleet, side.boardID)
+		if err != nil {
+			return false, err
+		}
+		if b.FleetDestroyed() {
+			return m.declareWinner(side.victor, battleID)
+		}
+	}
+	return false, nil
+}
+
+
+// Record the winner, but only if nobody beat them to it
+// - in_progress -> finished
+// - The players are rated along with it (see rate)
+func (m *PositionModel) declareWinner(playerID, battleID int) (bool, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	winner, err := declareWinner(tx, playerID, battleID)
+	if err != nil || !winner {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+func declareWinner(q querier, playerID, battleID int) (bool, error) {
+	in, args := statusIn(models.StatusFinished)
+	stmt := `UPDATE Battles SET winner = ?, status = ? WHERE rowid = ? AND winner = 0 AND ` + in
+	result, err := q.Exec(stmt, append([]interface{}{playerID, models.StatusFinished, battleID}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n != 1 {
+		return false, nil
+	}
+	return true, rate(q, battleID, playerID)
+}
+
+
+func (m *PositionModel) Insert(boardID int, battleshipID int, playerID int, coordX int, coordY int, pinColor string) (int, error) {
+	return 0, nil
+}
+func (m *PositionModel) Get(id int) (*models.Position, error) {
+	return nil, nil
+}
+func (m *PositionModel) List(boardID, playerID int) ([]*models.Position, error) {
+	// Get coordinates that are attempted or successful strikes (gray or red) for this battle
+	stmt := `SELECT
+				coordX, coordY, pinColor 
+			FROM 
+				Positions p 
+			WHERE 
+				(pinColor = 'gray' OR pinColor = 'red')
+			AND
+				boardID = ?;`
+	rows, err := m.DB.Query(stmt, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []*models.Position{}
+
+	for rows.Next() {
+		p := &models.Position{}
+		err = rows.Scan(&p.CoordX, &p.CoordY, &p.PinColor)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return positions, nil
+}
+
+
+// Update positions pinColor
+// - A single shot; this is a one shot Volley
+// - Return the pinColor and shipType (if sunk)
+func (m *PositionModel) Update(playerID int, playerTakingTheirTurn int, battleID int, boardID int, coordX int, coordY, secretTurn, newSecretTurn string) (string, string, bool, error) {
+	outcomes, winner, err := m.Volley(playerID, playerTakingTheirTurn, battleID, boardID, []game.Shot{{CoordX: coordX, CoordY: coordY}}, secretTurn, newSecretTurn)
+	if err != nil {
+		return "", "", false, err
+	}
+	return outcomes[0].PinColor(), outcomes[0].SunkShip(), winner, nil
+}
+
+
+// ShotsPerTurn - how many shots this player fires on their turn
+// - Always 1 in a classic battle; in a salvo battle it may depend on how
+//   many of their own ships are still afloat
+// - Never more than the squares left on the other board (once it's there)
+func (m *PositionModel) ShotsPerTurn(battleID, playerID int) (int, error) {
+	var pOne, boardOne, boardTwo, salvoShots int
+	var rules string
+	stmt := `SELECT player1ID, IFNULL(player1BoardID, 0), IFNULL(player2BoardID, 0),
+				IFNULL(rules, 'classic'), IFNULL(salvoShots, 0)
+				FROM Battles WHERE rowid = ? AND (player1ID = ? OR player2ID = ?)`
+	err := m.DB.QueryRow(stmt, battleID, playerID, playerID).Scan(&pOne, &boardOne, &boardTwo, &rules, &salvoShots)
+	if err != nil {
+		return 0, err
+	}
+	ownBoardID, targetBoardID := boardTwo, boardOne
+	if pOne == playerID {
+		ownBoardID, targetBoardID = boardOne, boardTwo
+	}
+	own, err := loadBoard(m.DB, m.Fleet, ownBoardID)
+	if err != nil {
+		return 0, err
+	}
+	unstruck := own.Size * own.Size
+	if targetBoardID != 0 {
+		target, err := loadBoard(m.DB, m.Fleet, targetBoardID)
+		if err != nil {
+			return 0, err
+		}
+		unstruck = target.Unstruck()
+	}
+	return game.Rules{Mode: rules, SalvoShots: salvoShots}.ShotsPerTurn(own.SurvivingShips(), unstruck), nil
+}
+
+
+// Knowledge - what an opponent can see of this board (see game.Board.Knowledge)
+func (m *PositionModel) Knowledge(boardID int) (game.Knowledge, error) {
+	board, err := loadBoard(m.DB, m.Fleet, boardID)
+	if err != nil {
+		return game.Knowledge{}, err
+	}
+	return board.Knowledge(), nil
+}
+
+
+// Volley - record every shot of a turn and pass the turn, all or nothing
+// - Claim the turn: secretTurn has to be the one this player was handed
+// - Query player, battle, board, and coordinates
+// - Resolve the shots with the game engine
+// - Return the outcome of each shot and whether this turn won the battle
+func (m *PositionModel) Volley(playerID int, playerTakingTheirTurn int, battleID int, boardID int, shots []game.Shot, secretTurn, newSecretTurn string) ([]game.Outcome, bool, error) {
+	var pOne, pTwo, boardOne, boardTwo, salvoShots int
+	var rules string
+	var ownBoardID int = 0
+	var sunkenShipSQL string = ""
+	var winner bool = false
+
+	if secretTurn == "" {
+		return nil, false, models.ErrStaleTurn
+	}
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// Claim the turn before anything else (optimistic concurrency on the battle row)
+	// - The secret changes every turn, so it doubles as the row's version
+	// - A second copy of this turn, or a strike racing it, waits for this
+	//   transaction and then finds nothing to update
+	// - Anything that goes wrong later rolls the turn back with the rest
+	// - The first shot of the battle moves it from setup to in_progress
+	stmt := `UPDATE Battles SET turn = CASE WHEN player1ID = ? THEN player2ID ELSE player1ID END, secretTurn = ?,
+				status = ?
+				WHERE rowid = ? AND turn = ? AND secretTurn = ? AND winner = 0 AND (player1ID = ? OR player2ID = ?)
+				AND status IN (?, ?)`
+	result, err := tx.Exec(stmt, playerTakingTheirTurn, newSecretTurn, models.StatusInProgress,
+		battleID, playerTakingTheirTurn, secretTurn, playerID, playerID, models.StatusSetup, models.StatusInProgress)
+	if err != nil {
+		return nil, false, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, false, err
+	} else if n == 0 {
+		return nil, false, models.ErrStaleTurn
+	}
+	// The other player's turn clock starts now
+	if err = startClock(tx, battleID); err != nil {
+		return nil, false, err
+	}
+
+	// See if playerTakingTheirTurn is player1 or player2
+	stmt = `SELECT player1ID, player2ID, IFNULL(player1BoardID, 0), IFNULL(player2BoardID, 0),
+				IFNULL(rules, 'classic'), IFNULL(salvoShots, 0)
+				FROM Battles WHERE rowid = ? AND (player1ID = ? OR player2ID = ?);`
+	err = tx.QueryRow(stmt, battleID, playerID, playerID).Scan(&pOne, &pTwo, &boardOne, &boardTwo, &rules, &salvoShots)
+	if err != nil {
+		return nil, false, err
+	}
+	// Find out which player this is
+	if pOne == playerTakingTheirTurn {
+		// Then player1 just went; if they sink a ship, we'll want to update the number of sunken ships the other player has
+		sunkenShipSQL = `UPDATE Battles SET Player2SunkenShips = Player2SunkenShips +1 WHERE rowid = ?`
+		ownBoardID = boardOne
+	} else {
+		sunkenShipSQL = `UPDATE Battles SET Player1SunkenShips = Player1SunkenShips +1 WHERE rowid = ?`
+		ownBoardID = boardTwo
+	}
+	// Shots only ever land on the other player's board in this battle
+	if boardID == ownBoardID || (boardID != boardOne && boardID != boardTwo) {
+		return nil, false, models.ErrNotYourBattle
+	}
+
+	// The rules decide how many shots make up a turn
+	own, err := loadBoard(tx, m.Fleet, ownBoardID)
+	if err != nil {
+		return nil, false, err
+	}
+	board, err := loadBoard(tx, m.Fleet, boardID)
+	if err != nil {
+		return nil, false, err
+	}
+	required := game.Rules{Mode: rules, SalvoShots: salvoShots}.ShotsPerTurn(own.SurvivingShips(), board.Unstruck())
+	if len(shots) != required {
+		return nil, false, fmt.Errorf("%w: need %d, got %d", game.ErrWrongShotCount, required, len(shots))
+	}
+
+	// Let the game engine resolve the volley against the target board
+	outcomes, err := board.Volley(shots)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Record the pins
+	// - If a ship is there, update the pinColor to "red"
+	// - If a ship is not there, insert a gray pin at those coordinates
+	for _, o := range outcomes {
+		if o.Result == game.Miss {
+			stmt = `INSERT INTO Positions (playerID, boardID, coordX, coordY, pinColor) VALUES (?, ?, ?, ?, ?)`
+			_, err = tx.Exec(stmt, playerID, boardID, o.Shot.CoordX, o.Shot.CoordY, o.PinColor())
+		} else {
+			stmt = `UPDATE Positions SET pinColor = ? WHERE boardID = ? AND coordX = ? AND coordY = ?`
+			_, err = tx.Exec(stmt, o.PinColor(), boardID, o.Shot.CoordX, o.Shot.CoordY)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if o.Result == game.Sunk {
+			// Update this player's sunken ship counter
+			_, err = tx.Exec(sunkenShipSQL, battleID)
+			if err != nil {
+				return nil, false, m.Dialect.errorf("unable to update the sunken ship counter: %w", err)
+			}
+		}
+	}
+
+	// Keep the history: who fired where, and in what order
+	if err = recordStrikes(tx, battleID, playerTakingTheirTurn, boardID, outcomes); err != nil {
+		return nil, false, err
+	}
+
+	if board.FleetDestroyed() {
+		winner, err = declareWinner(tx, playerID, battleID)
+		if err != nil {
+			return nil, false, m.Dialect.errorf("unable to declare the winner: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return outcomes, winner, nil
+}
@@ -0,0 +1,114 @@
+package sqldb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"github.com/519seven/cs610/battleship/pkg/rating"
+)
+
+// RatingModel - players' ratings, records and rating history (see pkg/rating)
+type RatingModel struct {
+	DB *sql.DB
+}
+
+// rate - the winner of a battle takes rating points from the loser
+// - Both players' win/loss records and streaks move, and each gets a history row
+// - Called with the statement that gives the battle its winner, so they commit together
+func rate(q querier, battleID, winnerID int) error {
+	var player1ID, player2ID int
+	stmt := `SELECT player1ID, player2ID FROM Battles WHERE rowid = ?`
+	if err := q.QueryRow(stmt, battleID).Scan(&player1ID, &player2ID); err != nil {
+		return err
+	}
+	loserID := player1ID
+	if winnerID == player1ID {
+		loserID = player2ID
+	}
+	var winnerRating, loserRating int
+	stmt = `SELECT IFNULL(rating, ?) FROM Players WHERE rowid = ?`
+	if err := q.QueryRow(stmt, rating.Initial, winnerID).Scan(&winnerRating); err != nil {
+		return err
+	}
+	if err := q.QueryRow(stmt, rating.Initial, loserID).Scan(&loserRating); err != nil {
+		return err
+	}
+	newWinner, newLoser := rating.Update(winnerRating, loserRating)
+
+	stmt = `UPDATE Players SET rating = ?, wins = IFNULL(wins, 0) + 1,
+				streak = CASE WHEN IFNULL(streak, 0) > 0 THEN streak + 1 ELSE 1 END
+				WHERE rowid = ?`
+	if _, err := q.Exec(stmt, newWinner, winnerID); err != nil {
+		return err
+	}
+	stmt = `UPDATE Players SET rating = ?, losses = IFNULL(losses, 0) + 1,
+				streak = CASE WHEN IFNULL(streak, 0) < 0 THEN streak - 1 ELSE -1 END
+				WHERE rowid = ?`
+	if _, err := q.Exec(stmt, newLoser, loserID); err != nil {
+		return err
+	}
+	now := time.Now()
+	stmt = `INSERT INTO Ratings (playerID, battleID, opponentID, rating, delta, created) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := q.Exec(stmt, winnerID, battleID, loserID, newWinner, newWinner-winnerRating, now); err != nil {
+		return err
+	}
+	_, err := q.Exec(stmt, loserID, battleID, winnerID, newLoser, newLoser-loserRating, now)
+	return err
+}
+
+// History - every change to a player's rating, newest first
+func (m *RatingModel) History(playerID int) ([]*models.Rating, error) {
+	stmt := `SELECT r.rowid, r.playerID, r.battleID, r.opponentID, IFNULL(p.screenName, ''), r.rating, r.delta, r.created
+				FROM Ratings r
+				LEFT OUTER JOIN Players p ON p.rowid = r.opponentID
+				WHERE r.playerID = ?
+				ORDER BY r.rowid DESC`
+	rows, err := m.DB.Query(stmt, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []*models.Rating{}
+	for rows.Next() {
+		r := &models.Rating{}
+		err = rows.Scan(&r.ID, &r.PlayerID, &r.BattleID, &r.OpponentID, &r.OpponentScreenName, &r.Rating, &r.Delta, &r.Created)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// Leaderboard - the best rated players who have finished a battle, best first
+func (m *RatingModel) Leaderboard(limit int) ([]*models.Player, error) {
+	stmt := `SELECT rowid, screenName, loggedIn, IFNULL(isComputer, 0), IFNULL(rating, ?), IFNULL(wins, 0), IFNULL(losses, 0), IFNULL(streak, 0)
+				FROM Players
+				WHERE IFNULL(wins, 0) + IFNULL(losses, 0) > 0
+				ORDER BY rating DESC, wins DESC, screenName
+				LIMIT ?`
+	rows, err := m.DB.Query(stmt, rating.Initial, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := []*models.Player{}
+	for rows.Next() {
+		p := &models.Player{}
+		err = rows.Scan(&p.ID, &p.ScreenName, &p.LoggedIn, &p.IsComputer, &p.Rating, &p.Wins, &p.Losses, &p.Streak)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return players, nil
+}
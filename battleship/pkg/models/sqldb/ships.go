@@ -0,0 +1,127 @@
+package sqldb
+
+import (
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+	"database/sql"
+	"errors"
+)
+
+type ShipModel struct {
+	DB *sql.DB
+}
+
+func (m *ShipModel) Insert(shipType string, shipLength int) (int, error) {
+	stmt := `INSERT INTO Ships (shipType, shipLength) VALUES (?, ?)`
+	result, err := m.DB.Exec(stmt, shipType, shipLength)
+	if err != nil {
+		return 0, err
+	}
+	rowid, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowid), nil
+}
+func (m *ShipModel) Get(id int) (*models.Ship, error) {
+	s := &models.Ship{}
+	stmt := `SELECT rowid, shipType, shipLength FROM Ships WHERE rowid = ?`
+	err := m.DB.QueryRow(stmt, id).Scan(&s.ID, &s.Title, &s.Length)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrNoRecord
+	} else if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+func (m *ShipModel) List() ([]*models.Ship, error) {
+	stmt := `SELECT rowid, shipType, shipLength FROM Ships ORDER BY rowid`
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ships := []*models.Ship{}
+	for rows.Next() {
+		s := &models.Ship{}
+		err = rows.Scan(&s.ID, &s.Title, &s.Length)
+		if err != nil {
+			return nil, err
+		}
+		ships = append(ships, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ships, nil
+}
+
+// Sync - make sure every ship in the fleet has a row in Ships, with the fleet's length
+// - Positions point at Ships by rowid, so existing rows are never removed
+// - ErrFleetMismatch (and nothing changes) if boards already saved have ships
+//   the fleet doesn't, or ships of another length; they were made with another fleet
+func (m *ShipModel) Sync(fleet game.Fleet) error {
+	squares, err := m.squares()
+	if err != nil {
+		return err
+	}
+	if err = models.FleetMismatch(fleet, squares); err != nil {
+		return err
+	}
+	ships, err := m.List()
+	if err != nil {
+		return err
+	}
+	known := map[string]*models.Ship{}
+	for _, s := range ships {
+		known[s.Title] = s
+	}
+	for _, s := range fleet {
+		if k, ok := known[s.Type]; ok {
+			if k.Length != s.Length {
+				_, err = m.DB.Exec(`UPDATE Ships SET shipLength = ? WHERE shipType = ?`, s.Length, s.Type)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		_, err = m.Insert(s.Type, s.Length)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// How many squares each ship has on each saved board (board ID -> ship type -> squares)
+func (m *ShipModel) squares() (map[int]map[string]int, error) {
+	stmt := `SELECT p.boardID, s.shipType, COUNT(*)
+				FROM Positions p
+				INNER JOIN Ships s ON s.rowid = p.shipID
+				GROUP BY p.boardID, s.shipType`
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	squares := map[int]map[string]int{}
+	for rows.Next() {
+		var boardID, n int
+		var shipType string
+		err = rows.Scan(&boardID, &shipType, &n)
+		if err != nil {
+			return nil, err
+		}
+		if squares[boardID] == nil {
+			squares[boardID] = map[string]int{}
+		}
+		squares[boardID][shipType] = n
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return squares, nil
+}
@@ -0,0 +1,61 @@
+// Package sqldb - the models on a SQL database (see models.BattleRepository and friends)
+// - The SQL is written once, for both SQLite and MySQL: every table has a "rowid"
+//   primary key, both drivers take "?" placeholders, and IFNULL, LIMIT and
+//   CASE mean the same thing in both
+// - What really differs is in a Dialect, which pkg/models/sqlite3 and
+//   pkg/models/mysql each provide along with their own Open and Migrations;
+//   the schema is where the rest of the differences (INSERT OR IGNORE, column types) live
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+)
+
+// Dialect - what one database does its own way
+// - Name starts every error the models make themselves ("sqlite3: ...")
+// - IsDuplicate - did an INSERT break a UNIQUE key?
+type Dialect struct {
+	Name        string
+	IsDuplicate func(err error) bool
+}
+
+// errorf - an error from the models, named after the database
+func (d Dialect) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(d.Name+": "+format, args...)
+}
+
+// Models - every repository, on one database
+type Models struct {
+	Battles       *BattleModel
+	Boards        *BoardModel
+	Messages      *MessageModel
+	Notifications *NotificationModel
+	Players       *PlayerModel
+	Positions     *PositionModel
+	Ratings       *RatingModel
+	Ships         *ShipModel
+	Strikes       *StrikeModel
+	Tokens        *TokenModel
+	Webhooks      *WebhookModel
+}
+
+// New - the repositories for a database that has been migrated to the latest version
+// - Battles are played with fleet
+func New(db *sql.DB, dialect Dialect, fleet game.Fleet) *Models {
+	return &Models{
+		Battles:       &BattleModel{DB: db},
+		Boards:        &BoardModel{DB: db, Dialect: dialect},
+		Messages:      &MessageModel{DB: db},
+		Notifications: &NotificationModel{DB: db},
+		Players:       &PlayerModel{DB: db, Dialect: dialect},
+		Positions:     &PositionModel{DB: db, Dialect: dialect, Fleet: fleet},
+		Ratings:       &RatingModel{DB: db},
+		Ships:         &ShipModel{DB: db},
+		Strikes:       &StrikeModel{DB: db},
+		Tokens:        &TokenModel{DB: db},
+		Webhooks:      &WebhookModel{DB: db},
+	}
+}
@@ -0,0 +1,140 @@
+package sqldb_test
+
+// What only the SQL models can get wrong: a database that fails underneath them
+// (pkg/models/contract_test.go has everything the implementations share)
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	driver "github.com/mattn/go-sqlite3"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/migrate"
+	"github.com/519seven/cs610/battleship/pkg/models/sqldb"
+	"github.com/519seven/cs610/battleship/pkg/models/sqlite3"
+)
+
+// One destroyer each keeps a battle short
+var fleet = game.Fleet{{Type: "destroyer", Name: "Destroyer", Abbreviation: "D", Length: 2}}
+
+// openBattle - a new SQLite database with a battle between amy and ben, both
+// with a destroyer on 1,A and 2,A; ben fires first, with the secret "first"
+func openBattle(t *testing.T) (db *sql.DB, m *sqldb.Models, amy, ben, battleID int, cleanup func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "sqldb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	if db, err = sqlite3.Open(filepath.Join(dir, "battleship.db")); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	cleanup = func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	fail := func(err error) {
+		cleanup()
+		t.Fatal(err)
+	}
+	if _, err = migrate.To(db, sqlite3.Migrations, migrate.Latest(sqlite3.Migrations)); err != nil {
+		fail(err)
+	}
+	m = sqldb.New(db, sqlite3.Dialect, fleet)
+	if err = m.Ships.Sync(fleet); err != nil {
+		fail(err)
+	}
+	var boards []int
+	for _, screenName := range []string{"amy", "ben"} {
+		playerID, err := m.Players.Insert(screenName, "", "B0mbs4way:(")
+		if err != nil {
+			fail(err)
+		}
+		boardID, err := m.Boards.Create(playerID, screenName+"'s", game.DefaultSize)
+		if err != nil {
+			fail(err)
+		}
+		if _, err = m.Boards.Insert(playerID, boardID, "destroyer", []string{"1,A", "2,A"}); err != nil {
+			fail(err)
+		}
+		boards = append(boards, playerID, boardID)
+	}
+	amy, ben = boards[0], boards[2]
+	if battleID, err = m.Battles.Create(amy, boards[1], ben, "first", game.Rules{Mode: game.Classic}); err != nil {
+		fail(err)
+	}
+	if _, err = m.Battles.Accept(ben, boards[3], battleID); err != nil {
+		fail(err)
+	}
+	return db, m, amy, ben, battleID, cleanup
+}
+
+func TestCheckWinnerPassesErrorsOn(t *testing.T) {
+	db, m, amy, _, battleID, cleanup := openBattle(t)
+	defer cleanup()
+
+	tests := []struct {
+		name     string
+		battleID int
+		closed   bool
+		err      bool
+	}{
+		{"their battle", battleID, false, false},
+		{"no such battle", battleID + 1, false, false},
+		{"the database has gone", battleID, true, true},
+	}
+	for _, tt := range tests {
+		if tt.closed {
+			db.Close()
+		}
+		won, err := m.Positions.CheckWinner(amy, tt.battleID)
+		if won || (err != nil) != tt.err {
+			t.Errorf("%s: CheckWinner = %v, %v; want an error %v", tt.name, won, err, tt.err)
+		}
+	}
+}
+
+func TestVolleyKeepsWhyTheWinnerWasntDeclared(t *testing.T) {
+	db, m, amy, ben, battleID, cleanup := openBattle(t)
+	defer cleanup()
+	b, err := m.Battles.Get(amy, battleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	turns := []struct {
+		playerID, boardID int
+		shot              string
+		secret, next      string
+	}{
+		{ben, b.Player1BoardID, "1,A", "first", "amy's turn"},
+		{amy, b.Player2BoardID, "9,J", "amy's turn", "ben's turn"},
+	}
+	for _, tt := range turns {
+		s, _ := game.ParseCoordinate(tt.shot)
+		if _, _, err = m.Positions.Volley(tt.playerID, tt.playerID, battleID, tt.boardID, []game.Shot{s}, tt.secret, tt.next); err != nil {
+			t.Fatalf("%s on %s: %v", tt.shot, tt.secret, err)
+		}
+	}
+
+	// Rating the battle is part of declaring the winner, and it has nowhere to go
+	if _, err = db.Exec(`DROP TABLE Ratings`); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := game.ParseCoordinate("2,A")
+	_, won, err := m.Positions.Volley(ben, ben, battleID, b.Player1BoardID, []game.Shot{s}, "ben's turn", "over")
+	var sqliteErr driver.Error
+	if won || err == nil || !strings.HasPrefix(err.Error(), "sqlite3: unable to declare the winner: ") || !errors.As(err, &sqliteErr) {
+		t.Fatalf("the winning shot = %v, %v; want the SQLite error, wrapped", won, err)
+	}
+	if b, err = m.Battles.Get(amy, battleID); err != nil || b.Winner != 0 || b.Status.Over() {
+		t.Errorf("after the turn failed the battle is %v (%v), want it still going", b, err)
+	}
+}
@@ -0,0 +1,61 @@
+package sqldb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/game"
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// StrikeModel - the history of every battle, one row per shot
+// - Pins on Positions only say what a square looks like now; Strikes keep
+//   the order the shots were fired in and who fired them
+type StrikeModel struct {
+	DB *sql.DB
+}
+
+// recordStrikes - add a turn's shots to the battle's history
+// - Called from Volley so the history commits (or not) with the pins
+func recordStrikes(q querier, battleID, playerID, boardID int, outcomes []game.Outcome) error {
+	var move int
+	stmt := `SELECT IFNULL(MAX(move), 0) + 1 FROM Strikes WHERE battleID = ?`
+	if err := q.QueryRow(stmt, battleID).Scan(&move); err != nil {
+		return err
+	}
+	now := time.Now()
+	stmt = `INSERT INTO Strikes (battleID, move, playerID, boardID, coordX, coordY, result, shipType, created)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, o := range outcomes {
+		_, err := q.Exec(stmt, battleID, move, playerID, boardID, o.Shot.CoordX, o.Shot.CoordY, o.Result.String(), o.Ship, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List - every shot fired in a battle, in the order they were fired
+func (m *StrikeModel) List(battleID int) ([]*models.Strike, error) {
+	stmt := `SELECT rowid, battleID, move, playerID, boardID, coordX, coordY, result, IFNULL(shipType, ''), created
+				FROM Strikes WHERE battleID = ? ORDER BY move, rowid`
+	rows, err := m.DB.Query(stmt, battleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	strikes := []*models.Strike{}
+	for rows.Next() {
+		s := &models.Strike{}
+		err = rows.Scan(&s.ID, &s.BattleID, &s.Move, &s.PlayerID, &s.BoardID, &s.CoordX, &s.CoordY, &s.Result, &s.ShipType, &s.Created)
+		if err != nil {
+			return nil, err
+		}
+		strikes = append(strikes, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return strikes, nil
+}
@@ -0,0 +1,116 @@
+package sqldb
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// Tokens look like "bst_<43 random characters>"
+// - 32 random bytes can't be guessed, so a plain SHA-256 is enough to keep the
+//   stored copy useless to a thief (and, unlike bcrypt, can be looked up)
+const tokenPrefix = "bst_"
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Insert - create a token for a player
+// - Returns the token itself; this is the only time it is ever available
+func (m *TokenModel) Insert(playerID int, name string, scopes []string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	stmt := `INSERT INTO ApiTokens (playerID, name, prefix, tokenHash, scopes, created, revoked)
+				VALUES (?, ?, ?, ?, ?, ?, 0)`
+	_, err := m.DB.Exec(stmt, playerID, name, token[:len(tokenPrefix)+6], hashToken(token), strings.Join(scopes, " "), time.Now())
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// List - a player's tokens that haven't been revoked
+func (m *TokenModel) List(playerID int) ([]*models.APIToken, error) {
+	stmt := `SELECT rowid, playerID, name, prefix, scopes, created, lastUsed
+				FROM ApiTokens
+				WHERE playerID = ? AND revoked = 0
+				ORDER BY created DESC`
+	rows, err := m.DB.Query(stmt, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*models.APIToken{}
+	for rows.Next() {
+		t := &models.APIToken{}
+		var scopes string
+		err = rows.Scan(&t.ID, &t.PlayerID, &t.Name, &t.Prefix, &scopes, &t.Created, &t.LastUsed)
+		if err != nil {
+			return nil, err
+		}
+		t.Scopes = strings.Fields(scopes)
+		tokens = append(tokens, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Revoke - a player can only revoke their own tokens
+func (m *TokenModel) Revoke(playerID, tokenID int) error {
+	stmt := `UPDATE ApiTokens SET revoked = 1 WHERE rowid = ? AND playerID = ? AND revoked = 0`
+	result, err := m.DB.Exec(stmt, tokenID, playerID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrNoRecord
+	}
+	return nil
+}
+
+// Authenticate - who does this bearer token belong to?
+// - Unknown and revoked tokens are both ErrInvalidCredentials
+func (m *TokenModel) Authenticate(token string) (*models.APIToken, error) {
+	t := &models.APIToken{}
+	var scopes string
+	stmt := `SELECT t.rowid, t.playerID, p.screenName, t.name, t.prefix, t.scopes, t.created, t.lastUsed
+				FROM ApiTokens t
+				JOIN Players p ON p.rowid = t.playerID
+				WHERE t.tokenHash = ? AND t.revoked = 0`
+	err := m.DB.QueryRow(stmt, hashToken(token)).Scan(
+		&t.ID, &t.PlayerID, &t.ScreenName, &t.Name, &t.Prefix, &scopes, &t.Created, &t.LastUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	t.Scopes = strings.Fields(scopes)
+	_, err = m.DB.Exec(`UPDATE ApiTokens SET lastUsed = ? WHERE rowid = ?`, time.Now(), t.ID)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
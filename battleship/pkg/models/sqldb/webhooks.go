@@ -0,0 +1,161 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/519seven/cs610/battleship/pkg/models"
+)
+
+// WebhookModel - players' webhooks and the log of what was sent to them
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+// Delete - a player can only delete their own webhooks
+// - The row stays (deleted = 1) so its delivery log still has something to point at
+func (m *WebhookModel) Delete(playerID, webhookID int) error {
+	stmt := `UPDATE Webhooks SET deleted = 1 WHERE rowid = ? AND playerID = ? AND deleted = 0`
+	result, err := m.DB.Exec(stmt, webhookID, playerID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrNoRecord
+	}
+	return nil
+}
+
+// Deliveries - the most recent deliveries to a webhook, newest first
+func (m *WebhookModel) Deliveries(webhookID, limit int) ([]*models.WebhookDelivery, error) {
+	stmt := `SELECT rowid, webhookID, battleID, event, payload, status, attempts, statusCode, error, created, updated
+				FROM WebhookDeliveries
+				WHERE webhookID = ?
+				ORDER BY rowid DESC
+				LIMIT ?`
+	rows, err := m.DB.Query(stmt, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d := &models.WebhookDelivery{}
+		err = rows.Scan(&d.ID, &d.WebhookID, &d.BattleID, &d.Event, &d.Payload, &d.Status,
+			&d.Attempts, &d.StatusCode, &d.Error, &d.Created, &d.Updated)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Get - one of a player's webhooks; ErrNoRecord if it isn't theirs (or was deleted)
+func (m *WebhookModel) Get(playerID, webhookID int) (*models.Webhook, error) {
+	h := &models.Webhook{}
+	var events string
+	stmt := `SELECT rowid, playerID, url, secret, events, created
+				FROM Webhooks
+				WHERE rowid = ? AND playerID = ? AND deleted = 0`
+	err := m.DB.QueryRow(stmt, webhookID, playerID).Scan(&h.ID, &h.PlayerID, &h.URL, &h.Secret, &events, &h.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	h.Events = strings.Fields(events)
+	return h, nil
+}
+
+// Insert - register a webhook for a player
+func (m *WebhookModel) Insert(playerID int, url, secret string, events []string) (int, error) {
+	stmt := `INSERT INTO Webhooks (playerID, url, secret, events, created, deleted) VALUES (?, ?, ?, ?, ?, 0)`
+	result, err := m.DB.Exec(stmt, playerID, url, secret, strings.Join(events, " "), time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// List - a player's webhooks, oldest first
+func (m *WebhookModel) List(playerID int) ([]*models.Webhook, error) {
+	stmt := `SELECT rowid, playerID, url, secret, events, created
+				FROM Webhooks
+				WHERE playerID = ? AND deleted = 0
+				ORDER BY rowid`
+	rows, err := m.DB.Query(stmt, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		h := &models.Webhook{}
+		var events string
+		err = rows.Scan(&h.ID, &h.PlayerID, &h.URL, &h.Secret, &events, &h.Created)
+		if err != nil {
+			return nil, err
+		}
+		h.Events = strings.Fields(events)
+		webhooks = append(webhooks, h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// Listening - a player's webhooks that want an event
+func (m *WebhookModel) Listening(playerID int, event string) ([]*models.Webhook, error) {
+	webhooks, err := m.List(playerID)
+	if err != nil {
+		return nil, err
+	}
+	listening := []*models.Webhook{}
+	for _, h := range webhooks {
+		if h.Wants(event) {
+			listening = append(listening, h)
+		}
+	}
+	return listening, nil
+}
+
+// LogDelivery - start the log entry for an event on its way to a webhook (pending, no attempts yet)
+func (m *WebhookModel) LogDelivery(webhookID, battleID int, event, payload string) (int, error) {
+	stmt := `INSERT INTO WebhookDeliveries (webhookID, battleID, event, payload, status, attempts, statusCode, error, created, updated)
+				VALUES (?, ?, ?, ?, ?, 0, 0, '', ?, ?)`
+	now := time.Now()
+	result, err := m.DB.Exec(stmt, webhookID, battleID, event, payload, models.DeliveryPending, now, now)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// UpdateDelivery - record how the latest attempt went
+func (m *WebhookModel) UpdateDelivery(deliveryID, attempts, statusCode int, status, errorText string) error {
+	stmt := `UPDATE WebhookDeliveries SET attempts = ?, statusCode = ?, status = ?, error = ?, updated = ? WHERE rowid = ?`
+	_, err := m.DB.Exec(stmt, attempts, statusCode, status, errorText, time.Now(), deliveryID)
+	return err
+}
@@ -0,0 +1,247 @@
+package sqlite3
+
+import (
+	"strings"
+
+	"github.com/519seven/cs610/battleship/pkg/migrate"
+)
+
+// Columns as each table was first created; later migrations add to them
+// - SQLite makes the rowid for us
+const (
+	battlesColumns = `player1ID INTEGER, player1Accepted BOOLEAN, player1BoardID INTEGER,
+		player2ID INTEGER, player2Accepted BOOLEAN, player2BoardID INTEGER,
+		player1SunkenShips INTEGER DEFAULT 0, player2SunkenShips INTEGER DEFAULT 0,
+		challengeDate DATETIME DEFAULT CURRENT_TIMESTAMP,
+		turn INTEGER, secretTurn STRING, winner INTEGER DEFAULT 0`
+	boardsColumns = `boardName TEXT, playerID INTEGER,
+		created DATETIME DEFAULT CURRENT_TIMESTAMP, isChosen BOOLEAN`
+	playersColumns = `screenName TEXT NOT NULL UNIQUE, emailAddress TEXT,
+		hashedPassword TEXT, created DATETIME, loggedIn BOOLEAN,
+		inBattle BOOLEAN, lastLogin DATETIME`
+)
+
+// rebuild - SQLite (3.29) can't drop a column, so copy the table into one
+// with just these columns, keeping every rowid
+func rebuild(table, columns string) []string {
+	var names []string
+	for _, column := range strings.Split(columns, ",") {
+		names = append(names, strings.Fields(column)[0])
+	}
+	list := strings.Join(names, ", ")
+	return []string{
+		`CREATE TABLE ` + table + `_rebuild (` + columns + `)`,
+		`INSERT INTO ` + table + `_rebuild (rowid, ` + list + `) SELECT rowid, ` + list + ` FROM ` + table,
+		`DROP TABLE ` + table,
+		`ALTER TABLE ` + table + `_rebuild RENAME TO ` + table,
+	}
+}
+
+func hasTable(name string) string {
+	return `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = '` + name + `'`
+}
+
+func hasColumn(table, name string) string {
+	return `SELECT COUNT(*) FROM pragma_table_info('` + table + `') WHERE name = '` + name + `'`
+}
+
+// Migrations - the SQLite schema, oldest first
+// - Never edit one that has shipped; add another
+// - Present lets databases from before schema_version pick up where they are
+var Migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: []string{
+			`CREATE TABLE Battles (` + battlesColumns + `)`,
+			`CREATE TABLE Boards (` + boardsColumns + `)`,
+			`CREATE TABLE Players (` + playersColumns + `)`,
+			`CREATE TABLE Positions (boardID INTEGER, shipID INTEGER, playerID INTEGER,
+				coordX INTEGER, coordY TEXT, pinColor TEXT)`,
+			`CREATE TABLE Ships (shipType TEXT, shipLength INTEGER)`,
+		},
+		Down: []string{
+			`DROP TABLE Ships`,
+			`DROP TABLE Positions`,
+			`DROP TABLE Players`,
+			`DROP TABLE Boards`,
+			`DROP TABLE Battles`,
+		},
+		Present: hasTable("Battles"),
+	},
+	{
+		Version: 2,
+		Name:    "board sizes",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN boardSize INTEGER DEFAULT 10`,
+			`ALTER TABLE Boards ADD COLUMN boardSize INTEGER DEFAULT 10`,
+		},
+		Down:    append(rebuild("Battles", battlesColumns), rebuild("Boards", boardsColumns)...),
+		Present: hasColumn("Boards", "boardSize"),
+	},
+	{
+		Version: 3,
+		Name:    "salvo rules",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN rules TEXT DEFAULT 'classic'`,
+			`ALTER TABLE Battles ADD COLUMN salvoShots INTEGER DEFAULT 0`,
+		},
+		Down:    rebuild("Battles", battlesColumns+`, boardSize INTEGER DEFAULT 10`),
+		Present: hasColumn("Battles", "salvoShots"),
+	},
+	{
+		Version: 4,
+		Name:    "api tokens",
+		Up: []string{
+			`CREATE TABLE ApiTokens (playerID INTEGER, name TEXT, prefix TEXT, tokenHash TEXT UNIQUE,
+				scopes TEXT, created DATETIME, lastUsed DATETIME, revoked BOOLEAN DEFAULT 0)`,
+		},
+		Down:    []string{`DROP TABLE ApiTokens`},
+		Present: hasTable("ApiTokens"),
+	},
+	{
+		Version: 5,
+		Name:    "computer player",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN aiStrategy TEXT DEFAULT ''`,
+			`ALTER TABLE Players ADD COLUMN isComputer BOOLEAN DEFAULT 0`,
+		},
+		Down: append(rebuild("Battles", battlesColumns+`, boardSize INTEGER DEFAULT 10,
+				rules TEXT DEFAULT 'classic', salvoShots INTEGER DEFAULT 0`),
+			rebuild("Players", playersColumns)...),
+		Present: hasColumn("Players", "isComputer"),
+	},
+	{
+		Version: 6,
+		Name:    "strike history",
+		Up: []string{
+			`CREATE TABLE Strikes (battleID INTEGER, move INTEGER, playerID INTEGER, boardID INTEGER,
+				coordX INTEGER, coordY TEXT, result TEXT, shipType TEXT, created DATETIME)`,
+		},
+		Down:    []string{`DROP TABLE Strikes`},
+		Present: hasTable("Strikes"),
+	},
+	{
+		// bob, sue and maria: B0mbs4way:(   elvis: P34nutButter76
+		// - Down leaves them be; by then they may own boards and battles
+		Version: 7,
+		Name:    "sample players",
+		Up: []string{
+			`INSERT OR IGNORE INTO Players (screenName, emailAddress, hashedPassword) VALUES
+				('bob', 'bob@bob.com', '$2a$13$g4P0c7YHfrBXXSDRS1tB3.WztiIn4WMLjQxB/XPLVm7NBoxi/dS6G'),
+				('sue', 'sue@sue.com', '$2a$13$g4P0c7YHfrBXXSDRS1tB3.WztiIn4WMLjQxB/XPLVm7NBoxi/dS6G'),
+				('elvis', 'elvis@graceland.com', '$2a$13$.e3xXhEC5.4Ill9vEjYpPe5B84EMGgMJNwdCVP9J0/zLwqVQbYn/6'),
+				('maria', 'maria@presley.com', '$2a$13$g4P0c7YHfrBXXSDRS1tB3.WztiIn4WMLjQxB/XPLVm7NBoxi/dS6G')`,
+		},
+		Present: `SELECT COUNT(*) FROM Players WHERE screenName = 'bob'`,
+	},
+	{
+		// A battle plays on copies of the players' saved boards (templateID is the
+		// saved board, battleID the battle); the saved boards themselves never get pins
+		// - Battles from before this keep playing on the boards they started with
+		Version: 8,
+		Name:    "board instances",
+		Up: []string{
+			`ALTER TABLE Boards ADD COLUMN templateID INTEGER DEFAULT 0`,
+			`ALTER TABLE Boards ADD COLUMN battleID INTEGER DEFAULT 0`,
+		},
+		Down:    rebuild("Boards", boardsColumns+`, boardSize INTEGER DEFAULT 10`),
+		Present: hasColumn("Boards", "templateID"),
+	},
+	{
+		// Battles from before this get the status their columns add up to
+		Version: 9,
+		Name:    "battle status",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN status TEXT DEFAULT 'challenged'`,
+			`UPDATE Battles SET status = 'setup' WHERE IFNULL(player2Accepted, 0) != 0`,
+			`UPDATE Battles SET status = 'in_progress' WHERE status = 'setup'
+				AND (rowid IN (SELECT battleID FROM Strikes)
+				OR player1BoardID IN (SELECT boardID FROM Positions WHERE pinColor IN ('red', 'gray'))
+				OR player2BoardID IN (SELECT boardID FROM Positions WHERE pinColor IN ('red', 'gray')))`,
+			`UPDATE Battles SET status = 'finished' WHERE IFNULL(winner, 0) != 0`,
+		},
+		Down: rebuild("Battles", battlesColumns+`, boardSize INTEGER DEFAULT 10,
+				rules TEXT DEFAULT 'classic', salvoShots INTEGER DEFAULT 0, aiStrategy TEXT DEFAULT ''`),
+		Present: hasColumn("Battles", "status"),
+	},
+	{
+		// turnTime is the turn clock in seconds (0 is none); deadline is when
+		// whoever has to move next runs out of time
+		Version: 10,
+		Name:    "turn clock",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN turnTime INTEGER DEFAULT 0`,
+			`ALTER TABLE Battles ADD COLUMN deadline DATETIME`,
+		},
+		Down: rebuild("Battles", battlesColumns+`, boardSize INTEGER DEFAULT 10,
+				rules TEXT DEFAULT 'classic', salvoShots INTEGER DEFAULT 0, aiStrategy TEXT DEFAULT '',
+				status TEXT DEFAULT 'challenged'`),
+		Present: hasColumn("Battles", "deadline"),
+	},
+	{
+		// Every player starts at 1500 (see pkg/rating); battles won before this don't count
+		// - Ratings is the history: one row per player per rated battle
+		Version: 11,
+		Name:    "ratings",
+		Up: []string{
+			`ALTER TABLE Players ADD COLUMN rating INTEGER DEFAULT 1500`,
+			`ALTER TABLE Players ADD COLUMN wins INTEGER DEFAULT 0`,
+			`ALTER TABLE Players ADD COLUMN losses INTEGER DEFAULT 0`,
+			`ALTER TABLE Players ADD COLUMN streak INTEGER DEFAULT 0`,
+			`CREATE TABLE Ratings (playerID INTEGER, battleID INTEGER, opponentID INTEGER,
+				rating INTEGER, delta INTEGER, created DATETIME)`,
+		},
+		Down: append([]string{`DROP TABLE Ratings`}, rebuild("Players", playersColumns+`, isComputer BOOLEAN DEFAULT 0`)...),
+		Present: hasTable("Ratings"),
+	},
+	{
+		// Either player can keep spectators out of their battle
+		Version: 12,
+		Name:    "spectators",
+		Up: []string{
+			`ALTER TABLE Battles ADD COLUMN player1Private BOOLEAN DEFAULT 0`,
+			`ALTER TABLE Battles ADD COLUMN player2Private BOOLEAN DEFAULT 0`,
+		},
+		Down: rebuild("Battles", battlesColumns+`, boardSize INTEGER DEFAULT 10,
+				rules TEXT DEFAULT 'classic', salvoShots INTEGER DEFAULT 0, aiStrategy TEXT DEFAULT '',
+				status TEXT DEFAULT 'challenged', turnTime INTEGER DEFAULT 0, deadline DATETIME`),
+		Present: hasColumn("Battles", "player1Private"),
+	},
+	{
+		// What the two players in a battle say to each other
+		Version: 13,
+		Name:    "chat",
+		Up: []string{`CREATE TABLE Messages (battleID INTEGER, playerID INTEGER, body TEXT, created DATETIME)`},
+		Down:    []string{`DROP TABLE Messages`},
+		Present: hasTable("Messages"),
+	},
+	{
+		// Each player's inbox: challenges, accepted challenges, turns and results
+		Version: 14,
+		Name:    "notifications",
+		Up: []string{
+			`CREATE TABLE Notifications (playerID INTEGER, battleID INTEGER, kind TEXT, message TEXT,
+				isRead BOOLEAN DEFAULT 0, created DATETIME)`,
+		},
+		Down:    []string{`DROP TABLE Notifications`},
+		Present: hasTable("Notifications"),
+	},
+	{
+		// Webhooks players register, and the log of every event sent to them
+		Version: 15,
+		Name:    "webhooks",
+		Up: []string{
+			`CREATE TABLE Webhooks (playerID INTEGER, url TEXT, secret TEXT, events TEXT,
+				created DATETIME, deleted BOOLEAN DEFAULT 0)`,
+			`CREATE TABLE WebhookDeliveries (webhookID INTEGER, battleID INTEGER, event TEXT, payload TEXT,
+				status TEXT, attempts INTEGER DEFAULT 0, statusCode INTEGER DEFAULT 0, error TEXT,
+				created DATETIME, updated DATETIME)`,
+		},
+		Down: []string{
+			`DROP TABLE WebhookDeliveries`,
+			`DROP TABLE Webhooks`,
+		},
+		Present: hasTable("Webhooks"),
+	},
+}
@@ -0,0 +1,36 @@
+// Package sqlite3 - what the models need to run on SQLite (see pkg/models/sqldb)
+// - The SQL itself is shared with MySQL; this is the Dialect, Open and the schema
+package sqlite3
+
+import (
+	"database/sql"
+	"errors"
+
+	driver "github.com/mattn/go-sqlite3"
+
+	"github.com/519seven/cs610/battleship/pkg/models/sqldb"
+)
+
+// Dialect - how SQLite differs from MySQL
+var Dialect = sqldb.Dialect{
+	Name: "sqlite3",
+	IsDuplicate: func(err error) bool {
+		var sqliteErr driver.Error
+		return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == driver.ErrConstraintUnique
+	},
+}
+
+// Open - open (or create) a database file
+// - SQLite only has one writer at a time; the others wait on busy_timeout
+func Open(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(3)
+	db.SetMaxIdleConns(2)
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,74 @@
+package models
+
+// BattleStatus - where a battle is in its life
+// - A battle starts out challenged; BattleModel only moves it along
+//   battleTransitions (anything else is ErrBattleStatus)
+// - challenged -> accepted -> setup -> in_progress -> finished
+// - challenged -> declined, cancelled or expired
+// - accepted, setup or in_progress -> abandoned
+type BattleStatus string
+
+const (
+	StatusChallenged	BattleStatus = "challenged"		// waiting on the opponent
+	StatusDeclined		BattleStatus = "declined"		// the opponent said no
+	StatusAccepted		BattleStatus = "accepted"		// the opponent said yes; their board isn't in the battle yet
+	StatusSetup			BattleStatus = "setup"			// both boards are in place, nobody has fired yet
+	StatusInProgress	BattleStatus = "in_progress"
+	StatusFinished		BattleStatus = "finished"		// somebody won
+	StatusAbandoned		BattleStatus = "abandoned"		// a player resigned or ran out of time part way through
+	StatusCancelled		BattleStatus = "cancelled"		// the challenger withdrew
+	StatusExpired		BattleStatus = "expired"		// nobody answered the challenge in time
+)
+
+var battleTransitions = map[BattleStatus][]BattleStatus{
+	StatusChallenged:	{StatusAccepted, StatusDeclined, StatusCancelled, StatusExpired},
+	StatusAccepted:		{StatusSetup, StatusAbandoned},
+	StatusSetup:		{StatusInProgress, StatusAbandoned},
+	StatusInProgress:	{StatusFinished, StatusAbandoned},
+}
+
+var statusLabels = map[BattleStatus]string{
+	StatusChallenged:	"Challenged",
+	StatusDeclined:		"Declined",
+	StatusAccepted:		"Accepted",
+	StatusSetup:		"Ready",
+	StatusInProgress:	"In progress",
+	StatusFinished:		"Finished",
+	StatusAbandoned:	"Abandoned",
+	StatusCancelled:	"Cancelled",
+	StatusExpired:		"Expired",
+}
+
+// CanBecome - is next one step along from here?
+func (s BattleStatus) CanBecome(next BattleStatus) bool {
+	for _, to := range battleTransitions[s] {
+		if to == next {
+			return true
+		}
+	}
+	return false
+}
+
+// From - every status that can become this one (for the models' UPDATE ... WHERE status IN)
+func (s BattleStatus) From() []BattleStatus {
+	var from []BattleStatus
+	for _, status := range []BattleStatus{StatusChallenged, StatusAccepted, StatusSetup, StatusInProgress} {
+		if status.CanBecome(s) {
+			from = append(from, status)
+		}
+	}
+	return from
+}
+
+// Over - nothing more will happen in this battle
+func (s BattleStatus) Over() bool {
+	return len(battleTransitions[s]) == 0
+}
+
+// Label - the status as the pages show it
+func (s BattleStatus) Label() string {
+	if label, ok := statusLabels[s]; ok {
+		return label
+	}
+	return string(s)
+}
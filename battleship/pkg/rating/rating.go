@@ -0,0 +1,29 @@
+// Package rating - Elo ratings for players
+// - Everybody starts at Initial; the winner of a battle takes points from the loser
+// - Beating someone rated well above you is worth more than beating someone below you
+// - Nothing in here knows about battles or the database
+package rating
+
+import "math"
+
+// Initial - the rating of a player who hasn't finished a battle yet
+const Initial = 1500
+
+// K - the most points a single battle can move a rating
+const K = 32
+
+// Expected - the chance (0 to 1) a player rated a beats a player rated b
+func Expected(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// Update - both ratings after the winner beat the loser
+// - Whatever the winner gains the loser loses, so the total never drifts
+// - A win is always worth at least a point
+func Update(winner, loser int) (int, int) {
+	delta := int(math.Round(K * (1 - Expected(winner, loser))))
+	if delta < 1 {
+		delta = 1
+	}
+	return winner + delta, loser - delta
+}
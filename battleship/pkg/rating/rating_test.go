@@ -0,0 +1,45 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpected(t *testing.T) {
+	tests := []struct {
+		a, b int
+		want float64
+	}{
+		{Initial, Initial, 0.5},
+		{1900, 1500, 10.0 / 11},
+		{1500, 1900, 1.0 / 11},
+	}
+	for _, tt := range tests {
+		if got := Expected(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Expected(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name            string
+		winner, loser   int
+		winner2, loser2 int
+	}{
+		{"even match", Initial, Initial, Initial + K/2, Initial - K/2},
+		{"favourite wins", 1600, 1400, 1608, 1392},
+		{"upset", 1400, 1600, 1424, 1576},
+		{"favourite by a mile wins", 2400, 1200, 2401, 1199}, // worth a point, not 0
+		{"upset by a mile", 1200, 2400, 1232, 2368},
+	}
+	for _, tt := range tests {
+		w, l := Update(tt.winner, tt.loser)
+		if w != tt.winner2 || l != tt.loser2 {
+			t.Errorf("%s: Update(%d, %d) = %d, %d; want %d, %d", tt.name, tt.winner, tt.loser, w, l, tt.winner2, tt.loser2)
+		}
+		if w+l != tt.winner+tt.loser {
+			t.Errorf("%s: the ratings add up to %d after, %d before", tt.name, w+l, tt.winner+tt.loser)
+		}
+	}
+}
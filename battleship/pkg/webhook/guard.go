@@ -0,0 +1,135 @@
+package webhook
+
+// Keeping players' webhooks off the server's own network
+// - A player picks the URL, so without a check a webhook (and its "Test" button)
+//   could be pointed at the server itself, or anything else only it can reach
+// - The URL is checked when it's registered (CheckURL) and again on every
+//   connection (Guarded), since a name can point somewhere else by the time it's used
+// - Redirects are never followed; they could go anywhere
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress - the URL goes to an address webhooks may not use
+var ErrBlockedAddress = errors.New("webhook: not a public address")
+
+// Addresses that aren't on the public internet (Blocked checks loopback,
+// multicast and the like with net.IP's own methods; Go 1.13 has nothing for the rest)
+var blockedNets = parseCIDRs(
+	"0.0.0.0/8",      // "this" network
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // carrier-grade NAT
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link-local (and cloud metadata services)
+	"172.16.0.0/12",  // private
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // private
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved, and broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"64:ff9b::/96",   // IPv4 translation, could reach any of the above
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link-local
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// Blocked - is this an address webhooks may not use?
+func Blocked(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() || ip.IsLinkLocalUnicast() {
+		return true
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// How names are looked up (tests can stand in for DNS)
+var lookupIP = net.LookupIP
+
+// CheckURL - is this a URL a player may register?
+// - It must be a ValidURL, and every address its host has must be public
+// - ErrBlockedAddress if one isn't; any other error is from looking the host up
+func CheckURL(raw string) error {
+	if !ValidURL(raw) {
+		return fmt.Errorf("webhook: %q is not an http:// or https:// URL", raw)
+	}
+	u, _ := url.Parse(raw)
+	host := u.Hostname()
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = lookupIP(host); err != nil {
+			return err
+		}
+	}
+	for _, ip := range ips {
+		if Blocked(ip) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// checkDial - refuse to connect to a blocked address (a net.Dialer's Control)
+//   - It sees the address after the name has been looked up, so it can't be fooled
+//     by a name that changes what it points to
+func checkDial(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || Blocked(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+func noRedirects(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// Guarded - a client for players' webhooks: public addresses only, no proxy and no redirects
+func Guarded() *http.Client {
+	dialer := &net.Dialer{Timeout: Timeout, Control: checkDial}
+	return &http.Client{
+		Timeout:       Timeout,
+		CheckRedirect: noRedirects,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: Timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// Trusted - a client for URLs whoever runs the server picked (-webhook): any
+// address, but still no redirects
+func Trusted() *http.Client {
+	return &http.Client{Timeout: Timeout, CheckRedirect: noRedirects}
+}
@@ -0,0 +1,209 @@
+// Package webhook - send game events to URLs outside the server
+// - Every payload is JSON, signed with HMAC-SHA256 using the webhook's secret
+// - A delivery is tried again, waiting longer each time, until the receiver
+//   answers 2xx, answers with an error that trying again won't fix, or Attempts run out
+// - Nothing in here knows about the database; the caller keeps the delivery log
+// - A Sender posts with any *http.Client, so an httptest.Server can stand in for a receiver
+// - Players' webhooks only reach public addresses (see guard.go)
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Event types a webhook can ask for
+// - Ping is only sent when someone tests a webhook; every webhook gets it
+const (
+	Challenge = "challenge"
+	Accept    = "accept"
+	Strike    = "strike"
+	Sunk      = "sunk"
+	Winner    = "winner"
+	Ping      = "ping"
+)
+
+// Events - everything a webhook can ask for, in the order it happens in a battle
+var Events = []string{Challenge, Accept, Strike, Sunk, Winner}
+
+// IsEvent - can a webhook ask for this?
+func IsEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Headers sent with every delivery
+// - Signature is "sha256=" and the hex HMAC of the body (see Verify)
+const (
+	HeaderEvent     = "X-Battleship-Event"
+	HeaderSignature = "X-Battleship-Signature"
+)
+
+// Retries
+// - Attempt 2 waits FirstWait, and every attempt after that waits twice as long, up to MaxWait
+// - Timeout is how long one attempt may take
+const (
+	Attempts  = 5
+	FirstWait = 2 * time.Second
+	MaxWait   = time.Minute
+	Timeout   = 10 * time.Second
+)
+
+// Payload - the body of every delivery
+// - ID is the same on every attempt, so a receiver can tell a retry from a new event
+// - Players are the two players in the battle (or the one a challenge went to)
+type Payload struct {
+	ID       string      `json:"id"`
+	Event    string      `json:"event"`
+	BattleID int         `json:"battle_id,omitempty"`
+	Players  []int       `json:"players,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+	Sent     time.Time   `json:"sent"`
+}
+
+// Sign - the signature header for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify - did this body come from someone who knows the secret? (for receivers)
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// ValidURL - a webhook must be an absolute http or https URL
+func ValidURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Wait - how long to wait before an attempt (nothing before the first)
+func Wait(attempt int) time.Duration {
+	if attempt < 2 {
+		return 0
+	}
+	wait := FirstWait
+	for i := 2; i < attempt && wait < MaxWait; i++ {
+		wait *= 2
+	}
+	if wait > MaxWait {
+		wait = MaxWait
+	}
+	return wait
+}
+
+// Delivery - one signed body on its way to one URL
+type Delivery struct {
+	URL    string
+	Secret string
+	Event  string
+	Body   []byte
+}
+
+// Result - how one attempt went
+// - StatusCode is 0 when there was no answer at all (Err says why)
+type Result struct {
+	Attempt    int
+	StatusCode int
+	Err        error
+	Elapsed    time.Duration
+}
+
+// OK - the receiver took it
+func (r Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Retry - is it worth trying again?
+// - No answer, a server error, or the receiver asking us to slow down or try later
+func (r Result) Retry() bool {
+	if r.OK() {
+		return false
+	}
+	return r.Err != nil || r.StatusCode >= 500 ||
+		r.StatusCode == http.StatusTooManyRequests || r.StatusCode == http.StatusRequestTimeout
+}
+
+// Sender - posts deliveries; safe to share between goroutines
+// - The zero value is ready to use: a Guarded client, Attempts tries and Wait between them
+type Sender struct {
+	Client   *http.Client
+	Attempts int
+	Wait     func(attempt int) time.Duration
+}
+
+var defaultClient = Guarded()
+
+func (s *Sender) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return defaultClient
+}
+
+// Post - make one attempt
+func (s *Sender) Post(d Delivery, attempt int) (result Result) {
+	result.Attempt = attempt
+	start := time.Now()
+	defer func() { result.Elapsed = time.Since(start) }()
+
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "battleship-webhook")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderSignature, Sign(d.Secret, d.Body))
+	resp, err := s.client().Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	// Read (a little of) the answer so the connection can be used again
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	result.StatusCode = resp.StatusCode
+	return result
+}
+
+// Send - post until the receiver takes it, it fails for good or attempts run out
+// - report (if not nil) hears about every attempt; the last Result is returned
+func (s *Sender) Send(d Delivery, report func(Result)) Result {
+	attempts, wait := s.Attempts, s.Wait
+	if attempts < 1 {
+		attempts = Attempts
+	}
+	if wait == nil {
+		wait = Wait
+	}
+	var result Result
+	for attempt := 1; attempt <= attempts; attempt++ {
+		time.Sleep(wait(attempt))
+		result = s.Post(d, attempt)
+		if report != nil {
+			report(result)
+		}
+		if !result.Retry() {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,225 @@
+package webhook
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"id":"7","event":"strike"}`)
+	signature := Sign("secret", body)
+	if signature != Sign("secret", body) || len(signature) != len("sha256=")+64 {
+		t.Fatalf("Sign = %q, want the same sha256= and 64 hex digits every time", signature)
+	}
+
+	tests := []struct {
+		secret    string
+		body      string
+		signature string
+		ok        bool
+	}{
+		{"secret", string(body), signature, true},
+		{"other secret", string(body), signature, false},
+		{"secret", `{"id":"7","event":"sunk"}`, signature, false},
+		{"secret", string(body), signature[len("sha256="):], false},
+		{"secret", string(body), "", false},
+	}
+	for _, tt := range tests {
+		if got := Verify(tt.secret, []byte(tt.body), tt.signature); got != tt.ok {
+			t.Errorf("Verify(%q, %s, %q) = %v, want %v", tt.secret, tt.body, tt.signature, got, tt.ok)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, FirstWait},
+		{3, 2 * FirstWait},
+		{4, 4 * FirstWait},
+		{5, 8 * FirstWait},
+		{6, 16 * FirstWait},
+		{7, MaxWait},
+		{50, MaxWait},
+	}
+	for _, tt := range tests {
+		if got := Wait(tt.attempt); got != tt.want {
+			t.Errorf("Wait(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		answers  []int
+		attempts int
+		ok       bool
+	}{
+		{"first time", []int{200}, 1, true},
+		{"after server errors", []int{500, 502, 204}, 3, true},
+		{"slow down, then fine", []int{429, 408, 200}, 3, true},
+		{"won't get any better", []int{404}, 1, false},
+		{"never", []int{503, 503, 503, 503, 503, 503}, Attempts, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var received int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				body, _ := ioutil.ReadAll(r.Body)
+				if !Verify("secret", body, r.Header.Get(HeaderSignature)) || r.Header.Get(HeaderEvent) != Strike {
+					t.Errorf("attempt %d came with %s %q", received+1, r.Header.Get(HeaderEvent), r.Header.Get(HeaderSignature))
+				}
+				w.WriteHeader(tt.answers[received])
+				received++
+			}))
+			defer ts.Close()
+
+			var waited []int
+			s := &Sender{
+				Client: ts.Client(),
+				Wait: func(attempt int) time.Duration {
+					waited = append(waited, attempt)
+					return 0
+				},
+			}
+			var reported []Result
+			result := s.Send(Delivery{URL: ts.URL, Secret: "secret", Event: Strike, Body: []byte(`{"id":"1"}`)},
+				func(r Result) { reported = append(reported, r) })
+
+			if result.OK() != tt.ok || result.Attempt != tt.attempts || received != tt.attempts {
+				t.Errorf("Send = %+v after %d requests, want OK %v after %d", result, received, tt.ok, tt.attempts)
+			}
+			if len(reported) != tt.attempts || len(waited) != tt.attempts {
+				t.Fatalf("%d attempts reported and %d waits, want %d", len(reported), len(waited), tt.attempts)
+			}
+			for i, r := range reported {
+				if r.Attempt != i+1 || waited[i] != i+1 || r.StatusCode != tt.answers[i] {
+					t.Errorf("attempt %d: reported %+v after waiting for attempt %d, want status %d", i+1, r, waited[i], tt.answers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"::", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"64:ff9b::a00:1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"ff02::1", true},
+		{"93.184.216.34", false},
+		{"172.32.0.1", false},
+		{"8.8.8.8", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+	}
+	for _, tt := range tests {
+		if got := Blocked(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("Blocked(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	defer func(lookup func(string) ([]net.IP, error)) { lookupIP = lookup }(lookupIP)
+	lookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "hooks.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "sneaky.example.com":
+			// one public address is no good if the other one isn't
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.0.0.1")}, nil
+		case "localhost":
+			return []net.IP{net.ParseIP("127.0.0.1")}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host}
+	}
+
+	tests := []struct {
+		url     string
+		blocked bool
+		ok      bool
+	}{
+		{"https://hooks.example.com/battleship", false, true},
+		{"http://93.184.216.34:8080/", false, true},
+		{"http://localhost:4000/", true, false},
+		{"http://sneaky.example.com/", true, false},
+		{"http://127.0.0.1/", true, false},
+		{"http://[::1]:4000/", true, false},
+		{"http://169.254.169.254/latest/meta-data/", true, false},
+		{"http://nowhere.example.com/", false, false},
+		{"ftp://hooks.example.com/", false, false},
+		{"hooks.example.com", false, false},
+	}
+	for _, tt := range tests {
+		err := CheckURL(tt.url)
+		if (err == nil) != tt.ok || errors.Is(err, ErrBlockedAddress) != tt.blocked {
+			t.Errorf("CheckURL(%s) = %v, want ok %v and blocked %v", tt.url, err, tt.ok, tt.blocked)
+		}
+	}
+}
+
+func TestGuarded(t *testing.T) {
+	reached := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer ts.Close()
+
+	// A name that was public when it was registered can point at the server by now
+	s := &Sender{Client: Guarded()}
+	result := s.Post(Delivery{URL: ts.URL, Secret: "secret", Event: Ping, Body: []byte("{}")}, 1)
+	if !errors.Is(result.Err, ErrBlockedAddress) || reached {
+		t.Errorf("Post to %s = %+v (reached %v), want ErrBlockedAddress and no request", ts.URL, result, reached)
+	}
+}
+
+func TestNoRedirects(t *testing.T) {
+	followed := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed = true
+	}))
+	defer target.Close()
+	ts := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer ts.Close()
+
+	s := &Sender{Client: Trusted()}
+	result := s.Post(Delivery{URL: ts.URL, Secret: "secret", Event: Ping, Body: []byte("{}")}, 1)
+	if result.StatusCode != http.StatusFound || followed {
+		t.Errorf("Post to a redirect = %+v (followed %v), want the 302 itself", result, followed)
+	}
+	if result.OK() || result.Retry() {
+		t.Errorf("a redirect is OK %v, Retry %v; want neither", result.OK(), result.Retry())
+	}
+}
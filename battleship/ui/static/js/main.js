@@ -5,4 +5,67 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+// Live updates - one event stream per page for logged in players
+// - Pages add their own listeners to battleEvents (strike, turn, winner, ...)
+// - Challenges, accepted challenges and matches are announced on every page
+// - A spectator's page sets watchBattleID to hear that battle's strikes as well
+var battleEvents = null;
+var liveNotice = document.getElementById("live_notice");
+if (liveNotice && window.EventSource) {
+	battleEvents = new EventSource(typeof watchBattleID == "undefined" ? "/events" : "/events?watch=" + watchBattleID);
+	var announce = function(message, href, linkText) {
+		liveNotice.textContent = message + " ";
+		var link = document.createElement("a");
+		link.href = href;
+		link.textContent = linkText;
+		liveNotice.appendChild(link);
+		liveNotice.style.display = "block";
+	};
+	battleEvents.addEventListener("challenge", function(e) {
+		var ev = JSON.parse(e.data);
+		announce(ev.data.challenger + " has challenged you!", "/status/battles/list", "View your challenges");
+	});
+	battleEvents.addEventListener("accept", function(e) {
+		var ev = JSON.parse(e.data);
+		announce(ev.data.opponent + " has accepted your challenge!", "/status/battles/list", "Go to battle");
+	});
+	battleEvents.addEventListener("decline", function(e) {
+		var ev = JSON.parse(e.data);
+		announce(ev.data.opponent + " has declined your challenge.", "/player/list", "Challenge someone else");
+	});
+	battleEvents.addEventListener("match", function(e) {
+		var ev = JSON.parse(e.data);
+		announce("You've been matched with " + ev.data.opponent + "!", "/battle/view/" + ev.battle_id, "Go to battle");
+	});
+	battleEvents.addEventListener("timeout", function(e) {
+		var ev = JSON.parse(e.data);
+		if (ev.data.status == "expired") {
+			announce("A challenge went unanswered and has expired.", "/status/battles/list", "View your battles");
+		} else {
+			announce("Time ran out in one of your battles.", "/status/battles/list", "View your battles");
+		}
+	});
+	battleEvents.addEventListener("withdraw", function(e) {
+		var ev = JSON.parse(e.data);
+		announce(ev.data.challenger + " has withdrawn their challenge.", "/status/battles/list", "View your challenges");
+	});
+}
+// The unread count in the nav bar - pushed as a "notice" event, or checked every 30 seconds
+var unreadCount = document.getElementById("unread_count");
+if (unreadCount && battleEvents) {
+	battleEvents.addEventListener("notice", function(e) {
+		unreadCount.textContent = JSON.parse(e.data).data.unread;
+	});
+} else if (unreadCount) {
+	setInterval(function() {
+		var request = new XMLHttpRequest();
+		request.open("GET", "/status/inbox");
+		request.onload = function() {
+			if (request.status == 200) {
+				unreadCount.textContent = JSON.parse(request.responseText).unread;
+			}
+		};
+		request.send();
+	}, 30000);
+}